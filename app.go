@@ -1,18 +1,25 @@
 package main
 
 import (
-	"bytes"
 	"chip8-wails/chip8"
+	"chip8-wails/internal/asm"
+	"chip8-wails/internal/c8pkg"
+	"chip8-wails/internal/demo"
+	"chip8-wails/internal/expr"
+	"chip8-wails/internal/romdb"
 	"chip8-wails/internal/roms"
+	"chip8-wails/internal/savestate"
 	"chip8-wails/internal/settings"
 	"context"
 	"encoding/base64"
-	"encoding/gob"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -20,6 +27,7 @@ import (
 )
 
 const debugUpdateInterval = time.Millisecond * 100 // ~10Hz throttle (1000ms / 100ms = 10 updates/sec)
+const statusHistorySize = 50
 type WailsInfo struct {
 	Info struct {
 		ProductName string `json:"productName"`
@@ -31,22 +39,66 @@ type WailsInfo struct {
 		Name string `json:"name"`
 	} `json:"author"`
 }
+
+/*
+InputEvent is a key press or release recorded at a CPU cycle, as returned by
+StopInputRecording and replayed by PlayInputRecording.
+*/
+type InputEvent = demo.Event
+
 type App struct {
 	ctx                 context.Context
 	cpu                 *chip8.Chip8
 	frontendReady       chan struct{}
-	cpuSpeed            time.Duration
+	cyclesPerFrame      int
+	frameClock          frameClock
+	timerClock          frameClock
+	cycleTimers         bool // Cycle-accurate timer mode: timers follow cycleTimer, not the frame
+	cycleTimer          cycleTimer
+	tone                tone
+	speedMultiplier     float64
+	stepsSinceFrame     int
+	frameBudget         time.Duration
+	deferredCycles      int
+	overBudget          bool
+	playlist            *playlist
+	soundTimer          byte
+	autoSaver           autoSaver
+	autoSaveDir         string
+	romDB               *romdb.DB
+	romDBPath           string
+	rewind              *rewindBuffer
+	slowdown            collisionSlowdown
+	lastError           string
+	halted              bool
 	logBuffer           []string
+	statusHistory       []string
 	logMutex            sync.Mutex
 	mu                  sync.RWMutex
-	isPaused            bool
 	isDebugging         bool
 	wailsInfo           WailsInfo
+	window              windowRuntime
 	romLoaded           []byte
+	romName             string
+	machine             chip8.Machine
+	userMachine         chip8.Machine // The machine last chosen with SetMachineType
+	romClock            int           // The loaded ROM's own clock speed (Hz), or 0 to use the settings
 	settings            settings.Settings
 	settingsManager     *settings.Manager
 	romLoader           *roms.Loader
-	lastDebugUpdateTime time.Time
+	displayThrottle     *eventThrottle
+	debugThrottle       *eventThrottle
+	clearCoalescer      clearCoalescer
+	freezeDetector      freezeDetector
+	framesDrawn         uint64
+	pendingDemo         *demo.Demo
+	demoPlayer          *demo.Player
+	recording           *demo.Demo
+	lastRecording       *demo.Demo
+	gamepadKeys         [16]bool
+	keys                *keyQueue
+	drawPending         bool // An instruction drew since the last display event
+	memorySnapshot      []byte
 }
 
 /*
@@ -60,13 +112,30 @@ func NewApp() *App {
 	appConfigDir := filepath.Join(configDir, "chip8-wails")
 	settingsPath := filepath.Join(appConfigDir, "settings.json")
 
-	return &App{
+	app := &App{
 		cpu:             chip8.New(),
 		frontendReady:   make(chan struct{}),
 		logBuffer:       make([]string, 0, 100),
-		isPaused:        true,
 		settingsManager: settings.NewManager(settingsPath),
+		displayThrottle: newEventThrottle(0),
+		debugThrottle:   newEventThrottle(0),
+		freezeDetector:  freezeDetector{threshold: frozenDisplayFrames},
+		machine:         chip8.MachineCHIP8,
+		userMachine:     chip8.MachineCHIP8,
+		speedMultiplier: 1,
+		window:          wailsWindow{},
+		autoSaveDir:     filepath.Join(appConfigDir, "autosave"),
+		romDBPath:       filepath.Join(appConfigDir, "romdb.json"),
+		keys:            newKeyQueue(keyEventBuffer),
 	}
+	defaults := settings.DefaultSettings()
+	app.applyClock(defaults.ClockSpeed)
+	app.applyFrameBudget(defaults.FrameBudgetMs)
+	app.applyEventRate(defaults.MaxEventsPerSecond)
+	app.rewind = newRewindBuffer(defaults.RewindDepth)
+	app.memorySnapshot = make([]byte, app.cpu.MemorySize())
+	app.cpu.StopOnHalt = true
+	return app
 }
 
 var frontendReadyOnce sync.Once
@@ -86,10 +155,29 @@ func (a *App) startup(ctx context.Context) {
 	a.mu.Lock()
 	a.settings = loadedSettings
 	a.romLoader = roms.NewLoader(loadedSettings.RomsPath)
+	a.romLoader.Recursive = loadedSettings.ScanROMSubfolders
+	a.applyEventRate(loadedSettings.MaxEventsPerSecond)
+	a.clearCoalescer.enabled = loadedSettings.CoalesceClears
+	a.applyFrameBudget(loadedSettings.FrameBudgetMs)
+	a.cpu.Quirks = loadedSettings.Quirks
+	a.cpu.HaltOnUnknown = loadedSettings.HaltOnUnknownOpcode
+	a.cpu.HaltOnOverflow = loadedSettings.HaltOnMemoryOverflow
+	a.cycleTimers = loadedSettings.CycleAccurateTimers
+	a.autoSaver.setInterval(loadedSettings.AutoSaveSeconds, time.Now())
+	a.applyRewindDepth(loadedSettings.RewindDepth)
+	a.slowdown.enabled = loadedSettings.SlowMotionOnCollision
 	a.mu.Unlock()
 
 	a.appendLog("Settings loaded successfully.")
+	db, err := romdb.Load(a.romDBPath)
+	if err != nil {
+		a.appendLog(fmt.Sprintf("Warning: %v", err))
+	}
+	a.mu.Lock()
+	a.romDB = db
+	a.mu.Unlock()
 	a.SetClockSpeed(loadedSettings.ClockSpeed)
+	runtime.OnFileDrop(ctx, a.handleFileDrop)
 	go a.runEmulator()
 }
 
@@ -102,55 +190,340 @@ func (a *App) runEmulator() {
 	if speed <= 0 {
 		speed = 700
 		a.appendLog(fmt.Sprintf("Warning: Invalid clock speed detected, falling back to %d Hz", speed))
+		a.SetClockSpeed(speed)
 	}
-	cpuTicker := time.NewTicker(time.Second / time.Duration(speed))
-	timerTicker := time.NewTicker(time.Second / 60)
-	defer cpuTicker.Stop()
-	defer timerTicker.Stop()
+	// Each 60Hz frame runs a batch of instructions, then ticks the timers
+	frameTicker := time.NewTicker(time.Second / chip8.TimerFrequency)
+	defer frameTicker.Stop()
+	a.runLoop(a.ctx.Done(), frameTicker.C, time.Now)
+}
+
+/*
+runLoop runs one frame for every value received from ticks until done is
+closed, reading the time from clock. The app drives it with a 60Hz ticker and
+the wall clock; tests can send ticks by hand and use a fixed clock, so frames
+run deterministically without sleeping.
+*/
+func (a *App) runLoop(done <-chan struct{}, ticks <-chan time.Time, clock func() time.Time) {
 	for {
 		select {
-		case <-a.ctx.Done():
+		case <-done:
 			return
-		case <-cpuTicker.C:
-			a.mu.RLock()
-			currentSpeed := a.settings.ClockSpeed
-			a.mu.RUnlock()
-			if currentSpeed > 0 && int(time.Second/a.cpuSpeed) != currentSpeed {
-				cpuTicker.Reset(time.Second / time.Duration(currentSpeed))
-			}
-			a.mu.RLock()
-			isRunning := !a.isPaused
-			a.mu.RUnlock()
-			if isRunning {
-				a.cpu.EmulateCycle()
-			}
-		case <-timerTicker.C:
-			a.mu.Lock()
-			isRunning := !a.isPaused
-			isDebugging := a.isDebugging
-			soundTimer := a.cpu.SoundTimer
-			drawFlag := a.cpu.DrawFlag
-			if isRunning {
-				a.cpu.UpdateTimers()
-				if soundTimer > 0 {
-					a.emit("playBeep")
-				}
+		case <-ticks:
+			a.runFrame(clock)
+		}
+	}
+}
+
+/*
+runFrame runs one 60Hz frame: a batch of instructions at the current clock
+speed unless paused, the timer ticks that go with it, then the per-frame
+housekeeping and events. clock supplies the time for the frame budget,
+throttling, the playlist and auto-saves.
+*/
+func (a *App) runFrame(clock func() time.Time) {
+	a.mu.Lock()
+	cpuRunning := !a.paused()
+	cycles := a.slowdown.cycles(a.frameClock.next() + a.deferredCycles)
+	ticks := a.slowdown.ticks(a.timerClock.next())
+	budget := a.frameBudget
+	a.mu.Unlock()
+	if !cpuRunning {
+		// Keep the keypad current while paused so it is right on resume
+		a.applyKeyEvents()
+	}
+	if cpuRunning {
+		timers := timerSpreader{ticks: ticks, cycles: cycles}
+		ran := runWithinBudget(cycles, budget, clock, func() bool {
+			return a.runCycle(&timers)
+		})
+		a.deferCycles(cycles - ran)
+	}
+
+	now := clock()
+	a.mu.RLock()
+	playlistDue := a.playlist != nil && !a.paused() && a.playlist.due(now)
+	a.mu.RUnlock()
+	if playlistDue {
+		a.NextInPlaylist()
+	}
+
+	a.mu.Lock()
+	isRunning := !a.paused()
+	// A breakpoint, watchpoint or fault stopped the CPU during this frame
+	stoppedNow := cpuRunning && !isRunning
+	if stoppedNow {
+		a.silence()
+	}
+	isDebugging := a.isDebugging
+	frozen := false
+	if isRunning {
+		// In the cycle-accurate timer mode the timers and tone were kept
+		// up to date instruction by instruction during the batch
+		if !a.cycleTimers {
+			if a.cpu.SoundTimer > a.soundTimer {
+				// The ROM started (or extended) the tone since the last tick
+				a.emit("soundStart", a.soundPayload())
 			}
-			if isDebugging && time.Since(a.lastDebugUpdateTime) >= debugUpdateInterval {
-				state := a.cpu.GetState()
-				a.lastDebugUpdateTime = time.Now()
-				a.mu.Unlock()
-				a.emit("debugUpdate", state)
-			} else {
-				a.mu.Unlock()
+			soundWasOn := a.soundTimer > 0 || a.cpu.SoundTimer > 0
+			if ticks > 0 && !a.cpu.UpdateTimers() && soundWasOn {
+				a.emit("soundStop")
 			}
-			if drawFlag {
-				displayData := base64.StdEncoding.EncodeToString(a.cpu.Display[:])
-				a.emit("displayUpdate", displayData)
-				a.cpu.ClearDrawFlag()
+		}
+		a.soundTimer = a.cpu.SoundTimer
+		a.slowdown.observe(a.cpu.CollisionCount)
+		frozen = a.freezeDetector.observe(a.cpu.DisplayHash())
+	}
+	haltedNow := a.cpu.Halted && !a.halted
+	a.halted = a.cpu.Halted
+	pc := a.cpu.PC
+	var cpuError map[string]interface{}
+	if a.cpu.LastError != a.lastError {
+		a.lastError = a.cpu.LastError
+		if a.lastError != "" {
+			cpuError = map[string]interface{}{
+				"message": a.lastError,
+				"halted":  !a.cpu.IsRunning,
 			}
 		}
 	}
+	frame, emitDisplay := a.pollDisplay(now)
+	var state map[string]interface{}
+	if isDebugging {
+		a.debugThrottle.mark()
+		if a.debugThrottle.ready(now) {
+			state = a.cpu.GetState()
+			a.memorySnapshot = append(a.memorySnapshot[:0], a.cpu.Memory...)
+		}
+	}
+	var autoSave []byte
+	if isRunning && a.romLoaded != nil && a.autoSaver.due(now) {
+		autoSave = a.packageState()
+	}
+	a.mu.Unlock()
+	if autoSave != nil {
+		if _, err := writeAutoSave(a.autoSaveDir, autoSave, now); err != nil {
+			a.appendLog(fmt.Sprintf("Auto-save failed: %v", err))
+		}
+	}
+	if stoppedNow {
+		a.emit("soundStop")
+		a.emit("pauseUpdate", true)
+	}
+	if haltedNow {
+		a.appendLog("ROM halted: it jumped to its own address.")
+		a.emit("halted", pc)
+	}
+	if cpuError != nil {
+		a.appendLog(fmt.Sprintf("CPU error: %s", cpuError["message"]))
+		a.emit("errorUpdate", cpuError)
+	}
+	if state != nil {
+		a.emit("debugUpdate", state)
+	}
+	if emitDisplay {
+		a.emitDisplay(frame)
+	}
+	if frozen {
+		a.appendLog("Display has not changed for a while; the ROM may be hung (or just showing a static screen).")
+		a.emit("displayFrozen")
+	}
+}
+
+/*
+runCycle applies pending input and executes one instruction of a frame batch,
+ticking the timers as the batch's spreader says. It reports false when the CPU
+cannot make progress this frame: halted on a self-jump, or waiting for a
+vertical blank with no tick left to take. Key events are applied first, so a
+press still reaches a halted ROM and wakes it. The CPU is only touched under
+a.mu, so the keypad and everything else it reads cannot change mid-instruction.
+*/
+func (a *App) runCycle(timers *timerSpreader) bool {
+	a.applyKeyEvents()
+	a.applyDemoInput()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.cpu.Halted && a.cpu.StopOnHalt {
+		return a.idleCycle()
+	}
+	if a.cpu.WaitingForVBlank() {
+		if a.cycleTimers {
+			return a.idleCycle()
+		}
+		if !timers.stalled() {
+			return false
+		}
+		a.cpu.UpdateTimers()
+	}
+	running := a.cpu.IsRunning
+	if running {
+		a.rewind.push(a.cpu)
+	}
+	if a.cpu.EmulateCycle().Drew {
+		a.drawPending = true
+	}
+	if a.cycleTimers {
+		if running {
+			a.stepCycleTimer()
+		}
+	} else if timers.step() {
+		a.cpu.UpdateTimers()
+	}
+	return true
+}
+
+/*
+idleCycle lets an instruction slot pass without executing anything, for a CPU
+that is halted or waiting for the vertical blank. In the cycle-accurate timer
+mode the slot still counts towards the next timer tick, which is what ends a
+vblank wait, and the batch carries on. Otherwise it reports false so the batch
+ends and the timers tick at the end of the frame. Callers must hold a.mu.
+*/
+func (a *App) idleCycle() bool {
+	if !a.cycleTimers {
+		return false
+	}
+	a.stepCycleTimer()
+	return true
+}
+
+/*
+stepCycleTimer counts one instruction slot in the cycle-accurate timer mode,
+ticking the timers when one is due and bringing the tone in line with them.
+Callers must hold a.mu.
+*/
+func (a *App) stepCycleTimer() {
+	if !a.cycleTimer.step() {
+		return
+	}
+	a.cpu.UpdateTimers()
+	a.syncTone()
+}
+
+/*
+tone is the sound the frontend was last told to play.
+*/
+type tone struct {
+	on      bool
+	pitch   byte
+	pattern [16]byte
+}
+
+/*
+syncTone tells the frontend about any change to the tone since the last timer
+tick: the sound timer starting or running out, or the ROM changing the pitch
+register or audio pattern while it sounds. The frontend restarts its loop when
+the pitch or pattern differs, so XO-CHIP music changes note exactly on the 60Hz
+tick it was written for. Callers must hold a.mu.
+*/
+func (a *App) syncTone() {
+	now := tone{on: a.cpu.SoundTimer > 0, pitch: a.cpu.AudioPitch, pattern: a.cpu.AudioBuffer}
+	switch {
+	case now.on && now != a.tone:
+		a.emit("soundStart", a.soundPayload())
+	case !now.on && a.tone.on:
+		a.emit("soundStop")
+	}
+	a.tone = now
+}
+
+/*
+soundPayload describes the current tone for a soundStart event. Callers must
+hold a.mu.
+*/
+func (a *App) soundPayload() map[string]interface{} {
+	return map[string]interface{}{
+		"durationMs": a.cpu.SoundDurationMs(),
+		"sampleRate": a.cpu.SampleRate(),
+		"pitch":      a.cpu.AudioPitch,
+		"pattern":    base64.StdEncoding.EncodeToString(a.cpu.AudioBuffer[:]),
+	}
+}
+
+/*
+pollDisplay is called once per frame and returns the display payload to emit, if
+any. It applies clear coalescing and the display event throttle. Callers must
+hold a.mu.
+*/
+func (a *App) pollDisplay(now time.Time) (displayFrame, bool) {
+	if a.drawPending && !a.clearCoalescer.hold(a.cpu.ScreenCleared) {
+		a.displayThrottle.mark()
+		a.drawPending = false
+		a.cpu.ClearDrawFlag()
+	}
+	if !a.displayThrottle.ready(now) {
+		return displayFrame{}, false
+	}
+	a.framesDrawn++
+	return captureDisplay(a.cpu), true
+}
+
+/*
+displayFrame is an encoded framebuffer ready to send as a displayUpdate event.
+*/
+type displayFrame struct {
+	data   string
+	width  int
+	height int
+}
+
+/*
+captureDisplay encodes the CPU's active framebuffer, one byte per pixel holding
+the XO-CHIP plane bits (0-3; classic programs only use 0 and 1). Callers must
+hold a.mu or own the CPU.
+*/
+func captureDisplay(cpu *chip8.Chip8) displayFrame {
+	return displayFrame{
+		data:   base64.StdEncoding.EncodeToString(cpu.Pixels()),
+		width:  cpu.Width(),
+		height: cpu.Height(),
+	}
+}
+
+/*
+emitDisplay sends a displayUpdate event. The base64 pixel data comes first, so
+listeners that only read one argument keep working; width and height follow.
+*/
+func (a *App) emitDisplay(frame displayFrame) {
+	a.emit("displayUpdate", frame.data, frame.width, frame.height)
+}
+
+/*
+deferCycles carries instructions that did not fit in this frame's time budget into
+the next frame, up to one frame's worth, and warns when a run of over-budget
+frames starts.
+*/
+func (a *App) deferCycles(remaining int) {
+	a.mu.Lock()
+	a.deferredCycles = min(remaining, a.cyclesPerFrame)
+	warn := remaining > 0 && !a.overBudget
+	a.overBudget = remaining > 0
+	a.mu.Unlock()
+	if warn {
+		a.appendLog(fmt.Sprintf("Warning: frame time budget exceeded, deferring %d instructions; the clock speed may be too high for this machine.", remaining))
+	}
+}
+
+/*
+applyFrameBudget sets the per-frame time budget from a settings value in
+milliseconds; a negative value disables it. Callers must hold a.mu.
+*/
+func (a *App) applyFrameBudget(ms int) {
+	if ms < 0 {
+		ms = 0
+	}
+	a.frameBudget = time.Duration(ms) * time.Millisecond
+}
+
+/*
+applyEventRate caps displayUpdate and debugUpdate emission to perSecond events per
+second. Debug updates are additionally held to debugUpdateInterval. Callers must
+hold a.mu.
+*/
+func (a *App) applyEventRate(perSecond int) {
+	a.displayThrottle.setRate(perSecond)
+	a.debugThrottle.setRate(perSecond)
+	a.debugThrottle.setMinInterval(debugUpdateInterval)
 }
 
 func (a *App) SelectRomsDirectory() (string, error) {
@@ -168,9 +541,37 @@ func (a *App) SaveSettings(newSettings settings.Settings) error {
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
+	if err := settings.ValidatePalette(newSettings.Palette); err != nil {
+		a.appendLog(fmt.Sprintf("Invalid settings: %v", err))
+		return err
+	}
+	if _, err := parseHexColor(newSettings.DisplayColor); err != nil && newSettings.DisplayColor != "" {
+		a.appendLog(fmt.Sprintf("Invalid settings: %v", err))
+		return err
+	}
+	if err := settings.ValidateKeyMap(newSettings.KeyMap); err != nil {
+		a.appendLog(fmt.Sprintf("Invalid settings: %v", err))
+		return err
+	}
+	if err := settings.ValidateGamepadMap(newSettings.GamepadMap); err != nil {
+		a.appendLog(fmt.Sprintf("Invalid settings: %v", err))
+		return err
+	}
+	// The display colour picker and the palette both set the foreground; whichever changed wins
+	palette := settings.FillPalette(newSettings.Palette, newSettings.DisplayColor)
+	if newSettings.DisplayColor != "" && newSettings.DisplayColor != a.settings.DisplayColor {
+		palette[1] = newSettings.DisplayColor
+	}
+	newSettings.Palette = palette
+	newSettings.DisplayColor = palette[1]
+
 	if a.settings.RomsPath != newSettings.RomsPath {
 		a.appendLog(fmt.Sprintf("ROMs path changed to: %s", newSettings.RomsPath))
 		a.romLoader = roms.NewLoader(newSettings.RomsPath)
+		a.romLoader.Recursive = newSettings.ScanROMSubfolders
+		a.emit("roms:path-changed")
+	} else if a.settings.ScanROMSubfolders != newSettings.ScanROMSubfolders {
+		a.romLoader.Recursive = newSettings.ScanROMSubfolders
 		a.emit("roms:path-changed")
 	}
 
@@ -179,11 +580,110 @@ func (a *App) SaveSettings(newSettings settings.Settings) error {
 		return err
 	}
 	a.settings = newSettings
+	a.applyEventRate(newSettings.MaxEventsPerSecond)
+	a.clearCoalescer.enabled = newSettings.CoalesceClears
+	a.applyFrameBudget(newSettings.FrameBudgetMs)
+	a.cpu.Quirks = newSettings.Quirks
+	a.cpu.HaltOnUnknown = newSettings.HaltOnUnknownOpcode
+	a.cpu.HaltOnOverflow = newSettings.HaltOnMemoryOverflow
+	a.cycleTimers = newSettings.CycleAccurateTimers
+	a.autoSaver.setInterval(newSettings.AutoSaveSeconds, time.Now())
+	a.applyRewindDepth(newSettings.RewindDepth)
+	a.slowdown.enabled = newSettings.SlowMotionOnCollision
 	a.setClockSpeedInternal(newSettings.ClockSpeed)
+	a.emit("paletteUpdate", newSettings.Palette)
 	a.appendLog("Settings saved successfully.")
 	return nil
 }
 
+/*
+SetWindowDecorated switches between the frameless window and native window
+chrome and persists the choice. Wails cannot change the frame of a window that
+is already open, so when the choice changes the app relaunches itself through
+the window runtime to apply it. If that fails, the new style takes effect the
+next time the app starts, and the window:decorationChanged event says so.
+*/
+func (a *App) SetWindowDecorated(decorated bool) error {
+	a.mu.Lock()
+	changed := a.settings.WindowDecorated != decorated
+	updated := a.settings
+	updated.WindowDecorated = decorated
+	if err := a.settingsManager.Save(updated); err != nil {
+		a.mu.Unlock()
+		a.appendLog(fmt.Sprintf("Failed to write settings file: %v", err))
+		return err
+	}
+	a.settings = updated
+	a.mu.Unlock()
+
+	restartRequired := false
+	if changed {
+		if err := a.window.Relaunch(a.ctx); err != nil {
+			restartRequired = true
+			a.appendLog(fmt.Sprintf("Window decorations set to %v; restart to apply (%v).", decorated, err))
+		} else {
+			a.appendLog(fmt.Sprintf("Window decorations set to %v; relaunching to apply.", decorated))
+		}
+	}
+	a.emit("window:decorationChanged", map[string]interface{}{
+		"decorated":       decorated,
+		"restartRequired": restartRequired,
+	})
+	return nil
+}
+
+/*
+SetQuirks applies a new set of interpreter quirks to the running emulator and
+saves them to the settings file.
+*/
+func (a *App) SetQuirks(quirks chip8.Quirks) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	updated := a.settings
+	updated.Quirks = quirks
+	if presetQuirks, _ := updated.QuirkPreset.Quirks(); presetQuirks != quirks {
+		updated.QuirkPreset = ""
+	}
+	if err := a.settingsManager.Save(updated); err != nil {
+		a.appendLog(fmt.Sprintf("Failed to write settings file: %v", err))
+		return err
+	}
+	a.settings = updated
+	a.cpu.Quirks = quirks
+	a.appendLog(fmt.Sprintf("Quirks updated: %+v", quirks))
+	return nil
+}
+
+/*
+ApplyQuirkPreset sets every quirk at once from a named preset ("cosmac-vip",
+"schip-1.1" or "xo-chip"), saves the choice to the settings file, and emits a
+quirksUpdate event so the frontend can show the resulting flags.
+*/
+func (a *App) ApplyQuirkPreset(name string) error {
+	preset := chip8.QuirkPreset(name)
+	quirks, ok := preset.Quirks()
+	if !ok {
+		return fmt.Errorf("unknown quirk preset %q", name)
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	updated := a.settings
+	updated.Quirks = quirks
+	updated.QuirkPreset = preset
+	if err := a.settingsManager.Save(updated); err != nil {
+		a.appendLog(fmt.Sprintf("Failed to write settings file: %v", err))
+		return err
+	}
+	a.settings = updated
+	a.cpu.Quirks = quirks
+	a.appendLog(fmt.Sprintf("Quirk preset %s applied: %+v", preset, quirks))
+	a.emit("quirksUpdate", map[string]interface{}{
+		"preset": preset,
+		"quirks": quirks,
+	})
+	return nil
+}
+
 /*
 GetInitialState returns the current CPU state and settings for the frontend.
 */
@@ -201,23 +701,99 @@ func (a *App) GetInitialState() map[string]interface{} {
 loadROMFromData loads a ROM into the emulator and updates state.
 */
 func (a *App) loadROMFromData(data []byte, romName string) {
+	a.loadROMWith(data, romName, nil)
+}
+
+/*
+loadROMWith loads a ROM like loadROMFromData, calling setup (if not nil) with
+a.mu held after the reset and before the CPU starts, so no instruction runs
+between the two.
+*/
+func (a *App) loadROMWith(data []byte, romName string, setup func()) {
+	a.mu.RLock()
+	stripHeaders := a.settings.StripROMHeaders
+	a.mu.RUnlock()
+	if stripHeaders {
+		var header string
+		if data, header = roms.StripHeader(data); header != "" {
+			a.appendLog(fmt.Sprintf("Stripped %s header from %s", header, romName))
+		}
+	}
+	if err := roms.Validate(data); err != nil {
+		var warning *roms.Warning
+		if !errors.As(err, &warning) {
+			a.appendLog(fmt.Sprintf("Error loading ROM data %s: %v", romName, err))
+			return
+		}
+		a.appendLog(fmt.Sprintf("Warning: %s: %v", romName, err))
+		a.emit("romWarning", fmt.Sprintf("%s may not be a CHIP-8 ROM: %s", romName, strings.Join(warning.Reasons, "; ")))
+	}
+	a.mu.Lock()
 	a.cpu.Reset()
 	if err := a.cpu.LoadROM(data); err != nil {
+		a.mu.Unlock()
 		errMsg := fmt.Sprintf("Error loading ROM data %s: %v", romName, err)
 		a.appendLog(errMsg)
 		return
 	}
-	a.mu.Lock()
+	a.rewind.clear()
+	a.slowdown.reset()
 	a.romLoaded = data
-	a.isPaused = false
+	a.romName = romName
+	a.memorySnapshot = append(a.memorySnapshot[:0], a.cpu.Memory...)
+	a.demoPlayer = nil
+	a.freezeDetector.reset()
+	a.framesDrawn = 0
+	a.stepsSinceFrame = 0
+	info := a.applyROMInfo(data, romName)
+	a.recording = nil
+	if setup != nil {
+		setup()
+	}
 	a.cpu.IsRunning = true
 	a.mu.Unlock()
-	statusMsg := fmt.Sprintf("Status: Running | ROM: %s", romName)
-	a.emit("statusUpdate", statusMsg)
-	a.appendLog(statusMsg)
+	a.setStatus(fmt.Sprintf("Status: Running | ROM: %s", romName))
+	a.emit("romInfo", info)
 	a.emit("pauseUpdate", false)
 }
 
+/*
+applyROMInfo looks a ROM up in the ROM database and, if it is known, switches to
+its platform, quirks and clock speed. An unknown ROM goes back to the machine,
+quirks and clock speed the user chose, so nothing carries over from a known ROM
+loaded before it. A per-ROM clock speed from the settings takes precedence in
+both cases. It returns the payload for the romInfo event. Callers must hold a.mu.
+*/
+func (a *App) applyROMInfo(data []byte, romName string) map[string]interface{} {
+	info := map[string]interface{}{
+		"sha1":  romdb.Hash(data),
+		"known": false,
+	}
+	var entry romdb.Entry
+	known := false
+	if a.romDB != nil {
+		entry, known = a.romDB.Lookup(data)
+	}
+	speed, overridden := a.settings.ROMClockSpeeds[romName]
+	if !known {
+		a.machine = a.userMachine
+		a.cpu.Quirks = a.settings.Quirks
+		a.setROMClock(speed)
+		return info
+	}
+	info["known"] = true
+	info["title"] = entry.Title
+	info["platform"] = entry.Platform
+	a.machine = entry.Platform
+	a.cpu.Quirks = entry.EffectiveQuirks()
+	if !overridden {
+		speed = entry.ClockSpeed
+	}
+	a.setROMClock(speed)
+	a.appendLog(fmt.Sprintf("Recognised %s (%s)", entry.Title, entry.Platform))
+	return info
+}
+
 /*
 LoadROMFromFile opens a file dialog for the user to select a ROM file and loads it.
 */
@@ -242,7 +818,7 @@ func (a *App) LoadROMByPath(path string) (string, error) {
 		a.appendLog(err.Error())
 		return "", err
 	}
-	romName := filepath.Base(path)
+	romName := a.romLoader.Name(path)
 	a.loadROMFromData(data, romName)
 	return romName, nil
 }
@@ -262,252 +838,1643 @@ func (a *App) LoadROM(romName string) error {
 }
 
 /*
-GetROMs returns a list of available ROMs.
+LoadROMFromURL downloads a ROM over HTTP(S) and loads it. It is opt-in: unless
+the AllowROMURLs setting is on, it fails without touching the network.
 */
-func (a *App) GetROMs() ([]string, error) {
-	return a.romLoader.List()
+func (a *App) LoadROMFromURL(url string) (string, error) {
+	a.mu.RLock()
+	allowed := a.settings.AllowROMURLs
+	maxSize := len(a.cpu.Memory) - chip8.ProgramStart
+	a.mu.RUnlock()
+	if !allowed {
+		return "", fmt.Errorf("loading ROMs from URLs is disabled; enable it in the settings")
+	}
+	a.appendLog(fmt.Sprintf("Attempting to load ROM from URL: %s", url))
+	a.setStatus("Status: Downloading ROM...")
+	ctx := a.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	data, romName, err := a.romLoader.LoadFromURL(ctx, url, maxSize)
+	if err != nil {
+		a.appendLog(err.Error())
+		a.setStatus("Status: ROM download failed")
+		return "", err
+	}
+	a.loadROMFromData(data, romName)
+	return romName, nil
 }
 
 /*
-SoftReset reloads the currently loaded ROM, if any.
+AssembleAndLoad assembles CHIP-8 assembly source and loads the result as the
+current ROM, so edited code can be run immediately. Parse errors report the
+offending source line.
 */
-func (a *App) SoftReset() error {
-	a.mu.RLock()
-	romToLoad := a.romLoaded
-	a.mu.RUnlock()
-	if romToLoad == nil {
-		return fmt.Errorf("no ROM loaded to soft reset")
+func (a *App) AssembleAndLoad(source string) error {
+	rom, err := asm.Assemble(source)
+	if err != nil {
+		a.appendLog(fmt.Sprintf("Assembly failed: %v", err))
+		return err
 	}
-	a.loadROMFromData(romToLoad, "previously loaded ROM")
-	a.appendLog("Soft reset complete.")
+	if len(rom) == 0 {
+		return fmt.Errorf("source contains no instructions")
+	}
+	a.appendLog(fmt.Sprintf("Assembled %d bytes.", len(rom)))
+	a.loadROMFromData(rom, "assembled program")
 	return nil
 }
 
 /*
-HardReset resets the emulator state and clears the loaded ROM.
+LoadPlaylist queues ROM files to play one after another, each for the configured
+playlist duration, wrapping around at the end. The first ROM starts immediately.
 */
-func (a *App) HardReset() {
+func (a *App) LoadPlaylist(paths []string) error {
+	if len(paths) == 0 {
+		return fmt.Errorf("playlist is empty")
+	}
 	a.mu.Lock()
-	a.isPaused = true
-	a.cpu.Reset()
-	a.romLoaded = nil
+	seconds := a.settings.PlaylistSeconds
+	if seconds < 0 {
+		seconds = 0
+	}
+	a.playlist = newPlaylist(append([]string(nil), paths...), time.Duration(seconds)*time.Second, time.Now())
+	first := a.playlist.current()
 	a.mu.Unlock()
-	statusMsg := "Status: Hard Reset | ROM cleared."
-	a.appendLog(statusMsg)
-	a.emit("statusUpdate", statusMsg)
-	a.emit("pauseUpdate", true)
-	a.emit("displayUpdate", base64.StdEncoding.EncodeToString(a.cpu.Display[:]))
-	a.emit("debugUpdate", a.cpu.GetState())
+	a.appendLog(fmt.Sprintf("Playlist loaded with %d ROM(s)", len(paths)))
+	_, err := a.LoadROMByPath(first)
+	return err
 }
 
 /*
-TogglePause toggles the paused state of the emulator.
+NextInPlaylist skips to the next ROM in the playlist.
 */
-func (a *App) TogglePause() bool {
+func (a *App) NextInPlaylist() (string, error) {
 	a.mu.Lock()
-	a.isPaused = !a.isPaused
-	a.cpu.IsRunning = !a.isPaused
-	isPausedNow := a.isPaused
-	a.mu.Unlock()
-	if isPausedNow {
-		a.appendLog("Emulation Paused.")
-	} else {
-		a.appendLog("Emulation Resumed.")
+	if a.playlist == nil {
+		a.mu.Unlock()
+		return "", fmt.Errorf("no playlist loaded")
 	}
-	a.emit("pauseUpdate", isPausedNow)
-	return isPausedNow
+	next := a.playlist.advance(time.Now())
+	a.mu.Unlock()
+	return a.LoadROMByPath(next)
 }
 
 /*
-GetMemory returns a base64-encoded slice of memory from the emulator.
+StopPlaylist stops automatic advancing; the current ROM keeps running.
 */
-func (a *App) GetMemory(offset, limit int) string {
-	a.mu.RLock()
-	defer a.mu.RUnlock()
-	memLen := len(a.cpu.Memory)
-	if offset < 0 || limit <= 0 || offset >= memLen {
-		return ""
-	}
-	if offset+limit > memLen {
-		limit = memLen - offset
-	}
-	return base64.StdEncoding.EncodeToString(a.cpu.Memory[offset : offset+limit])
+func (a *App) StopPlaylist() {
+	a.mu.Lock()
+	a.playlist = nil
+	a.mu.Unlock()
+	a.appendLog("Playlist stopped")
 }
 
 /*
-SetClockSpeed updates the emulator's clock speed.
+LoadNextROM loads the ROM after the current one in the ROMs directory listing,
+wrapping around to the first.
 */
-func (a *App) SetClockSpeed(speed int) {
-	a.mu.Lock()
-	defer a.mu.Unlock()
+func (a *App) LoadNextROM() (string, error) {
+	return a.loadAdjacentROM(1)
+}
 
-	a.setClockSpeedInternal(speed)
+/*
+LoadPreviousROM loads the ROM before the current one in the ROMs directory
+listing, wrapping around to the last.
+*/
+func (a *App) LoadPreviousROM() (string, error) {
+	return a.loadAdjacentROM(-1)
 }
 
-func (a *App) setClockSpeedInternal(speed int) {
-	if speed > 0 {
-		a.cpuSpeed = time.Second / time.Duration(speed)
-		if a.settings.ClockSpeed != speed {
+func (a *App) loadAdjacentROM(delta int) (string, error) {
+	names, err := a.romLoader.List()
+	if err != nil {
+		return "", err
+	}
+	a.mu.RLock()
+	current := a.romName
+	a.mu.RUnlock()
+	idx := adjacentIndex(names, current, delta)
+	if idx < 0 {
+		return "", fmt.Errorf("no ROMs found in %s", a.romLoader.RomsDir)
+	}
+	if err := a.LoadROM(names[idx]); err != nil {
+		return "", err
+	}
+	return names[idx], nil
+}
+
+/*
+SuggestMachineType scans the loaded ROM for SUPER-CHIP and XO-CHIP marker opcodes
+and recommends a machine type and quirk set. The suggestion is advisory and is
+also sent to the frontend as a machineSuggestion event.
+*/
+func (a *App) SuggestMachineType() (map[string]interface{}, error) {
+	a.mu.RLock()
+	rom := a.romLoaded
+	a.mu.RUnlock()
+	if rom == nil {
+		return nil, fmt.Errorf("no ROM loaded to analyse")
+	}
+	machine := chip8.DetectMachine(rom)
+	suggestion := map[string]interface{}{
+		"machine": machine,
+		"quirks":  chip8.MachineQuirks(machine),
+	}
+	a.appendLog(fmt.Sprintf("ROM looks like it targets %s.", machine))
+	a.emit("machineSuggestion", suggestion)
+	return suggestion, nil
+}
+
+/*
+GetROMs returns a list of available ROMs.
+*/
+func (a *App) GetROMs() ([]string, error) {
+	return a.romLoader.List()
+}
+
+/*
+GetBundledROMs returns the names of the example ROMs built into the app. They
+are listed separately from the ROMs directory.
+*/
+func (a *App) GetBundledROMs() []string {
+	return roms.Bundled()
+}
+
+/*
+LoadBundledROM loads one of the built-in example ROMs by name.
+*/
+func (a *App) LoadBundledROM(name string) error {
+	data, err := roms.LoadBundled(name)
+	if err != nil {
+		a.appendLog(err.Error())
+		return err
+	}
+	a.loadROMFromData(data, "built-in: "+name)
+	return nil
+}
+
+/*
+SoftReset reloads the currently loaded ROM, if any.
+*/
+func (a *App) SoftReset() error {
+	a.mu.RLock()
+	romToLoad, romName := a.romLoaded, a.romName
+	a.mu.RUnlock()
+	if romToLoad == nil {
+		return fmt.Errorf("no ROM loaded to soft reset")
+	}
+	a.loadROMWith(romToLoad, romName, func() {
+		if a.pendingDemo != nil {
+			if a.pendingDemo.HasSeed {
+				a.cpu.SetSeed(a.pendingDemo.Seed)
+			}
+			a.demoPlayer = demo.NewPlayer(a.pendingDemo.Events)
+			a.pendingDemo = nil
+			a.appendLog("Demo playback started.")
+		}
+	})
+	a.appendLog("Soft reset complete.")
+	return nil
+}
+
+/*
+StartInputRecording soft-resets the loaded ROM with a fresh RNG seed and starts
+recording key presses against the CPU cycle counter. Loading another ROM stops
+the recording.
+*/
+func (a *App) StartInputRecording() error {
+	a.mu.RLock()
+	romToLoad, romName := a.romLoaded, a.romName
+	a.mu.RUnlock()
+	if romToLoad == nil {
+		return fmt.Errorf("no ROM loaded to record")
+	}
+	seed := time.Now().UnixNano()
+	a.loadROMWith(romToLoad, romName, func() {
+		a.cpu.SetSeed(seed)
+		a.recording = &demo.Demo{Seed: seed, HasSeed: true}
+	})
+	a.appendLog("Input recording started.")
+	return nil
+}
+
+/*
+StopInputRecording stops recording and returns the key events captured since
+StartInputRecording. Key events already sent are applied first, so they are
+included. The recording, with its RNG seed, is kept for PlayInputRecording and
+ExportInputRecording.
+*/
+func (a *App) StopInputRecording() []InputEvent {
+	a.applyKeyEvents()
+	a.mu.Lock()
+	rec := a.recording
+	a.recording = nil
+	if rec != nil {
+		a.lastRecording = rec
+	}
+	a.mu.Unlock()
+	if rec == nil {
+		return nil
+	}
+	a.appendLog(fmt.Sprintf("Input recording stopped with %d events.", len(rec.Events)))
+	return append([]InputEvent(nil), rec.Events...)
+}
+
+/*
+PlayInputRecording soft-resets the loaded ROM and replays the given key events
+at their recorded cycles. The RNG seed of the last recording is reused, so
+replaying events returned by StopInputRecording reproduces the run exactly.
+*/
+func (a *App) PlayInputRecording(events []InputEvent) error {
+	if err := demo.ValidateEvents(events); err != nil {
+		return err
+	}
+	d := &demo.Demo{Events: events}
+	a.mu.Lock()
+	if a.lastRecording != nil {
+		d.Seed, d.HasSeed = a.lastRecording.Seed, a.lastRecording.HasSeed
+	}
+	a.pendingDemo = d
+	a.mu.Unlock()
+	return a.SoftReset()
+}
+
+/*
+ExportInputRecording returns the last recording, including its RNG seed, as a
+base64-encoded demo that LoadDemo accepts.
+*/
+func (a *App) ExportInputRecording() (string, error) {
+	a.mu.RLock()
+	rec := a.lastRecording
+	a.mu.RUnlock()
+	if rec == nil {
+		return "", fmt.Errorf("no input recording to export")
+	}
+	data, err := demo.Encode(*rec)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+/*
+recordInput appends a key transition to the active recording, if any. Callers
+must hold a.mu.
+*/
+func (a *App) recordInput(key int, down bool) {
+	if a.recording != nil {
+		a.recording.Events = append(a.recording.Events, InputEvent{Cycle: a.cpu.CycleCount, Key: key, Down: down})
+	}
+}
+
+/*
+LoadDemo decodes a base64-encoded demo (a recorded input log) and queues it
+for deterministic playback starting from the next soft reset.
+*/
+func (a *App) LoadDemo(encoded string) error {
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return fmt.Errorf("failed to decode demo: %w", err)
+	}
+	d, err := demo.Decode(data)
+	if err != nil {
+		a.appendLog(err.Error())
+		return err
+	}
+	a.mu.Lock()
+	a.pendingDemo = d
+	a.mu.Unlock()
+	a.appendLog(fmt.Sprintf("Demo with %d input events queued; soft reset to play it.", len(d.Events)))
+	return nil
+}
+
+/*
+applyDemoInput feeds any demo events due at the current cycle into the keypad.
+*/
+func (a *App) applyDemoInput() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.demoPlayer == nil {
+		return
+	}
+	for _, ev := range a.demoPlayer.Due(a.cpu.CycleCount) {
+		a.cpu.Keys[ev.Key] = ev.Down
+	}
+	if a.demoPlayer.Done() {
+		a.demoPlayer = nil
+		a.appendLog("Demo playback finished.")
+	}
+}
+
+/*
+HardReset resets the emulator state and clears the loaded ROM.
+*/
+func (a *App) HardReset() {
+	a.mu.Lock()
+	a.cpu.Reset()
+	a.rewind.clear()
+	a.romLoaded = nil
+	a.romName = ""
+	a.framesDrawn = 0
+	frame := captureDisplay(a.cpu)
+	state := a.cpu.GetState()
+	a.mu.Unlock()
+	a.setStatus("Status: Hard Reset | ROM cleared.")
+	a.emit("pauseUpdate", true)
+	a.emitDisplay(frame)
+	a.emit("debugUpdate", state)
+}
+
+/*
+GetSessionStats summarises the current run since the ROM was loaded: instructions
+executed, frames sent to the display, peak stack depth, how many different
+instructions the ROM used, and whether it stopped on a fault.
+*/
+func (a *App) GetSessionStats() map[string]interface{} {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return map[string]interface{}{
+		"cycles":          a.cpu.CycleCount,
+		"framesDrawn":     a.framesDrawn,
+		"peakStackDepth":  a.cpu.StackHighWater,
+		"distinctOpcodes": a.cpu.DistinctOpcodes(),
+		"faulted":         a.cpu.LastError != "",
+		"lastError":       a.cpu.LastError,
+	}
+}
+
+/*
+TogglePause toggles the paused state of the emulator.
+*/
+func (a *App) TogglePause() bool {
+	a.mu.Lock()
+	a.cpu.IsRunning = !a.cpu.IsRunning
+	isPausedNow := a.paused()
+	if isPausedNow {
+		a.silence()
+	} else {
+		// Resuming moves on from whatever watchpoint stopped the CPU
+		a.cpu.WatchpointHit = false
+	}
+	a.mu.Unlock()
+	if isPausedNow {
+		a.emit("soundStop")
+		a.setStatus("Status: Paused")
+	} else {
+		a.setStatus("Status: Running")
+	}
+	a.emit("pauseUpdate", isPausedNow)
+	return isPausedNow
+}
+
+/*
+silence forgets the tone the frontend was playing, for when emulation pauses and
+the frontend is told to stop it. On resume a sound timer still running counts
+as a new tone, so it starts playing again. Callers must hold a.mu.
+*/
+func (a *App) silence() {
+	a.soundTimer = 0
+	a.tone = tone{}
+}
+
+/*
+paused reports whether emulation is paused. The CPU's IsRunning flag is the only
+record of this, so a breakpoint, watchpoint or fault that stops the CPU pauses
+the app too and the timers stop with it. Callers must hold a.mu.
+*/
+func (a *App) paused() bool {
+	return !a.cpu.IsRunning
+}
+
+/*
+GetMemory returns a base64-encoded slice of memory from the emulator.
+*/
+func (a *App) GetMemory(offset, limit int) string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	memLen := len(a.cpu.Memory)
+	if offset < 0 || limit <= 0 || offset >= memLen {
+		return ""
+	}
+	if offset+limit > memLen {
+		limit = memLen - offset
+	}
+	return base64.StdEncoding.EncodeToString(a.cpu.Memory[offset : offset+limit])
+}
+
+/*
+PeekMemory returns the byte at address.
+*/
+func (a *App) PeekMemory(address uint16) (byte, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if int(address) >= len(a.cpu.Memory) {
+		return 0, fmt.Errorf("address 0x%04X is outside memory (0x%04X bytes)", address, len(a.cpu.Memory))
+	}
+	return a.cpu.Memory[address], nil
+}
+
+/*
+PokeMemory writes value to address, for patching memory from the debugger. The
+write bypasses watchpoints and write protection, and shows up in the next debug
+update and in the memory change mask.
+*/
+func (a *App) PokeMemory(address uint16, value byte) error {
+	a.mu.Lock()
+	if int(address) >= len(a.cpu.Memory) {
+		size := len(a.cpu.Memory)
+		a.mu.Unlock()
+		return fmt.Errorf("address 0x%04X is outside memory (0x%04X bytes)", address, size)
+	}
+	old := a.cpu.Memory[address]
+	a.cpu.Memory[address] = value
+	a.mu.Unlock()
+	a.appendLog(fmt.Sprintf("Memory 0x%04X poked: 0x%02X -> 0x%02X", address, old, value))
+	return nil
+}
+
+/*
+SetRegister changes a CPU register from the debugger: "V0".."VF", "I", "PC",
+"SP", "DT" or "ST", case-insensitively. Byte registers take 0-255, SP at most the
+stack depth, and I and PC must point inside memory. It emits a debugUpdate with
+the new state.
+*/
+func (a *App) SetRegister(name string, value uint16) error {
+	a.mu.Lock()
+	err := setRegister(a.cpu, strings.ToUpper(name), value)
+	var state map[string]interface{}
+	if err == nil {
+		state = a.cpu.GetState()
+	}
+	a.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	a.appendLog(fmt.Sprintf("Register %s set to 0x%X", strings.ToUpper(name), value))
+	a.emit("debugUpdate", state)
+	return nil
+}
+
+/*
+setRegister applies SetRegister to cpu after validating the value.
+*/
+func setRegister(cpu *chip8.Chip8, name string, value uint16) error {
+	byteValue := func() (byte, error) {
+		if value > 0xFF {
+			return 0, fmt.Errorf("value 0x%X does not fit in 8-bit register %s", value, name)
+		}
+		return byte(value), nil
+	}
+	switch name {
+	case "I", "PC":
+		if int(value) >= cpu.MemorySize() {
+			return fmt.Errorf("%s 0x%X is outside memory (0x%X bytes)", name, value, cpu.MemorySize())
+		}
+		if name == "I" {
+			cpu.I = value
+		} else {
+			cpu.PC = value
+		}
+		return nil
+	case "SP":
+		if int(value) > len(cpu.Stack) {
+			return fmt.Errorf("SP %d exceeds the stack depth of %d", value, len(cpu.Stack))
+		}
+		cpu.SP = byte(value)
+		return nil
+	case "DT", "ST":
+		b, err := byteValue()
+		if err != nil {
+			return err
+		}
+		if name == "DT" {
+			cpu.DelayTimer = b
+		} else {
+			cpu.SoundTimer = b
+		}
+		return nil
+	}
+	if len(name) == 2 && name[0] == 'V' {
+		if reg, err := strconv.ParseUint(name[1:], 16, 8); err == nil {
+			b, err := byteValue()
+			if err != nil {
+				return err
+			}
+			cpu.Registers[reg] = b
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown register %q", name)
+}
+
+/*
+GetMemoryChangeMask returns a base64-encoded bitmap of which bytes in the given
+range changed since the last debug update, one bit per byte (least significant
+bit first). It takes the same offset and limit as GetMemory.
+*/
+func (a *App) GetMemoryChangeMask(offset, limit int) string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	memLen := len(a.cpu.Memory)
+	if offset < 0 || limit <= 0 || offset >= memLen {
+		return ""
+	}
+	if offset+limit > memLen {
+		limit = memLen - offset
+	}
+	mask := changeMask(a.memorySnapshot[offset:offset+limit], a.cpu.Memory[offset:offset+limit])
+	return base64.StdEncoding.EncodeToString(mask)
+}
+
+/*
+SetORDrawing switches sprite drawing between normal XOR and a diagnostic OR mode
+that never erases pixels, showing the union of everything drawn. It is off by
+default and not saved with the settings.
+*/
+func (a *App) SetORDrawing(enabled bool) {
+	a.mu.Lock()
+	a.cpu.ORDraw = enabled
+	a.mu.Unlock()
+	if enabled {
+		a.appendLog("Diagnostic OR drawing enabled: sprites no longer erase or collide.")
+	} else {
+		a.appendLog("Diagnostic OR drawing disabled.")
+	}
+}
+
+/*
+GetMemoryMap describes the memory layout as contiguous labelled regions, each with
+an inclusive start and end address and a type of "reserved", "font", "program"
+or "free".
+*/
+func (a *App) GetMemoryMap() []map[string]interface{} {
+	a.mu.RLock()
+	regions := memoryRegions(len(a.romLoaded), len(a.cpu.Memory))
+	a.mu.RUnlock()
+	out := make([]map[string]interface{}, 0, len(regions))
+	for _, r := range regions {
+		out = append(out, map[string]interface{}{
+			"start": r.Start,
+			"end":   r.End,
+			"type":  r.Type,
+			"label": r.Label,
+		})
+	}
+	return out
+}
+
+/*
+GetDisassembly returns a full listing of the loaded ROM, with labels on jump and
+call destinations, so the frontend can show the whole program rather than the
+window around the PC.
+*/
+func (a *App) GetDisassembly() []chip8.Instruction {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return chip8.DisassembleProgram(a.romLoaded, chip8.ProgramStart, a.cpu.Quirks)
+}
+
+/*
+GetStructuredDisassembly returns count instructions from memory starting at start,
+each split into mnemonic and operands so the frontend can highlight them.
+*/
+func (a *App) GetStructuredDisassembly(start, count uint16) []chip8.Line {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return chip8.DisassembleLines(a.cpu.Memory[:], start, count, a.cpu.Quirks)
+}
+
+/*
+GetInstructionAtPC returns the next instruction to execute with its fields
+decoded, so the debugger inspector does not have to parse disassembly strings.
+*/
+func (a *App) GetInstructionAtPC() chip8.InstructionInfo {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.cpu.InstructionAtPC()
+}
+
+/*
+GetOpcodeReference returns the instruction set reference (pattern, mnemonic and
+description of every opcode) for the in-app help panel.
+*/
+func (a *App) GetOpcodeReference() []map[string]string {
+	return chip8.OpcodeReference()
+}
+
+/*
+DecodeOpcode returns the named fields of an opcode (x, y, n, nn, nnn, ...) for
+the frontend's instruction breakdown overlay.
+*/
+func (a *App) DecodeOpcode(opcode uint16) map[string]uint16 {
+	return chip8.DecodeFields(opcode)
+}
+
+/*
+IsCodeAddress reports whether address falls within the loaded ROM image, so the
+disassembly view can tell program bytes from empty memory.
+*/
+func (a *App) IsCodeAddress(address uint16) bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return int(address) >= chip8.ProgramStart && int(address) < chip8.ProgramStart+len(a.romLoaded)
+}
+
+/*
+EvalWatch evaluates a debugger watch expression such as "V3 + V4" or "mem[I]"
+against the current emulator state.
+*/
+func (a *App) EvalWatch(expression string) (int, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return expr.Eval(expression, a.cpu)
+}
+
+/*
+GetKeyForChip8Key returns the keyboard key bound to the given CHIP-8 key (0x0-0xF)
+in the current key map. If several keyboard keys map to it, the alphabetically
+first is returned so the answer is stable.
+*/
+func (a *App) GetKeyForChip8Key(chip8Key int) (string, error) {
+	if chip8Key < 0 || chip8Key > 0xF {
+		return "", fmt.Errorf("CHIP-8 key %d out of range", chip8Key)
+	}
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	keys := settings.ReverseKeyMap(a.settings.KeyMap)[chip8Key]
+	if len(keys) == 0 {
+		return "", fmt.Errorf("CHIP-8 key 0x%X is not bound", chip8Key)
+	}
+	return keys[0], nil
+}
+
+/*
+GetReverseKeyMap returns the keyboard keys bound to each CHIP-8 key in the
+current key map, each list sorted alphabetically.
+*/
+func (a *App) GetReverseKeyMap() map[int][]string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return settings.ReverseKeyMap(a.settings.KeyMap)
+}
+
+/*
+SetSpeedMultiplier runs emulation at a multiple of the configured clock speed,
+snapped to one of 0.25, 0.5, 1, 2 or 4, without changing the saved setting. The
+timers tick at the same multiple, so a ROM runs as it would at normal speed, only
+faster or slower; 4 serves as turbo and 1 releases it. It returns the multiplier
+applied and emits it in a turboUpdate event.
+*/
+func (a *App) SetSpeedMultiplier(multiplier float64) float64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.speedMultiplier = clampMultiplier(multiplier)
+	if speed := a.clockSpeed(); speed > 0 {
+		a.applyClock(speed)
+	}
+	a.emit("turboUpdate", a.speedMultiplier)
+	a.appendLog(fmt.Sprintf("Speed multiplier set to %gx", a.speedMultiplier))
+	return a.speedMultiplier
+}
+
+/*
+SetMachineType switches the emulated platform ("chip8", "schip" or "xochip") and
+applies that platform's default clock speed from the settings, unless the current
+ROM has its own clock speed override. A platform missing from the settings gets
+the built-in default.
+*/
+func (a *App) SetMachineType(machine string) error {
+	m := chip8.Machine(machine)
+	if !m.Valid() {
+		return fmt.Errorf("unknown machine type %q", machine)
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.machine = m
+	a.userMachine = m
+	speed, overridden := a.settings.ROMClockSpeeds[a.romName]
+	if !overridden {
+		speed = a.settings.MachineClockSpeeds[machine]
+		if speed <= 0 {
+			speed = settings.DefaultMachineClockSpeeds()[machine]
+		}
+	}
+	a.setClockSpeedInternal(speed)
+	a.appendLog(fmt.Sprintf("Machine type set to %s", m))
+	return nil
+}
+
+/*
+SetClockSpeed updates the emulator's clock speed.
+*/
+func (a *App) SetClockSpeed(speed int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.setClockSpeedInternal(speed)
+}
+
+/*
+applyClock sets the instructions run per frame for a clock speed in Hz at the
+current speed multiplier. Callers must hold a.mu.
+*/
+func (a *App) applyClock(clockHz int) {
+	a.cyclesPerFrame = cyclesPerFrame(clockHz, a.speedMultiplier)
+	a.frameClock.set(clockHz, a.speedMultiplier)
+	a.timerClock.set(chip8.TimerFrequency, a.speedMultiplier)
+	a.cycleTimer.set(clockHz)
+}
+
+/*
+clockSpeed returns the clock speed in Hz the emulator runs at: the loaded ROM's
+own speed if it has one, otherwise the one in the settings. Callers must hold
+a.mu.
+*/
+func (a *App) clockSpeed() int {
+	if a.romClock > 0 {
+		return a.romClock
+	}
+	return a.settings.ClockSpeed
+}
+
+/*
+setROMClock runs the emulator at a clock speed chosen for the loaded ROM without
+changing the one in the settings; 0 goes back to the settings. Callers must hold
+a.mu.
+*/
+func (a *App) setROMClock(speed int) {
+	a.romClock = speed
+	if speed := a.clockSpeed(); speed > 0 {
+		a.applyClock(speed)
+		a.emit("clockSpeedUpdate", speed)
+	}
+}
+
+func (a *App) setClockSpeedInternal(speed int) {
+	if speed > 0 {
+		a.romClock = 0
+		a.applyClock(speed)
+		if a.settings.ClockSpeed != speed {
 			a.settings.ClockSpeed = speed
 		}
-		a.emit("clockSpeedUpdate", speed)
-		a.appendLog(fmt.Sprintf("Clock speed set to %d Hz", speed))
+		a.emit("clockSpeedUpdate", speed)
+		a.appendLog(fmt.Sprintf("Clock speed set to %d Hz", speed))
+	}
+}
+
+/*
+emit sends an event to the frontend if the context is available.
+*/
+func (a *App) emit(eventName string, data ...interface{}) {
+	if a.ctx == nil {
+		return
+	}
+	runtime.EventsEmit(a.ctx, eventName, data...)
+}
+
+/*
+appendLog adds a message to the log buffer and prints it.
+*/
+func (a *App) appendLog(msg string) {
+	a.logMutex.Lock()
+	defer a.logMutex.Unlock()
+	log.Println(msg)
+	if len(a.logBuffer) >= 100 {
+		a.logBuffer = a.logBuffer[1:]
+	}
+	a.logBuffer = append(a.logBuffer, time.Now().Format("15:04:05")+" | "+msg)
+}
+
+/*
+setStatus records a change of emulator status: it is appended to the status
+history and the log, and sent to the frontend as a statusUpdate event.
+*/
+func (a *App) setStatus(msg string) {
+	a.logMutex.Lock()
+	if len(a.statusHistory) >= statusHistorySize {
+		a.statusHistory = a.statusHistory[1:]
+	}
+	a.statusHistory = append(a.statusHistory, time.Now().Format("15:04:05")+" | "+msg)
+	a.logMutex.Unlock()
+	a.appendLog(msg)
+	a.emit("statusUpdate", msg)
+}
+
+/*
+GetStatusHistory returns a copy of the recent status changes, oldest first.
+*/
+func (a *App) GetStatusHistory() []string {
+	a.logMutex.Lock()
+	defer a.logMutex.Unlock()
+	historyCopy := make([]string, len(a.statusHistory))
+	copy(historyCopy, a.statusHistory)
+	return historyCopy
+}
+
+/*
+GetLogs returns a copy of the current log buffer.
+*/
+func (a *App) GetLogs() []string {
+	a.logMutex.Lock()
+	defer a.logMutex.Unlock()
+	logsCopy := make([]string, len(a.logBuffer))
+	copy(logsCopy, a.logBuffer)
+	return logsCopy
+}
+
+/*
+KeyDown queues a press of the specified key. Key events reach the keypad in the
+order they were sent, between instructions, and a key released before the
+emulation loop picked up its press still reads as down for at least one
+instruction. Events sent while paused are applied once per frame, so the keypad
+is current on resume.
+*/
+func (a *App) KeyDown(key int) {
+	if key >= 0 && key < 16 {
+		a.queueKey(keyEvent{key: key, down: true})
+	}
+}
+
+/*
+KeyUp queues a release of the specified key, with the same ordering as KeyDown.
+*/
+func (a *App) KeyUp(key int) {
+	if key >= 0 && key < 16 {
+		a.queueKey(keyEvent{key: key, down: false})
+	}
+}
+
+/*
+queueKey hands a key event to the emulation loop. If the buffer is full the
+backlog is applied here first, so no event is dropped and none overtakes another.
+*/
+func (a *App) queueKey(ev keyEvent) {
+	for !a.keys.push(ev) {
+		a.applyKeyEvents()
+	}
+}
+
+/*
+applyKeyEvents applies the queued key events due at this instruction boundary
+to the keypad, recording them if input is being recorded. A press also wakes a
+halted ROM.
+*/
+func (a *App) applyKeyEvents() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, ev := range a.keys.take() {
+		a.cpu.Keys[ev.key] = ev.down
+		if ev.down {
+			a.cpu.Halted = false
+		}
+		a.recordInput(ev.key, ev.down)
+	}
+}
+
+/*
+SetGamepadState takes the full set of gamepad buttons currently held, keyed by
+the names in settings.GamepadButtons, and presses or releases the CHIP-8 keys
+they are bound to in the gamepad map. Only keys whose gamepad state changed
+since the last call are touched, so the keyboard keeps working alongside a pad.
+Unbound buttons are ignored.
+*/
+func (a *App) SetGamepadState(buttons map[string]bool) {
+	var held [16]bool
+	a.mu.Lock()
+	for button, pressed := range buttons {
+		if key, ok := a.settings.GamepadMap[button]; ok && pressed && key >= 0 && key < 16 {
+			held[key] = true
+		}
+	}
+	previous := a.gamepadKeys
+	a.gamepadKeys = held
+	a.mu.Unlock()
+
+	for key := range held {
+		switch {
+		case held[key] && !previous[key]:
+			a.KeyDown(key)
+		case !held[key] && previous[key]:
+			a.KeyUp(key)
+		}
+	}
+}
+
+/*
+StartDebugUpdates enables debug state updates.
+*/
+func (a *App) StartDebugUpdates() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.isDebugging = true
+	a.appendLog("Debug view activated.")
+}
+
+/*
+StopDebugUpdates disables debug state updates.
+*/
+func (a *App) StopDebugUpdates() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.isDebugging = false
+	a.appendLog("Debug view deactivated.")
+}
+
+/*
+LoadStateFromFile loads a saved emulator state from a file.
+*/
+func (a *App) LoadStateFromFile() error {
+	selection, err := runtime.OpenFileDialog(a.ctx, runtime.OpenDialogOptions{
+		Title:   "Load CHIP-8 State",
+		Filters: []runtime.FileFilter{{DisplayName: "CHIP-8 State (*.ch8state)", Pattern: "*.ch8state"}},
+	})
+	if err != nil || selection == "" {
+		return err
+	}
+	if err := a.loadStateFromPath(selection); err != nil {
+		runtime.MessageDialog(a.ctx, runtime.MessageDialogOptions{
+			Type:    runtime.ErrorDialog,
+			Title:   "Cannot Load State",
+			Message: err.Error(),
+		})
+		return err
+	}
+	return nil
+}
+
+/*
+loadStateFromPath reads a saved state file and restores it, keeping the loaded ROM.
+*/
+func (a *App) loadStateFromPath(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read state file: %w", err)
+	}
+	loadedCPU, err := decodeState(data)
+	if err != nil {
+		a.appendLog(fmt.Sprintf("Error loading state: %v", err))
+		return err
+	}
+	a.mu.Lock()
+	romLoaded := a.romLoaded
+	a.mu.Unlock()
+	a.restoreState(loadedCPU, romLoaded)
+	a.appendLog("State loaded successfully. Forcing UI refresh.")
+	return nil
+}
+
+/*
+handleFileDrop is the window's file drop handler. It loads the first dropped
+file and reports failures, including unsupported file types, in an error dialog.
+*/
+func (a *App) handleFileDrop(x, y int, paths []string) {
+	if len(paths) == 0 {
+		return
+	}
+	name, err := a.loadDroppedFile(paths[0])
+	if err != nil {
+		runtime.MessageDialog(a.ctx, runtime.MessageDialogOptions{
+			Type:    runtime.ErrorDialog,
+			Title:   "Cannot Load Dropped File",
+			Message: err.Error(),
+		})
+		return
+	}
+	a.emit("fileDropped", name)
+}
+
+/*
+loadDroppedFile routes a dropped file by extension: ROMs (.ch8, .c8) load through
+LoadROMByPath and save states (.ch8state) through the state loader. Other files
+are rejected before anything is read.
+*/
+func (a *App) loadDroppedFile(path string) (string, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".ch8", ".c8":
+		return a.LoadROMByPath(path)
+	case ".ch8state":
+		if err := a.loadStateFromPath(path); err != nil {
+			return "", err
+		}
+		return filepath.Base(path), nil
+	}
+	err := fmt.Errorf("unsupported file %s: drop a .ch8 or .c8 ROM or a .ch8state save state", filepath.Base(path))
+	a.appendLog(err.Error())
+	return "", err
+}
+
+/*
+SaveStateToFile saves the current emulator state to a file.
+*/
+func (a *App) SaveStateToFile() error {
+	a.mu.Lock()
+	a.cpu.IsRunning = false
+	snapshot := a.cpu.Clone()
+	a.mu.Unlock()
+
+	data, err := encodeState(snapshot)
+	if err != nil {
+		return err
+	}
+	selection, err := runtime.SaveFileDialog(a.ctx, runtime.SaveDialogOptions{
+		Title:           "Save CHIP-8 State",
+		Filters:         []runtime.FileFilter{{DisplayName: "CHIP-8 State (*.ch8state)", Pattern: "*.ch8state"}},
+		DefaultFilename: "chip8_state.ch8state",
+	})
+	if err != nil || selection == "" {
+		return err
+	}
+	if err := ioutil.WriteFile(selection, data, 0644); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	a.appendLog(fmt.Sprintf("State saved to: %s", selection))
+	return nil
+}
+
+/*
+SetDryRunDrawing switches sprite drawing to a diagnostic collision-only mode: DRW
+still reports collisions in VF but leaves the screen untouched, showing where
+sprites would hit without altering the display. It is off by default and not
+saved with the settings.
+*/
+func (a *App) SetDryRunDrawing(enabled bool) {
+	a.mu.Lock()
+	a.cpu.DryRunDraw = enabled
+	a.mu.Unlock()
+	if enabled {
+		a.appendLog("Diagnostic dry-run drawing enabled: sprites report collisions without drawing.")
+	} else {
+		a.appendLog("Diagnostic dry-run drawing disabled.")
+	}
+}
+
+/*
+SetTracing turns recording of recently executed instructions on or off.
+*/
+func (a *App) SetTracing(enabled bool) {
+	a.mu.Lock()
+	a.cpu.SetTracing(enabled)
+	a.mu.Unlock()
+}
+
+/*
+GetTrace returns the most recently executed instructions, oldest first, or nil
+when tracing is off.
+*/
+func (a *App) GetTrace() []chip8.TraceEntry {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.cpu.Trace()
+}
+
+/*
+ExportTrace writes the execution trace to a file chosen in a save dialog, as JSON
+lines if the file name ends in .jsonl and as CSV otherwise.
+*/
+func (a *App) ExportTrace() error {
+	a.mu.RLock()
+	entries := a.cpu.Trace()
+	a.mu.RUnlock()
+	if entries == nil {
+		return fmt.Errorf("tracing is not enabled")
+	}
+
+	selection, err := runtime.SaveFileDialog(a.ctx, runtime.SaveDialogOptions{
+		Title: "Export Execution Trace",
+		Filters: []runtime.FileFilter{
+			{DisplayName: "CSV (*.csv)", Pattern: "*.csv"},
+			{DisplayName: "JSON Lines (*.jsonl)", Pattern: "*.jsonl"},
+		},
+		DefaultFilename: "chip8_trace.csv",
+	})
+	if err != nil || selection == "" {
+		return err
+	}
+
+	var data []byte
+	if strings.EqualFold(filepath.Ext(selection), ".jsonl") {
+		if data, err = chip8.FormatTraceJSONL(entries); err != nil {
+			return fmt.Errorf("failed to format trace: %w", err)
+		}
+	} else {
+		data = chip8.FormatTraceCSV(entries)
 	}
+	if err := ioutil.WriteFile(selection, data, 0644); err != nil {
+		return fmt.Errorf("failed to write trace file: %w", err)
+	}
+	a.appendLog(fmt.Sprintf("Exported %d trace entries to: %s", len(entries), selection))
+	return nil
 }
 
 /*
-emit sends an event to the frontend if the context is available.
+ExportPackage bundles the loaded ROM and the current state into a .c8pkg file.
 */
-func (a *App) emit(eventName string, data ...interface{}) {
-	if a.ctx == nil {
-		return
+func (a *App) ExportPackage() error {
+	a.mu.Lock()
+	rom := a.romLoaded
+	a.cpu.IsRunning = false
+	snapshot := a.cpu.Clone()
+	a.mu.Unlock()
+	a.emit("pauseUpdate", true)
+	if rom == nil {
+		return fmt.Errorf("no ROM loaded to export")
 	}
-	runtime.EventsEmit(a.ctx, eventName, data...)
+
+	state, err := encodeState(snapshot)
+	if err != nil {
+		return err
+	}
+	selection, err := runtime.SaveFileDialog(a.ctx, runtime.SaveDialogOptions{
+		Title:           "Export CHIP-8 Package",
+		Filters:         []runtime.FileFilter{{DisplayName: "CHIP-8 Package (*.c8pkg)", Pattern: "*.c8pkg"}},
+		DefaultFilename: "chip8_package.c8pkg",
+	})
+	if err != nil || selection == "" {
+		return err
+	}
+	if err := ioutil.WriteFile(selection, c8pkg.Encode(c8pkg.Package{ROM: rom, State: state}), 0644); err != nil {
+		return fmt.Errorf("failed to write package file: %w", err)
+	}
+	a.appendLog(fmt.Sprintf("Package exported to: %s", selection))
+	return nil
 }
 
 /*
-appendLog adds a message to the log buffer and prints it.
+packageState bundles the loaded ROM and current CPU state as a .c8pkg file, or
+returns nil if the state cannot be encoded. Callers must hold a.mu.
 */
-func (a *App) appendLog(msg string) {
-	a.logMutex.Lock()
-	defer a.logMutex.Unlock()
-	log.Println(msg)
-	if len(a.logBuffer) >= 100 {
-		a.logBuffer = a.logBuffer[1:]
+func (a *App) packageState() []byte {
+	state, err := encodeState(a.cpu)
+	if err != nil {
+		return nil
 	}
-	a.logBuffer = append(a.logBuffer, time.Now().Format("15:04:05")+" | "+msg)
+	return c8pkg.Encode(c8pkg.Package{ROM: a.romLoaded, State: state})
 }
 
 /*
-GetLogs returns a copy of the current log buffer.
+SetAutoSaveInterval writes a rolling auto-save of the ROM and state every
+seconds seconds while a ROM is running, keeping the newest few. 0 disables it.
+The interval is saved with the settings.
 */
-func (a *App) GetLogs() []string {
-	a.logMutex.Lock()
-	defer a.logMutex.Unlock()
-	logsCopy := make([]string, len(a.logBuffer))
-	copy(logsCopy, a.logBuffer)
-	return logsCopy
+func (a *App) SetAutoSaveInterval(seconds int) error {
+	if seconds < 0 {
+		return fmt.Errorf("auto-save interval must not be negative")
+	}
+	a.mu.Lock()
+	a.autoSaver.setInterval(seconds, time.Now())
+	a.settings.AutoSaveSeconds = seconds
+	s := a.settings
+	a.mu.Unlock()
+	if seconds == 0 {
+		a.appendLog("Auto-save disabled.")
+	} else {
+		a.appendLog(fmt.Sprintf("Auto-saving every %d seconds.", seconds))
+	}
+	return a.settingsManager.Save(s)
 }
 
 /*
-KeyDown sets the specified key as pressed.
+GetLatestAutoSave returns the time of the newest auto-save as RFC 3339, or "" if
+there is none, so the frontend can offer to restore it on startup.
 */
-func (a *App) KeyDown(key int) {
-	if key >= 0 && key < 16 {
-		a.cpu.Keys[key] = true
+func (a *App) GetLatestAutoSave() (string, error) {
+	path, err := latestAutoSave(a.autoSaveDir)
+	if err != nil || path == "" {
+		return "", err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
 	}
+	return info.ModTime().Format(time.RFC3339), nil
 }
 
 /*
-KeyUp sets the specified key as released.
+RestoreAutoSave loads the newest auto-save, restoring both its ROM and its state.
 */
-func (a *App) KeyUp(key int) {
-	if key >= 0 && key < 16 {
-		a.cpu.Keys[key] = false
+func (a *App) RestoreAutoSave() error {
+	path, err := latestAutoSave(a.autoSaveDir)
+	if err != nil {
+		return err
+	}
+	if path == "" {
+		return fmt.Errorf("no auto-save found")
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read auto-save: %w", err)
+	}
+	pkg, err := c8pkg.Decode(data)
+	if err != nil {
+		return err
+	}
+	loadedCPU, err := decodeState(pkg.State)
+	if err != nil {
+		return err
 	}
+	a.restoreState(loadedCPU, pkg.ROM)
+	a.appendLog(fmt.Sprintf("Auto-save restored from: %s", path))
+	return nil
 }
 
 /*
-StartDebugUpdates enables debug state updates.
+ImportPackage loads a .c8pkg file, restoring both its ROM and its saved state.
 */
-func (a *App) StartDebugUpdates() {
+func (a *App) ImportPackage() error {
+	selection, err := runtime.OpenFileDialog(a.ctx, runtime.OpenDialogOptions{
+		Title:   "Import CHIP-8 Package",
+		Filters: []runtime.FileFilter{{DisplayName: "CHIP-8 Package (*.c8pkg)", Pattern: "*.c8pkg"}},
+	})
+	if err != nil || selection == "" {
+		return err
+	}
+	data, err := ioutil.ReadFile(selection)
+	if err != nil {
+		return fmt.Errorf("failed to read package file: %w", err)
+	}
+	pkg, err := c8pkg.Decode(data)
+	if err != nil {
+		a.appendLog(fmt.Sprintf("Error importing package: %v", err))
+		return err
+	}
+	loadedCPU, err := decodeState(pkg.State)
+	if err != nil {
+		return err
+	}
+	a.restoreState(loadedCPU, pkg.ROM)
+	a.setStatus(fmt.Sprintf("Status: Paused | Package: %s", filepath.Base(selection)))
+	a.appendLog(fmt.Sprintf("Package imported from: %s", selection))
+	return nil
+}
+
+/*
+restoreState swaps in a previously saved CPU, paused, and refreshes the UI.
+Protected ranges and opcode hooks, which save states do not record, carry over
+from the CPU it replaces, and the per-run frame and halt tracking starts over.
+*/
+func (a *App) restoreState(cpu *chip8.Chip8, rom []byte) {
 	a.mu.Lock()
-	defer a.mu.Unlock()
-	a.isDebugging = true
-	a.appendLog("Debug view activated.")
+	cpu.IsRunning = false
+	cpu.HaltOnUnknown = a.settings.HaltOnUnknownOpcode
+	cpu.HaltOnOverflow = a.settings.HaltOnMemoryOverflow
+	cpu.StopOnHalt = true
+	cpu.CopySetup(a.cpu)
+	a.cpu = cpu
+	a.stepsSinceFrame = 0
+	a.halted = false
+	a.lastError = ""
+	a.freezeDetector.reset()
+	a.rewind.clear()
+	a.romLoaded = rom
+	a.memorySnapshot = append(a.memorySnapshot[:0], cpu.Memory...)
+	a.demoPlayer = nil
+	frame := captureDisplay(cpu)
+	state := cpu.GetState()
+	a.mu.Unlock()
+	a.emitDisplay(frame)
+	a.emit("debugUpdate", state)
+	a.emit("pauseUpdate", true)
 }
 
 /*
-StopDebugUpdates disables debug state updates.
+encodeState serialises a CPU snapshot for save states and packages, as
+versioned JSON.
 */
-func (a *App) StopDebugUpdates() {
+func encodeState(cpu *chip8.Chip8) ([]byte, error) {
+	return savestate.EncodeJSON(cpu)
+}
+
+/*
+decodeState restores a CPU snapshot written by encodeState, or by older builds
+that saved states with gob.
+*/
+func decodeState(data []byte) (*chip8.Chip8, error) {
+	loadedCPU, err := savestate.Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	if loadedCPU.Breakpoints == nil {
+		loadedCPU.Breakpoints = make(map[uint16]bool)
+	}
+	return loadedCPU, nil
+}
+
+/*
+SaveStateJSON pauses emulation and returns the current state as versioned JSON,
+readable by external tooling.
+*/
+func (a *App) SaveStateJSON() ([]byte, error) {
 	a.mu.Lock()
-	defer a.mu.Unlock()
-	a.isDebugging = false
-	a.appendLog("Debug view deactivated.")
+	a.cpu.IsRunning = false
+	data, err := savestate.EncodeJSON(a.cpu)
+	a.mu.Unlock()
+	a.emit("pauseUpdate", true)
+	return data, err
 }
 
 /*
-LoadStateFromFile loads a saved emulator state from a file.
+LoadStateJSON restores a state produced by SaveStateJSON, keeping the loaded ROM.
 */
-func (a *App) LoadStateFromFile() error {
-	selection, err := runtime.OpenFileDialog(a.ctx, runtime.OpenDialogOptions{
-		Title:   "Load CHIP-8 State",
-		Filters: []runtime.FileFilter{{DisplayName: "CHIP-8 State (*.ch8state)", Pattern: "*.ch8state"}},
-	})
-	if err != nil || selection == "" {
+func (a *App) LoadStateJSON(data []byte) error {
+	loadedCPU, err := decodeState(data)
+	if err != nil {
 		return err
 	}
-	data, err := ioutil.ReadFile(selection)
+	a.mu.Lock()
+	romLoaded := a.romLoaded
+	a.mu.Unlock()
+	a.restoreState(loadedCPU, romLoaded)
+	a.appendLog("State loaded from JSON.")
+	return nil
+}
+
+/*
+SetBreakpoint sets a breakpoint at the given address.
+*/
+func (a *App) SetBreakpoint(address uint16) {
+	a.mu.Lock()
+	a.cpu.SetBreakpointSkip(address, 0)
+	delete(a.cpu.BreakConditions, address)
+	a.mu.Unlock()
+	a.appendLog(fmt.Sprintf("Breakpoint set at 0x%04X", address))
+}
+
+/*
+SetConditionalBreakpoint sets a breakpoint at the given address that only halts
+when the comparison V[register] op value holds. op is one of ==, !=, >, <, >= or <=.
+*/
+func (a *App) SetConditionalBreakpoint(address uint16, register byte, op string, value byte) error {
+	a.mu.Lock()
+	err := a.cpu.SetConditionalBreakpoint(address, register, op, value)
+	a.mu.Unlock()
 	if err != nil {
-		return fmt.Errorf("failed to read state file: %w", err)
+		return err
 	}
+	a.appendLog(fmt.Sprintf("Breakpoint set at 0x%04X when V%X %s 0x%02X", address, register, op, value))
+	return nil
+}
+
+/*
+Step executes exactly one instruction while paused, ticking the timers each time a
+frame's worth of instructions (at the current clock speed) has been stepped, then
+emits fresh debug and display updates. It does nothing if the emulator is running
+or no ROM is loaded.
+*/
+func (a *App) Step() {
 	a.mu.Lock()
-	defer a.mu.Unlock()
-	a.isPaused = true
-	a.cpu.IsRunning = false
-	var loadedCPU chip8.Chip8
-	if err := gob.NewDecoder(bytes.NewBuffer(data)).Decode(&loadedCPU); err != nil {
-		return fmt.Errorf("failed to decode CPU state: %w", err)
+	if !a.paused() || a.romLoaded == nil {
+		a.mu.Unlock()
+		return
 	}
-	a.cpu = &loadedCPU
-	a.appendLog("State loaded successfully. Forcing UI refresh.")
-	a.emit("displayUpdate", base64.StdEncoding.EncodeToString(a.cpu.Display[:]))
-	a.emit("debugUpdate", a.cpu.GetState())
-	a.emit("pauseUpdate", true)
-	return nil
+	a.rewind.push(a.cpu)
+	a.cpu.Step()
+	a.stepsSinceFrame++
+	if a.stepsSinceFrame >= a.cyclesPerFrame {
+		a.stepsSinceFrame = 0
+		a.cpu.UpdateTimers()
+	}
+	frame := captureDisplay(a.cpu)
+	state := a.cpu.GetState()
+	a.mu.Unlock()
+	a.emit("debugUpdate", state)
+	a.emitDisplay(frame)
 }
 
 /*
-SaveStateToFile saves the current emulator state to a file.
+StepFrame runs the rest of the current 60Hz frame while paused: instructions up
+to the frame's cycle count at the current clock, counting any already taken with
+Step, then one timer tick. A DRW under the DisplayWait quirk ends the frame early,
+as it would when running. A breakpoint, watchpoint, fault or self-jump halt stops
+it before the timer tick, leaving the rest of the frame for the next call. It
+does nothing while running or with no ROM loaded.
 */
-func (a *App) SaveStateToFile() error {
+func (a *App) StepFrame() {
 	a.mu.Lock()
-	a.isPaused = true
+	if !a.paused() || a.romLoaded == nil {
+		a.mu.Unlock()
+		return
+	}
+	stopped := false
+	a.cpu.IsRunning = true
+	for a.stepsSinceFrame < a.cyclesPerFrame && !a.cpu.WaitingForVBlank() {
+		a.rewind.push(a.cpu)
+		res := a.cpu.EmulateCycle()
+		a.stepsSinceFrame++
+		if res.Halted || res.Err != nil {
+			stopped = true
+			break
+		}
+	}
 	a.cpu.IsRunning = false
+	if !stopped {
+		a.stepsSinceFrame = 0
+		a.cpu.UpdateTimers()
+	}
+	frame := captureDisplay(a.cpu)
+	state := a.cpu.GetState()
 	a.mu.Unlock()
+	a.emit("debugUpdate", state)
+	a.emitDisplay(frame)
+}
 
-	var buf bytes.Buffer
-	if err := gob.NewEncoder(&buf).Encode(a.cpu); err != nil {
-		return fmt.Errorf("failed to encode CPU state: %w", err)
+/*
+RunUntil runs from a paused state until PC reaches address, as if a temporary
+breakpoint were set there. It stops early on a real breakpoint, a watchpoint, a
+fault or a self-jump halt, and gives up after the configured maximum number of
+instructions. Timers tick once per frame's worth of instructions, as with Step.
+The lock is released between frames, so the frontend stays responsive during a
+long run; if the emulator is resumed or a ROM loaded meanwhile, RunUntil stops.
+It returns why it stopped: "target", "breakpoint", "watchpoint", "fault",
+"halted", "timeout" or "interrupted". Unless interrupted, the emulator is left
+paused.
+*/
+func (a *App) RunUntil(address uint16) (string, error) {
+	a.mu.Lock()
+	if !a.paused() || a.romLoaded == nil {
+		a.mu.Unlock()
+		return "", fmt.Errorf("emulator must be paused with a ROM loaded")
 	}
-	selection, err := runtime.SaveFileDialog(a.ctx, runtime.SaveDialogOptions{
-		Title:           "Save CHIP-8 State",
-		Filters:         []runtime.FileFilter{{DisplayName: "CHIP-8 State (*.ch8state)", Pattern: "*.ch8state"}},
-		DefaultFilename: "chip8_state.ch8state",
-	})
-	if err != nil || selection == "" {
-		return err
+	reason := "timeout"
+	a.cpu.WatchpointHit = false
+	for total := 0; total < a.settings.RunUntilMaxCycles; {
+		ran, stop := a.runUntilFrame(address, a.settings.RunUntilMaxCycles-total)
+		total += ran
+		if stop != "" {
+			reason = stop
+			break
+		}
+		a.mu.Unlock()
+		a.mu.Lock()
+		if !a.paused() || a.romLoaded == nil {
+			reason = "interrupted"
+			break
+		}
 	}
-	if err := ioutil.WriteFile(selection, buf.Bytes(), 0644); err != nil {
-		return fmt.Errorf("failed to write state file: %w", err)
+	frame := captureDisplay(a.cpu)
+	state := a.cpu.GetState()
+	a.mu.Unlock()
+	a.appendLog(fmt.Sprintf("Run until 0x%04X stopped at 0x%04X (%s)", address, state["PC"], reason))
+	a.emit("debugUpdate", state)
+	a.emitDisplay(frame)
+	return reason, nil
+}
+
+/*
+runUntilFrame runs instructions for RunUntil up to the end of the current frame,
+at most limit of them, ticking the timers first if the previous frame is over.
+It returns how many ran and why it stopped, or "" if it reached the end of the
+frame or the limit first. The CPU is paused again on return. Callers must hold
+a.mu.
+*/
+func (a *App) runUntilFrame(address uint16, limit int) (int, string) {
+	if a.cpu.WaitingForVBlank() || a.stepsSinceFrame >= a.cyclesPerFrame {
+		a.stepsSinceFrame = 0
+		a.cpu.UpdateTimers()
 	}
-	a.appendLog(fmt.Sprintf("State saved to: %s", selection))
-	return nil
+	a.cpu.IsRunning = true
+	defer func() { a.cpu.IsRunning = false }()
+	for ran := 1; ran <= limit; ran++ {
+		a.rewind.push(a.cpu)
+		res := a.cpu.EmulateCycle()
+		a.stepsSinceFrame++
+		if res.Halted {
+			switch {
+			case a.cpu.Halted:
+				return ran, "halted"
+			case a.cpu.WatchpointHit:
+				return ran, "watchpoint"
+			case res.Err != nil:
+				return ran, "fault"
+			default:
+				return ran, "breakpoint"
+			}
+		}
+		if a.cpu.PC == address {
+			return ran, "target"
+		}
+		if a.cpu.WaitingForVBlank() || a.stepsSinceFrame >= a.cyclesPerFrame {
+			return ran, ""
+		}
+	}
+	return limit, ""
 }
 
 /*
-SetBreakpoint sets a breakpoint at the given address.
+StepWithOverride executes a single instruction with register V[reg] temporarily set
+to value, e.g. to try the other side of a branch. Side effects of the instruction
+persist, but V[reg] reverts to its previous value unless the instruction wrote a
+different value to it. The emulator is left paused.
 */
-func (a *App) SetBreakpoint(address uint16) {
-	if a.cpu != nil {
-		a.mu.Lock()
-		a.cpu.Breakpoints[address] = true
-		a.mu.Unlock()
-		a.appendLog(fmt.Sprintf("Breakpoint set at 0x%04X", address))
+func (a *App) StepWithOverride(reg int, value byte) error {
+	a.mu.Lock()
+	a.cpu.IsRunning = false
+	err := a.cpu.StepWithOverride(reg, value)
+	frame := captureDisplay(a.cpu)
+	state := a.cpu.GetState()
+	a.mu.Unlock()
+	if err != nil {
+		return err
 	}
+	a.emit("pauseUpdate", true)
+	a.emitDisplay(frame)
+	a.emit("debugUpdate", state)
+	return nil
+}
+
+/*
+SetBreakpointWithSkip sets a breakpoint at the given address that only halts once
+the address has been reached skip times, i.e. on hit skip+1 and after. Hit counts
+start over when a ROM is loaded.
+*/
+func (a *App) SetBreakpointWithSkip(address uint16, skip int) {
+	a.mu.Lock()
+	a.cpu.SetBreakpointSkip(address, skip)
+	a.mu.Unlock()
+	a.appendLog(fmt.Sprintf("Breakpoint set at 0x%04X, skipping %d hit(s)", address, skip))
 }
 
 /*
 ClearBreakpoint removes a breakpoint at the given address.
 */
 func (a *App) ClearBreakpoint(address uint16) {
-	if a.cpu != nil {
-		a.mu.Lock()
-		delete(a.cpu.Breakpoints, address)
+	a.mu.Lock()
+	delete(a.cpu.Breakpoints, address)
+	delete(a.cpu.BreakpointSkips, address)
+	delete(a.cpu.BreakConditions, address)
+	a.mu.Unlock()
+	a.appendLog(fmt.Sprintf("Breakpoint cleared at 0x%04X", address))
+}
+
+/*
+StepBack undoes the last executed instruction by restoring the most recent
+rewind snapshot, then emits fresh debug and display updates. Breakpoints,
+watchpoints and quirks keep their current settings. It does nothing unless the
+emulator is paused, and returns false once the rewind history is exhausted.
+*/
+func (a *App) StepBack() bool {
+	a.mu.Lock()
+	if !a.paused() {
+		a.mu.Unlock()
+		return false
+	}
+	snap, ok := a.rewind.pop()
+	if !ok {
 		a.mu.Unlock()
-		a.appendLog(fmt.Sprintf("Breakpoint cleared at 0x%04X", address))
+		return false
+	}
+	snap.Breakpoints = a.cpu.Breakpoints
+	snap.BreakpointSkips = a.cpu.BreakpointSkips
+	snap.BreakConditions = a.cpu.BreakConditions
+	snap.Watchpoints = a.cpu.Watchpoints
+	snap.Quirks = a.cpu.Quirks
+	snap.IsRunning = false
+	*a.cpu = snap
+	if a.stepsSinceFrame > 0 {
+		a.stepsSinceFrame--
+	}
+	frame := captureDisplay(a.cpu)
+	state := a.cpu.GetState()
+	a.mu.Unlock()
+	a.emit("debugUpdate", state)
+	a.emitDisplay(frame)
+	return true
+}
+
+/*
+applyRewindDepth resizes the rewind history, dropping what it held. Callers must
+hold a.mu.
+*/
+func (a *App) applyRewindDepth(depth int) {
+	if a.rewind != nil && len(a.rewind.snapshots) == depth {
+		return
+	}
+	a.rewind = newRewindBuffer(depth)
+}
+
+/*
+SetWatchpoint pauses emulation whenever the ROM writes to the given address.
+*/
+func (a *App) SetWatchpoint(address uint16) {
+	a.mu.Lock()
+	if a.cpu.Watchpoints == nil {
+		a.cpu.Watchpoints = make(map[uint16]bool)
+	}
+	a.cpu.Watchpoints[address] = true
+	a.mu.Unlock()
+	a.appendLog(fmt.Sprintf("Watchpoint set at 0x%04X", address))
+}
+
+/*
+ClearWatchpoint removes the watchpoint at the given address.
+*/
+func (a *App) ClearWatchpoint(address uint16) {
+	a.mu.Lock()
+	delete(a.cpu.Watchpoints, address)
+	if a.cpu.WatchpointHit && a.cpu.WatchpointAddr == address {
+		a.cpu.WatchpointHit = false
 	}
+	a.mu.Unlock()
+	a.appendLog(fmt.Sprintf("Watchpoint cleared at 0x%04X", address))
 }
 
 /*
@@ -545,6 +2512,40 @@ func (a *App) OpenGitHubLink() {
 	runtime.BrowserOpenURL(a.ctx, a.wailsInfo.Info.ProjectURL)
 }
 
+/*
+ExportFramebufferPNG renders the current display (64x32 or 128x64) to a PNG
+without going through the frontend. A scale below 1 uses the configured pixel
+scale, and an empty fg or bg the configured palette's foreground or background.
+*/
+func (a *App) ExportFramebufferPNG(scale int, fg, bg string) ([]byte, error) {
+	a.mu.RLock()
+	pixels := a.cpu.Pixels()
+	width, height := a.cpu.Width(), a.cpu.Height()
+	if scale < 1 {
+		scale = a.settings.PixelScale
+	}
+	palette := settings.FillPalette(a.settings.Palette, a.settings.DisplayColor)
+	a.mu.RUnlock()
+	if scale < 1 {
+		scale = 10
+	}
+	if fg == "" {
+		fg = palette[1]
+	}
+	if bg == "" {
+		bg = palette[0]
+	}
+	fgColor, err := parseHexColor(fg)
+	if err != nil {
+		return nil, err
+	}
+	bgColor, err := parseHexColor(bg)
+	if err != nil {
+		return nil, err
+	}
+	return renderPNG(pixels, width, height, scale, fgColor, bgColor)
+}
+
 /*
 SaveScreenshot saves a base64-encoded PNG screenshot to a file.
 */
@@ -0,0 +1,1519 @@
+package main
+
+import (
+	"bytes"
+	"chip8-wails/chip8"
+	"chip8-wails/internal/demo"
+	"chip8-wails/internal/romdb"
+	"chip8-wails/internal/roms"
+	"chip8-wails/internal/settings"
+	"context"
+	"encoding/base64"
+	"image/png"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+/*
+TestStatusHistoryOrder loads a ROM, pauses, and hard resets, and checks that each
+step pushed its own status entry in order.
+*/
+func TestStatusHistoryOrder(t *testing.T) {
+	a := NewApp()
+	a.loadROMFromData([]byte{0x12, 0x00}, "loop.ch8")
+	a.TogglePause()
+	a.HardReset()
+
+	history := a.GetStatusHistory()
+	want := []string{"ROM: loop.ch8", "Status: Paused", "Hard Reset"}
+	if len(history) != len(want) {
+		t.Fatalf("Expected %d status entries, got %d: %v", len(want), len(history), history)
+	}
+	for i, w := range want {
+		if !strings.Contains(history[i], w) {
+			t.Errorf("Entry %d: expected %q in %q", i, w, history[i])
+		}
+	}
+}
+
+/*
+fakeWindow records relaunches instead of going through the Wails runtime.
+*/
+type fakeWindow struct {
+	relaunches int
+}
+
+func (w *fakeWindow) Relaunch(ctx context.Context) error {
+	w.relaunches++
+	return nil
+}
+
+/*
+TestSetWindowDecoratedPersists checks that the window decoration choice is written
+to the settings file and read back on the next load, and that the window is
+relaunched to apply it only when it changes.
+*/
+func TestSetWindowDecoratedPersists(t *testing.T) {
+	a := NewApp()
+	a.settingsManager = settings.NewManager(filepath.Join(t.TempDir(), "settings.json"))
+	a.settings = settings.DefaultSettings()
+	window := &fakeWindow{}
+	a.window = window
+
+	if err := a.SetWindowDecorated(true); err != nil {
+		t.Fatalf("SetWindowDecorated failed: %v", err)
+	}
+	if window.relaunches != 1 {
+		t.Errorf("Expected one relaunch to apply the new frame, got %d", window.relaunches)
+	}
+	if err := a.SetWindowDecorated(true); err != nil {
+		t.Fatalf("SetWindowDecorated failed: %v", err)
+	}
+	if window.relaunches != 1 {
+		t.Errorf("Expected no relaunch when the frame is unchanged, got %d", window.relaunches)
+	}
+
+	loaded, err := a.settingsManager.Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if !loaded.WindowDecorated {
+		t.Error("Expected WindowDecorated to be persisted as true")
+	}
+	if loaded.ClockSpeed != a.settings.ClockSpeed {
+		t.Errorf("Expected other settings to be preserved, got clock speed %d", loaded.ClockSpeed)
+	}
+}
+
+/*
+TestSuggestMachineTypeSCHIP loads a ROM using the SUPER-CHIP hi-res opcode and
+checks that SuggestMachineType recommends "schip".
+*/
+func TestSuggestMachineTypeSCHIP(t *testing.T) {
+	a := NewApp()
+	if _, err := a.SuggestMachineType(); err == nil {
+		t.Error("Expected an error with no ROM loaded")
+	}
+
+	a.loadROMFromData([]byte{0x00, 0xFF, 0x00, 0xE0, 0x12, 0x04}, "hires.ch8")
+	suggestion, err := a.SuggestMachineType()
+	if err != nil {
+		t.Fatalf("SuggestMachineType failed: %v", err)
+	}
+	if suggestion["machine"] != chip8.MachineSCHIP {
+		t.Errorf("Expected schip recommendation, got %v", suggestion["machine"])
+	}
+}
+
+/*
+TestGetSessionStats runs a short known program and checks the reported cycle,
+frame and stack counts, and that loading a ROM resets them.
+*/
+func TestGetSessionStats(t *testing.T) {
+	a := NewApp()
+	rom := []byte{
+		0x00, 0xE0, // 0x200: CLS
+		0x22, 0x08, // 0x202: CALL 0x208
+		0xD0, 0x15, // 0x204: DRW V0, V1, 5
+		0x12, 0x06, // 0x206: JP 0x206
+		0x00, 0xEE, // 0x208: RET
+	}
+	a.loadROMFromData(rom, "stats.ch8")
+	for i := 0; i < 6; i++ {
+		a.runCycle(&timerSpreader{})
+	}
+	a.pollDisplay(time.Unix(1, 0))
+
+	stats := a.GetSessionStats()
+	if stats["cycles"] != uint64(5) { // The sixth cycle stalls on the halt at 0x206
+		t.Errorf("Expected 5 cycles, got %v", stats["cycles"])
+	}
+	if stats["framesDrawn"] != uint64(1) {
+		t.Errorf("Expected 1 frame drawn, got %v", stats["framesDrawn"])
+	}
+	if stats["peakStackDepth"] != byte(1) {
+		t.Errorf("Expected peak stack depth 1, got %v", stats["peakStackDepth"])
+	}
+	if stats["distinctOpcodes"] != 5 {
+		t.Errorf("Expected 5 distinct opcodes, got %v", stats["distinctOpcodes"])
+	}
+	if stats["faulted"] != false {
+		t.Error("Expected no fault")
+	}
+
+	a.loadROMFromData(rom, "stats.ch8")
+	stats = a.GetSessionStats()
+	if stats["cycles"] != uint64(0) || stats["framesDrawn"] != uint64(0) {
+		t.Errorf("Expected stats to reset on ROM load, got %v", stats)
+	}
+}
+
+/*
+TestGetMemoryChangeMask writes one memory cell after a ROM load and checks that
+only that cell is flagged in the mask.
+*/
+func TestGetMemoryChangeMask(t *testing.T) {
+	a := NewApp()
+	a.loadROMFromData([]byte{0x12, 0x00}, "loop.ch8")
+	a.cpu.Memory[0x305] = 0xAB
+
+	encoded := a.GetMemoryChangeMask(0x300, 16)
+	mask, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		t.Fatalf("Failed to decode mask: %v", err)
+	}
+	if len(mask) != 2 || mask[0] != 1<<5 || mask[1] != 0 {
+		t.Errorf("Expected mask [00100000 00000000], got %08b", mask)
+	}
+}
+
+/*
+TestPeekPokeMemory checks that a poked byte reads back, is logged, and that
+addresses past the end of memory are rejected.
+*/
+func TestPeekPokeMemory(t *testing.T) {
+	a := NewApp()
+	if err := a.PokeMemory(0x300, 0xAB); err != nil {
+		t.Fatalf("PokeMemory failed: %v", err)
+	}
+	if got, err := a.PeekMemory(0x300); err != nil || got != 0xAB {
+		t.Errorf("Expected to read back 0xAB, got 0x%02X (%v)", got, err)
+	}
+	if logs := a.GetLogs(); len(logs) == 0 || !strings.Contains(logs[len(logs)-1], "0x0300") {
+		t.Errorf("Expected the edit to be logged, got %v", logs)
+	}
+	if err := a.PokeMemory(0x1000, 1); err == nil {
+		t.Error("Expected an error for an address past the end of memory")
+	}
+	if _, err := a.PeekMemory(0xFFFF); err == nil {
+		t.Error("Expected an error for an address past the end of memory")
+	}
+}
+
+/*
+TestSetRegister checks each kind of register name, case-insensitively, and that
+out-of-range values and unknown names are rejected.
+*/
+func TestSetRegister(t *testing.T) {
+	a := NewApp()
+	for name, value := range map[string]uint16{"v3": 0x42, "VF": 0xFF, "I": 0x3FF, "pc": 0x250, "SP": 2, "DT": 9, "st": 7} {
+		if err := a.SetRegister(name, value); err != nil {
+			t.Errorf("SetRegister(%q, 0x%X) failed: %v", name, value, err)
+		}
+	}
+	c := a.cpu
+	if c.Registers[0x3] != 0x42 || c.Registers[0xF] != 0xFF || c.I != 0x3FF || c.PC != 0x250 || c.SP != 2 || c.DelayTimer != 9 || c.SoundTimer != 7 {
+		t.Errorf("Expected every register to be set, got %+v", c.GetState())
+	}
+
+	for _, bad := range []struct {
+		name  string
+		value uint16
+	}{{"V0", 0x100}, {"DT", 0x100}, {"SP", 17}, {"I", 0x1000}, {"VG", 1}, {"Q", 1}} {
+		if err := a.SetRegister(bad.name, bad.value); err == nil {
+			t.Errorf("Expected SetRegister(%q, 0x%X) to fail", bad.name, bad.value)
+		}
+	}
+}
+
+/*
+TestEvalWatch checks that watch expressions see the live CPU state.
+*/
+func TestEvalWatch(t *testing.T) {
+	a := NewApp()
+	a.cpu.Registers[3] = 7
+	a.cpu.Registers[4] = 8
+
+	got, err := a.EvalWatch("V3 + V4")
+	if err != nil {
+		t.Fatalf("EvalWatch failed: %v", err)
+	}
+	if got != 15 {
+		t.Errorf("Expected 15, got %d", got)
+	}
+	if _, err := a.EvalWatch("V3 +"); err == nil {
+		t.Error("Expected an error for an incomplete expression")
+	}
+}
+
+/*
+TestIsCodeAddress checks the ROM span boundaries reported by IsCodeAddress.
+*/
+func TestIsCodeAddress(t *testing.T) {
+	a := NewApp()
+	a.loadROMFromData([]byte{0x00, 0xE0, 0x12, 0x02}, "tiny.ch8")
+
+	for addr, want := range map[uint16]bool{
+		0x1FF: false,
+		0x200: true,
+		0x203: true,
+		0x204: false,
+		0xFFF: false,
+	} {
+		if got := a.IsCodeAddress(addr); got != want {
+			t.Errorf("IsCodeAddress(0x%03X): expected %v, got %v", addr, want, got)
+		}
+	}
+}
+
+/*
+TestSetQuirksAppliesAndPersists checks that SetQuirks reaches the CPU and the
+settings file, and that a settings file without quirks loads the COSMAC defaults.
+*/
+func TestSetQuirksAppliesAndPersists(t *testing.T) {
+	a := NewApp()
+	path := filepath.Join(t.TempDir(), "settings.json")
+	a.settingsManager = settings.NewManager(path)
+	a.settings = settings.DefaultSettings()
+
+	quirks := chip8.Quirks{ShiftFlagLast: true}
+	if err := a.SetQuirks(quirks); err != nil {
+		t.Fatalf("SetQuirks failed: %v", err)
+	}
+	if a.cpu.Quirks != quirks {
+		t.Errorf("Expected CPU quirks %+v, got %+v", quirks, a.cpu.Quirks)
+	}
+	loaded, err := a.settingsManager.Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if loaded.Quirks != quirks {
+		t.Errorf("Expected persisted quirks %+v, got %+v", quirks, loaded.Quirks)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"clockSpeed": 500}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err = a.settingsManager.Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if loaded.Quirks != chip8.DefaultQuirks() {
+		t.Errorf("Expected default quirks for an old settings file, got %+v", loaded.Quirks)
+	}
+}
+
+/*
+TestApplyQuirkPreset checks that a preset sets every quirk, survives a reload of
+the settings file, and is dropped once a quirk is changed individually.
+*/
+func TestApplyQuirkPreset(t *testing.T) {
+	a := NewApp()
+	a.settingsManager = settings.NewManager(filepath.Join(t.TempDir(), "settings.json"))
+	a.settings = settings.DefaultSettings()
+
+	if err := a.ApplyQuirkPreset("xo-chip"); err != nil {
+		t.Fatalf("ApplyQuirkPreset failed: %v", err)
+	}
+	want, _ := chip8.PresetXOCHIP.Quirks()
+	if a.cpu.Quirks != want {
+		t.Errorf("Expected CPU quirks %+v, got %+v", want, a.cpu.Quirks)
+	}
+	loaded, err := a.settingsManager.Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if loaded.QuirkPreset != chip8.PresetXOCHIP || loaded.Quirks != want {
+		t.Errorf("Expected the persisted XO-CHIP preset, got %q %+v", loaded.QuirkPreset, loaded.Quirks)
+	}
+
+	if err := a.SetQuirks(chip8.Quirks{}); err != nil {
+		t.Fatalf("SetQuirks failed: %v", err)
+	}
+	if a.settings.QuirkPreset != "" {
+		t.Errorf("Expected custom quirks to clear the preset, got %q", a.settings.QuirkPreset)
+	}
+	if err := a.ApplyQuirkPreset("nes"); err == nil {
+		t.Error("Expected an error for an unknown preset")
+	}
+}
+
+/*
+TestSetMachineTypeClockSpeed checks that switching machine type applies the
+type's default clock speed, falling back to the built-in one for a type missing
+from the settings, and that a per-ROM override takes precedence, also after a
+soft reset.
+*/
+func TestSetMachineTypeClockSpeed(t *testing.T) {
+	a := NewApp()
+	a.settings = settings.DefaultSettings()
+	a.settings.MachineClockSpeeds = map[string]int{"chip8": 600, "xochip": 1800}
+
+	if err := a.SetMachineType("xochip"); err != nil {
+		t.Fatalf("SetMachineType failed: %v", err)
+	}
+	if a.settings.ClockSpeed != 1800 {
+		t.Errorf("Expected XO-CHIP default of 1800 Hz, got %d", a.settings.ClockSpeed)
+	}
+
+	if err := a.SetMachineType("schip"); err != nil {
+		t.Fatalf("SetMachineType failed: %v", err)
+	}
+	if want := settings.DefaultMachineClockSpeeds()["schip"]; a.settings.ClockSpeed != want {
+		t.Errorf("Expected the built-in SUPER-CHIP default of %d Hz, got %d", want, a.settings.ClockSpeed)
+	}
+
+	a.loadROMFromData([]byte{0x12, 0x00}, "game.ch8")
+	a.settings.ROMClockSpeeds = map[string]int{"game.ch8": 450}
+	if err := a.SoftReset(); err != nil {
+		t.Fatalf("SoftReset failed: %v", err)
+	}
+	if err := a.SetMachineType("schip"); err != nil {
+		t.Fatalf("SetMachineType failed: %v", err)
+	}
+	if a.settings.ClockSpeed != 450 {
+		t.Errorf("Expected the per-ROM override of 450 Hz, got %d", a.settings.ClockSpeed)
+	}
+
+	if err := a.SetMachineType("nes"); err == nil {
+		t.Error("Expected an error for an unknown machine type")
+	}
+}
+
+/*
+TestSaveStateRoundTripsHiRes checks that the resolution flag survives a save state.
+*/
+func TestSaveStateRoundTripsHiRes(t *testing.T) {
+	cpu := chip8.New()
+	cpu.HiRes = true
+	data, err := encodeState(cpu)
+	if err != nil {
+		t.Fatalf("encodeState failed: %v", err)
+	}
+	restored, err := decodeState(data)
+	if err != nil {
+		t.Fatalf("decodeState failed: %v", err)
+	}
+	if !restored.HiRes || restored.Width() != chip8.HiResWidth {
+		t.Error("Expected the restored CPU to be in hi-res mode")
+	}
+}
+
+/*
+TestRestoreStateKeepsSetup checks that restoring a save state keeps the current
+protected ranges and opcode hooks and resets the per-run counters.
+*/
+func TestRestoreStateKeepsSetup(t *testing.T) {
+	a := NewApp()
+	a.settings = settings.DefaultSettings()
+	a.loadROMFromData([]byte{0x00, 0xE0, 0x12, 0x00}, "setup.ch8")
+	a.cpu.ProtectRange(0x300, 0x300)
+	ran := false
+	a.cpu.RegisterOpcodeHandler(0xFFFF, 0x0123, func(c *chip8.Chip8, opcode uint16) { ran = true })
+	a.stepsSinceFrame = 5
+	a.halted = true
+	a.lastError = "stale"
+
+	saved := chip8.New()
+	saved.I = 0x300
+	// custom 0123 ; LD [I], V0
+	saved.LoadROM([]byte{0x01, 0x23, 0xF0, 0x55})
+	a.restoreState(saved, saved.Memory[chip8.ProgramStart:chip8.ProgramStart+4])
+	if a.stepsSinceFrame != 0 || a.halted || a.lastError != "" {
+		t.Errorf("Expected the per-run counters to reset, got steps %d halted %v error %q", a.stepsSinceFrame, a.halted, a.lastError)
+	}
+
+	a.cpu.IsRunning = true
+	a.cpu.EmulateCycle()
+	if !ran {
+		t.Error("Expected the opcode hook to survive the restore")
+	}
+	a.cpu.EmulateCycle()
+	if a.cpu.LastError == "" {
+		t.Error("Expected the protected range to survive the restore")
+	}
+}
+
+/*
+TestSetSpeedMultiplierScalesCycles checks that the multiplier scales the cycles
+run per frame on top of the clock speed and snaps to the allowed values.
+*/
+func TestSetSpeedMultiplierScalesCycles(t *testing.T) {
+	a := NewApp()
+	a.SetClockSpeed(600)
+	if a.cyclesPerFrame != 10 {
+		t.Fatalf("Expected 10 cycles per frame at 600 Hz, got %d", a.cyclesPerFrame)
+	}
+
+	if got := a.SetSpeedMultiplier(2); got != 2 || a.cyclesPerFrame != 20 {
+		t.Errorf("Expected 2x and 20 cycles per frame, got %vx and %d", got, a.cyclesPerFrame)
+	}
+	if got := a.SetSpeedMultiplier(10); got != 4 || a.cyclesPerFrame != 40 {
+		t.Errorf("Expected 4x and 40 cycles per frame, got %vx and %d", got, a.cyclesPerFrame)
+	}
+
+	a.SetClockSpeed(300)
+	if a.cyclesPerFrame != 20 {
+		t.Errorf("Expected the multiplier to persist across clock changes, got %d cycles per frame", a.cyclesPerFrame)
+	}
+}
+
+/*
+TestGetKeyForChip8Key checks the reverse key map lookup for the default map, a
+custom map and an unbound key.
+*/
+func TestGetKeyForChip8Key(t *testing.T) {
+	a := NewApp()
+	a.settings = settings.DefaultSettings()
+
+	if key, err := a.GetKeyForChip8Key(0xC); err != nil || key != "4" {
+		t.Errorf("Expected \"4\" for key 0xC, got %q (%v)", key, err)
+	}
+
+	a.settings.KeyMap = map[string]int{"arrowup": 0x2, "k": 0x2, "j": 0x8}
+	if key, err := a.GetKeyForChip8Key(0x2); err != nil || key != "arrowup" {
+		t.Errorf("Expected \"arrowup\" for key 0x2, got %q (%v)", key, err)
+	}
+	if _, err := a.GetKeyForChip8Key(0x5); err == nil {
+		t.Error("Expected an error for an unbound key")
+	}
+	if _, err := a.GetKeyForChip8Key(16); err == nil {
+		t.Error("Expected an error for an out of range key")
+	}
+}
+
+/*
+TestStepWhilePaused checks that Step runs one instruction only while paused with
+a ROM loaded, and ticks the timers once per frame's worth of steps.
+*/
+func TestStepWhilePaused(t *testing.T) {
+	a := NewApp()
+	a.Step()
+	if a.cpu.PC != chip8.ProgramStart {
+		t.Fatalf("Expected Step without a ROM to do nothing, PC is 0x%X", a.cpu.PC)
+	}
+
+	// LD V0, 0x05 ; LD DT, V0 ; then a loop of ADD V1, 1 / JP 0x204
+	a.loadROMFromData([]byte{0x60, 0x05, 0xF0, 0x15, 0x71, 0x01, 0x12, 0x04}, "step.ch8")
+	a.Step()
+	if a.cpu.PC != chip8.ProgramStart {
+		t.Fatal("Expected Step to do nothing while running")
+	}
+
+	a.TogglePause()
+	a.SetClockSpeed(180) // 3 cycles per frame
+	a.Step()
+	a.Step()
+	if a.cpu.PC != chip8.ProgramStart+4 || a.cpu.DelayTimer != 5 {
+		t.Fatalf("Expected PC 0x204 and DT 5 after two steps, got PC 0x%X DT %d", a.cpu.PC, a.cpu.DelayTimer)
+	}
+	a.Step()
+	if a.cpu.DelayTimer != 4 {
+		t.Errorf("Expected the timers to tick after a frame of steps, DT is %d", a.cpu.DelayTimer)
+	}
+	if a.cpu.IsRunning {
+		t.Error("Expected the CPU to stay paused after stepping")
+	}
+}
+
+/*
+TestStepFrame checks that StepFrame runs one frame of instructions and ticks the
+timers once, finishing a frame already started with Step, that a DRW under the
+DisplayWait quirk ends the frame early, and that a self-jump halt stops the frame
+before the timers tick.
+*/
+func TestStepFrame(t *testing.T) {
+	a := NewApp()
+	a.StepFrame()
+	if a.cpu.PC != chip8.ProgramStart {
+		t.Fatalf("Expected StepFrame without a ROM to do nothing, PC is 0x%X", a.cpu.PC)
+	}
+
+	// LD V0, 0x05 ; LD DT, V0 ; then a loop of ADD V1, 1 / JP 0x204
+	a.loadROMFromData([]byte{0x60, 0x05, 0xF0, 0x15, 0x71, 0x01, 0x12, 0x04}, "frame.ch8")
+	a.TogglePause()
+	a.SetClockSpeed(180) // 3 cycles per frame
+	a.StepFrame()
+	if a.cpu.PC != chip8.ProgramStart+6 || a.cpu.DelayTimer != 4 {
+		t.Fatalf("Expected PC 0x206 and DT 4 after a frame, got PC 0x%X DT %d", a.cpu.PC, a.cpu.DelayTimer)
+	}
+	a.Step()
+	a.StepFrame()
+	if a.cpu.CycleCount != 6 || a.cpu.DelayTimer != 3 {
+		t.Errorf("Expected StepFrame to finish the stepped frame, got %d cycles and DT %d", a.cpu.CycleCount, a.cpu.DelayTimer)
+	}
+	if a.cpu.IsRunning {
+		t.Error("Expected the CPU to stay paused")
+	}
+
+	// DRW V0, V0, 1 ; JP 0x200
+	a.loadROMFromData([]byte{0xD0, 0x01, 0x12, 0x00}, "wait.ch8")
+	a.TogglePause()
+	a.cpu.Quirks.DisplayWait = true
+	a.StepFrame()
+	if a.cpu.CycleCount != 1 || a.cpu.WaitingForVBlank() {
+		t.Errorf("Expected the frame to end after the DRW, got %d cycles (waiting=%v)", a.cpu.CycleCount, a.cpu.WaitingForVBlank())
+	}
+
+	// LD V0, 0x05 ; LD DT, V0 ; JP 0x204
+	a.loadROMFromData([]byte{0x60, 0x05, 0xF0, 0x15, 0x12, 0x04}, "halt.ch8")
+	a.TogglePause()
+	a.SetClockSpeed(600) // 10 cycles per frame
+	a.StepFrame()
+	if !a.cpu.Halted || a.cpu.CycleCount != 3 || a.cpu.DelayTimer != 5 {
+		t.Errorf("Expected the frame to stop at the self-jump without a timer tick, got halted=%v after %d cycles with DT %d", a.cpu.Halted, a.cpu.CycleCount, a.cpu.DelayTimer)
+	}
+	if a.cpu.IsRunning {
+		t.Error("Expected the CPU to stay paused after the halt")
+	}
+}
+
+/*
+TestStepBackRestoresPreviousInstruction steps forwards twice while paused, then
+steps back through both instructions, and checks that loading a ROM clears the
+rewind history.
+*/
+func TestStepBackRestoresPreviousInstruction(t *testing.T) {
+	a := NewApp()
+	// LD V0, 0x05 ; LD V1, 0x07
+	rom := []byte{0x60, 0x05, 0x61, 0x07}
+	a.loadROMFromData(rom, "rewind.ch8")
+	a.TogglePause()
+	a.Step()
+	a.Step()
+
+	if !a.StepBack() || a.cpu.PC != chip8.ProgramStart+2 || a.cpu.Registers[0x1] != 0 {
+		t.Fatalf("Expected the first StepBack to undo LD V1, got PC 0x%X V1 %d", a.cpu.PC, a.cpu.Registers[0x1])
+	}
+	if !a.StepBack() || a.cpu.PC != chip8.ProgramStart || a.cpu.Registers[0x0] != 0 {
+		t.Fatalf("Expected the second StepBack to undo LD V0, got PC 0x%X V0 %d", a.cpu.PC, a.cpu.Registers[0x0])
+	}
+	if a.StepBack() {
+		t.Error("Expected StepBack to fail once the history is exhausted")
+	}
+
+	a.Step()
+	a.loadROMFromData(rom, "rewind.ch8")
+	a.TogglePause()
+	if a.StepBack() {
+		t.Error("Expected loading a ROM to clear the rewind history")
+	}
+}
+
+/*
+TestExportFramebufferPNGHiRes checks that the export follows the display mode,
+producing a 128x64 image in hi-res mode at scale 1.
+*/
+func TestExportFramebufferPNGHiRes(t *testing.T) {
+	a := NewApp()
+	a.loadROMFromData([]byte{0x00, 0xFF}, "hires.ch8") // HIGH
+	a.cpu.EmulateCycle()
+
+	data, err := a.ExportFramebufferPNG(1, "#FFFFFF", "")
+	if err != nil {
+		t.Fatalf("ExportFramebufferPNG failed: %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if b := img.Bounds(); b.Dx() != chip8.HiResWidth || b.Dy() != chip8.HiResHeight {
+		t.Errorf("Expected a %dx%d image, got %dx%d", chip8.HiResWidth, chip8.HiResHeight, b.Dx(), b.Dy())
+	}
+}
+
+/*
+TestLoadROMAppliesROMDatabase checks that a ROM found in the ROM database
+switches the machine type, quirks and clock speed when loaded, without changing
+the saved clock speed, and that an unknown ROM loaded after it goes back to the
+user's machine type, quirks and clock speed.
+*/
+func TestLoadROMAppliesROMDatabase(t *testing.T) {
+	rom := []byte{0x00, 0xE0, 0x12, 0x02}
+	path := filepath.Join(t.TempDir(), "romdb.json")
+	data := `{"version": 1, "roms": {"` + romdb.Hash(rom) + `": {"title": "Known", "platform": "schip", "clockSpeed": 1100}}}`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	db, err := romdb.Load(path)
+	if err != nil {
+		t.Fatalf("romdb.Load failed: %v", err)
+	}
+
+	a := NewApp()
+	a.settings = settings.DefaultSettings()
+	a.romDB = db
+	a.loadROMFromData(rom, "known.ch8")
+	if a.machine != chip8.MachineSCHIP {
+		t.Errorf("Expected machine %s, got %s", chip8.MachineSCHIP, a.machine)
+	}
+	if a.cpu.Quirks != chip8.MachineQuirks(chip8.MachineSCHIP) {
+		t.Errorf("Expected SCHIP quirks, got %+v", a.cpu.Quirks)
+	}
+	if a.clockSpeed() != 1100 {
+		t.Errorf("Expected clock speed 1100, got %d", a.clockSpeed())
+	}
+	if a.settings.ClockSpeed != 700 {
+		t.Errorf("Expected the saved clock speed to stay 700, got %d", a.settings.ClockSpeed)
+	}
+
+	a.loadROMFromData([]byte{0x00, 0xE0, 0x12, 0x00}, "unknown.ch8")
+	if a.machine != chip8.MachineCHIP8 {
+		t.Errorf("Expected machine %s, got %s", chip8.MachineCHIP8, a.machine)
+	}
+	if a.cpu.Quirks != a.settings.Quirks {
+		t.Errorf("Expected the user's quirks %+v, got %+v", a.settings.Quirks, a.cpu.Quirks)
+	}
+	if a.clockSpeed() != 700 {
+		t.Errorf("Expected clock speed 700, got %d", a.clockSpeed())
+	}
+}
+
+/*
+TestAssembleAndLoad checks that assembled source is loaded as the current ROM and
+that assembly errors name the offending line.
+*/
+func TestAssembleAndLoad(t *testing.T) {
+	a := NewApp()
+	a.settings = settings.DefaultSettings()
+	if err := a.AssembleAndLoad("start:\n  LD V1, 0x2A\n  JP start\n"); err != nil {
+		t.Fatalf("AssembleAndLoad failed: %v", err)
+	}
+	want := []byte{0x61, 0x2A, 0x12, 0x00}
+	if !bytes.Equal(a.romLoaded, want) {
+		t.Errorf("Expected ROM % X, got % X", want, a.romLoaded)
+	}
+	if !bytes.Equal(a.cpu.Memory[chip8.ProgramStart:chip8.ProgramStart+4], want) {
+		t.Errorf("Expected assembled bytes in memory at 0x200, got % X", a.cpu.Memory[chip8.ProgramStart:chip8.ProgramStart+4])
+	}
+
+	err := a.AssembleAndLoad("CLS\nBOGUS V1\n")
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("Expected an error on line 2, got %v", err)
+	}
+}
+
+/*
+TestRunUntil checks that RunUntil stops at the target address, at a real
+breakpoint or self-jump hit first, and after the cycle limit when the target is
+never reached.
+*/
+func TestRunUntil(t *testing.T) {
+	a := NewApp()
+	a.settings = settings.DefaultSettings()
+	if _, err := a.RunUntil(0x206); err == nil {
+		t.Error("Expected RunUntil without a ROM to fail")
+	}
+
+	// 0x200: ADD V0, 1 ; SE V0, 5 ; JP 0x200 ; 0x206: LD V1, 1 ; JP 0x208
+	a.loadROMFromData([]byte{0x70, 0x01, 0x30, 0x05, 0x12, 0x00, 0x61, 0x01, 0x12, 0x08}, "loop.ch8")
+	a.TogglePause()
+	reason, err := a.RunUntil(0x206)
+	if err != nil || reason != "target" {
+		t.Fatalf("Expected to reach the target, got %q (%v)", reason, err)
+	}
+	if a.cpu.PC != 0x206 || a.cpu.Registers[0] != 5 || a.cpu.IsRunning {
+		t.Errorf("Expected to pause at 0x206 with V0 = 5, got PC 0x%X V0 = %d running=%v", a.cpu.PC, a.cpu.Registers[0], a.cpu.IsRunning)
+	}
+
+	a.loadROMFromData([]byte{0x70, 0x01, 0x30, 0x05, 0x12, 0x00, 0x61, 0x01, 0x12, 0x08}, "loop.ch8")
+	a.TogglePause()
+	a.SetBreakpoint(0x204)
+	if reason, _ := a.RunUntil(0x206); reason != "breakpoint" || a.cpu.PC != 0x204 {
+		t.Errorf("Expected to stop at the breakpoint at 0x204, got %q at 0x%X", reason, a.cpu.PC)
+	}
+	a.ClearBreakpoint(0x204)
+	if reason, _ := a.RunUntil(0x300); reason != "halted" || a.cpu.PC != 0x208 {
+		t.Errorf("Expected to stop at the self-jump at 0x208, got %q at 0x%X", reason, a.cpu.PC)
+	}
+
+	// 0x200: ADD V0, 1 ; JP 0x200
+	a.loadROMFromData([]byte{0x70, 0x01, 0x12, 0x00}, "spin.ch8")
+	a.TogglePause()
+	a.settings.RunUntilMaxCycles = 50
+	if reason, _ := a.RunUntil(0x300); reason != "timeout" || a.cpu.CycleCount != 50 {
+		t.Errorf("Expected an unreachable target to time out after 50 cycles, got %q after %d", reason, a.cpu.CycleCount)
+	}
+}
+
+/*
+TestRunUntilReleasesLock starts a long RunUntil towards an unreachable address
+and checks that the lock is free between frames: a reader gets in while it runs,
+and resuming the emulator then interrupts it.
+*/
+func TestRunUntilReleasesLock(t *testing.T) {
+	a := NewApp()
+	a.settings = settings.DefaultSettings()
+	a.settings.RunUntilMaxCycles = 1000000
+	// 0x200: ADD V0, 1 ; JP 0x200
+	a.loadROMFromData([]byte{0x70, 0x01, 0x12, 0x00}, "spin.ch8")
+	a.TogglePause()
+
+	done := make(chan string)
+	go func() {
+		reason, _ := a.RunUntil(0x300)
+		done <- reason
+	}()
+	for {
+		a.mu.RLock()
+		cycles := a.cpu.CycleCount
+		a.mu.RUnlock()
+		if cycles > 0 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	a.TogglePause()
+	if reason := <-done; reason != "interrupted" {
+		t.Errorf("Expected RunUntil to be interrupted, got %q", reason)
+	}
+	if !a.cpu.IsRunning {
+		t.Error("Expected the emulator to keep running after the interruption")
+	}
+}
+
+/*
+TestLoadROMFromURLIsOptIn checks that URL loading is refused until the setting
+enables it.
+*/
+func TestLoadROMFromURLIsOptIn(t *testing.T) {
+	a := NewApp()
+	a.settings = settings.DefaultSettings()
+	if _, err := a.LoadROMFromURL("http://127.0.0.1:1/rom.ch8"); err == nil || !strings.Contains(err.Error(), "disabled") {
+		t.Errorf("Expected URL loading to be disabled by default, got %v", err)
+	}
+}
+
+/*
+TestLoadDroppedFile checks that dropped ROMs and save states are routed to the
+right loader and that other files are rejected.
+*/
+func TestLoadDroppedFile(t *testing.T) {
+	dir := t.TempDir()
+	a := NewApp()
+	a.settings = settings.DefaultSettings()
+	a.romLoader = roms.NewLoader(dir)
+
+	romPath := filepath.Join(dir, "drop.CH8")
+	if err := os.WriteFile(romPath, []byte{0x60, 0x2A, 0x12, 0x02}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if name, err := a.loadDroppedFile(romPath); err != nil || name != "drop.CH8" {
+		t.Fatalf("Expected the ROM to load, got %q (%v)", name, err)
+	}
+
+	a.cpu.Registers[3] = 0x33
+	state, err := encodeState(a.cpu)
+	if err != nil {
+		t.Fatal(err)
+	}
+	statePath := filepath.Join(dir, "drop.ch8state")
+	if err := os.WriteFile(statePath, state, 0644); err != nil {
+		t.Fatal(err)
+	}
+	a.cpu.Registers[3] = 0
+	if _, err := a.loadDroppedFile(statePath); err != nil {
+		t.Fatalf("Expected the state to load, got %v", err)
+	}
+	if a.cpu.Registers[3] != 0x33 {
+		t.Errorf("Expected the dropped state to restore V3 = 0x33, got 0x%02X", a.cpu.Registers[3])
+	}
+
+	if _, err := a.loadDroppedFile(filepath.Join(dir, "notes.txt")); err == nil || !strings.Contains(err.Error(), "unsupported") {
+		t.Errorf("Expected an unsupported file error, got %v", err)
+	}
+}
+
+/*
+TestLoadBundledROM checks that every built-in example ROM loads into memory and
+that an unknown name is an error.
+*/
+func TestLoadBundledROM(t *testing.T) {
+	a := NewApp()
+	a.settings = settings.DefaultSettings()
+	names := a.GetBundledROMs()
+	if len(names) == 0 {
+		t.Fatal("Expected bundled ROMs")
+	}
+	for _, name := range names {
+		if err := a.LoadBundledROM(name); err != nil {
+			t.Errorf("LoadBundledROM(%q) failed: %v", name, err)
+			continue
+		}
+		if a.romName != "built-in: "+name || len(a.romLoaded) == 0 {
+			t.Errorf("Expected %s to be the loaded ROM, got %q", name, a.romName)
+		}
+	}
+	if err := a.LoadBundledROM("missing.ch8"); err == nil {
+		t.Error("Expected an unknown bundled ROM to fail")
+	}
+}
+
+/*
+TestSaveSettingsPalette checks that malformed palette colours are rejected, that
+a short palette is filled with defaults, and that the display colour and the
+palette's foreground stay in step.
+*/
+func TestSaveSettingsPalette(t *testing.T) {
+	a := NewApp()
+	a.settingsManager = settings.NewManager(filepath.Join(t.TempDir(), "settings.json"))
+	a.settings = settings.DefaultSettings()
+
+	for _, palette := range [][]string{{"#12345"}, {"red"}, {"#000000", "#GG0000"}, {"#000000", "#111111", "#222222", "#333333", "#444444"}} {
+		s := a.settings
+		s.Palette = palette
+		if err := a.SaveSettings(s); err == nil {
+			t.Errorf("Expected palette %v to be rejected", palette)
+		}
+	}
+
+	s := a.settings
+	s.Palette = []string{"#101010"}
+	if err := a.SaveSettings(s); err != nil {
+		t.Fatalf("SaveSettings failed: %v", err)
+	}
+	want := []string{"#101010", "#33FF00", "#FF5500", "#FFFFFF"}
+	if !reflect.DeepEqual(a.settings.Palette, want) {
+		t.Errorf("Expected palette %v, got %v", want, a.settings.Palette)
+	}
+
+	s = a.settings
+	s.DisplayColor = "#FFBF00"
+	if err := a.SaveSettings(s); err != nil {
+		t.Fatalf("SaveSettings failed: %v", err)
+	}
+	if a.settings.Palette[1] != "#FFBF00" {
+		t.Errorf("Expected a new display colour to become the palette foreground, got %s", a.settings.Palette[1])
+	}
+
+	s = a.settings
+	s.Palette = []string{"#000000", "#ABCDEF"}
+	if err := a.SaveSettings(s); err != nil {
+		t.Fatalf("SaveSettings failed: %v", err)
+	}
+	if a.settings.DisplayColor != "#ABCDEF" {
+		t.Errorf("Expected the palette foreground to update the display colour, got %s", a.settings.DisplayColor)
+	}
+}
+
+/*
+TestInputRecordingReplaysDeterministically records a key press against a ROM
+that reads random numbers and waits for a key, then replays the recording and
+checks that the run, including the RND results, is reproduced exactly.
+*/
+func TestInputRecordingReplaysDeterministically(t *testing.T) {
+	a := NewApp()
+	a.settings = settings.DefaultSettings()
+	if err := a.StartInputRecording(); err == nil {
+		t.Error("Expected recording without a ROM to fail")
+	}
+	run := func(n int) {
+		for i := 0; i < n; i++ {
+			a.applyKeyEvents()
+			a.applyDemoInput()
+			a.cpu.EmulateCycle()
+		}
+	}
+
+	// RND V1, 0xFF ; LD V0, K ; RND V3, 0xFF ; JP 0x206
+	a.loadROMFromData([]byte{0xC1, 0xFF, 0xF0, 0x0A, 0xC3, 0xFF, 0x12, 0x06}, "rnd.ch8")
+	if err := a.StartInputRecording(); err != nil {
+		t.Fatalf("StartInputRecording failed: %v", err)
+	}
+	run(5)
+	a.KeyDown(7)
+	run(5)
+	a.KeyUp(7)
+	events := a.StopInputRecording()
+	if len(events) != 2 || events[0] != (InputEvent{Cycle: 5, Key: 7, Down: true}) {
+		t.Fatalf("Expected a press at cycle 5 and a release, got %+v", events)
+	}
+	want := a.cpu.Registers
+
+	if err := a.PlayInputRecording(events); err != nil {
+		t.Fatalf("PlayInputRecording failed: %v", err)
+	}
+	run(10)
+	if a.cpu.Registers != want {
+		t.Errorf("Expected the replay to reproduce registers %v, got %v", want, a.cpu.Registers)
+	}
+	if a.cpu.Registers[0] != 7 {
+		t.Errorf("Expected the replayed key press to reach LD V0, K, got V0 = %d", a.cpu.Registers[0])
+	}
+
+	encoded, err := a.ExportInputRecording()
+	if err != nil {
+		t.Fatalf("ExportInputRecording failed: %v", err)
+	}
+	if err := a.LoadDemo(encoded); err != nil {
+		t.Fatalf("LoadDemo failed: %v", err)
+	}
+	if err := a.SoftReset(); err != nil {
+		t.Fatal(err)
+	}
+	run(10)
+	if a.cpu.Registers != want {
+		t.Errorf("Expected the exported demo to replay identically, got %v", a.cpu.Registers)
+	}
+}
+
+/*
+TestDemoReplaysZeroSeed checks that a demo recorded with an RNG seed of zero
+reseeds the CPU on soft reset rather than treating zero as no seed.
+*/
+func TestDemoReplaysZeroSeed(t *testing.T) {
+	a := NewApp()
+	a.settings = settings.DefaultSettings()
+	rom := []byte{0xC1, 0xFF, 0xC2, 0xFF} // RND V1, 0xFF ; RND V2, 0xFF
+	a.loadROMFromData(rom, "rnd.ch8")
+	a.cpu.SetSeed(99)
+	data, err := demo.Encode(demo.Demo{HasSeed: true})
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if err := a.LoadDemo(base64.StdEncoding.EncodeToString(data)); err != nil {
+		t.Fatalf("LoadDemo failed: %v", err)
+	}
+	if err := a.SoftReset(); err != nil {
+		t.Fatal(err)
+	}
+	a.cpu.EmulateCycle()
+	a.cpu.EmulateCycle()
+
+	want := chip8.New()
+	want.LoadROM(rom)
+	want.SetSeed(0)
+	want.IsRunning = true
+	want.EmulateCycle()
+	want.EmulateCycle()
+	if a.cpu.Registers[1] != want.Registers[1] || a.cpu.Registers[2] != want.Registers[2] {
+		t.Errorf("Expected the RND results of seed 0, %d and %d, got %d and %d", want.Registers[1], want.Registers[2], a.cpu.Registers[1], a.cpu.Registers[2])
+	}
+}
+
+/*
+TestLoadSettingsRepairsKeyMap checks that an invalid key map or gamepad map in
+the settings file is replaced by the default on load, so later saves of the
+loaded settings succeed.
+*/
+func TestLoadSettingsRepairsKeyMap(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "settings.json")
+	data := `{"clockSpeed": 700, "keyMap": {"v": 16}, "gamepadMap": {"a": 0, "turbo": 1}}`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	a := NewApp()
+	a.settingsManager = settings.NewManager(path)
+	loaded, err := a.settingsManager.Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if !reflect.DeepEqual(loaded.KeyMap, settings.DefaultKeyMap()) {
+		t.Errorf("Expected the default key map, got %v", loaded.KeyMap)
+	}
+	if !reflect.DeepEqual(loaded.GamepadMap, settings.DefaultGamepadMap()) {
+		t.Errorf("Expected the default gamepad map, got %v", loaded.GamepadMap)
+	}
+	a.settings = loaded
+	if err := a.SaveSettings(loaded); err != nil {
+		t.Errorf("Expected the repaired settings to save, got %v", err)
+	}
+}
+
+/*
+TestSaveSettingsKeyMap checks that key maps with out-of-range or unbound CHIP-8
+keys are rejected and leave the saved key map alone, and that the reverse map
+lists every keyboard key bound to a CHIP-8 key.
+*/
+func TestSaveSettingsKeyMap(t *testing.T) {
+	a := NewApp()
+	a.settingsManager = settings.NewManager(filepath.Join(t.TempDir(), "settings.json"))
+	a.settings = settings.DefaultSettings()
+
+	outOfRange := settings.DefaultSettings().KeyMap
+	outOfRange["v"] = 16
+	unbound := settings.DefaultSettings().KeyMap
+	unbound["v"] = 0xE
+	negative := settings.DefaultSettings().KeyMap
+	negative["p"] = -1
+	for _, keyMap := range []map[string]int{outOfRange, unbound, negative, nil} {
+		s := a.settings
+		s.KeyMap = keyMap
+		if err := a.SaveSettings(s); err == nil {
+			t.Errorf("Expected key map %v to be rejected", keyMap)
+		}
+	}
+	if a.settings.KeyMap["v"] != 0xF {
+		t.Errorf("Expected a rejected key map to leave the saved one alone, got v=%d", a.settings.KeyMap["v"])
+	}
+
+	s := a.settings
+	s.KeyMap = settings.DefaultSettings().KeyMap
+	s.KeyMap["p"] = 0xF
+	if err := a.SaveSettings(s); err != nil {
+		t.Fatalf("SaveSettings failed: %v", err)
+	}
+	if got := a.GetReverseKeyMap()[0xF]; !reflect.DeepEqual(got, []string{"p", "v"}) {
+		t.Errorf("Expected CHIP-8 key F to be bound to [p v], got %v", got)
+	}
+	if key, err := a.GetKeyForChip8Key(0xF); err != nil || key != "p" {
+		t.Errorf("Expected CHIP-8 key F to report p, got %q (%v)", key, err)
+	}
+}
+
+/*
+TestSetGamepadState checks that held gamepad buttons press their bound CHIP-8
+keys, that releasing them releases the keys, and that a key held on the
+keyboard is left alone by a gamepad with nothing pressed.
+*/
+func TestSetGamepadState(t *testing.T) {
+	a := NewApp()
+	a.settings = settings.DefaultSettings()
+
+	a.SetGamepadState(map[string]bool{"up": true, "a": true, "b": false, "home": true})
+	a.applyKeyEvents()
+	if !a.cpu.Keys[0x5] || !a.cpu.Keys[0x6] {
+		t.Errorf("Expected up and A to press keys 5 and 6, got %v", a.cpu.Keys)
+	}
+	if a.cpu.Keys[0x4] {
+		t.Errorf("Expected a released button to leave its key up")
+	}
+
+	a.KeyDown(0x1)
+	a.SetGamepadState(map[string]bool{})
+	a.applyKeyEvents()
+	if a.cpu.Keys[0x5] || a.cpu.Keys[0x6] {
+		t.Errorf("Expected releasing the buttons to release keys 5 and 6, got %v", a.cpu.Keys)
+	}
+	if !a.cpu.Keys[0x1] {
+		t.Errorf("Expected the keyboard-held key 1 to stay pressed")
+	}
+
+	s := a.settings
+	s.GamepadMap = settings.DefaultGamepadMap()
+	s.GamepadMap["turbo"] = 0x1
+	a.settingsManager = settings.NewManager(filepath.Join(t.TempDir(), "settings.json"))
+	if err := a.SaveSettings(s); err == nil {
+		t.Errorf("Expected a gamepad map with an unknown button to be rejected")
+	}
+	delete(s.GamepadMap, "turbo")
+	s.GamepadMap["up"] = 0x6
+	if err := a.SaveSettings(s); err == nil {
+		t.Errorf("Expected a gamepad map leaving key 5 unbound to be rejected")
+	}
+}
+
+/*
+TestKeyEventsAreNotLost checks that a press released before the keypad is next
+updated still reads as down for one update, that keys sent while the buffer is
+full are not dropped, and that a press wakes a halted ROM.
+*/
+func TestKeyEventsAreNotLost(t *testing.T) {
+	a := NewApp()
+	a.cpu.Halted = true
+	a.KeyDown(0x4)
+	a.KeyUp(0x4)
+	a.applyKeyEvents()
+	if !a.cpu.Keys[0x4] {
+		t.Errorf("Expected a quick tap to read as pressed for one update")
+	}
+	if a.cpu.Halted {
+		t.Errorf("Expected the press to clear Halted")
+	}
+	a.applyKeyEvents()
+	if a.cpu.Keys[0x4] {
+		t.Errorf("Expected the tap to be released on the next update")
+	}
+
+	for i := 0; i < keyEventBuffer+8; i++ {
+		a.KeyDown(i % 16)
+	}
+	a.applyKeyEvents()
+	for key, pressed := range a.cpu.Keys {
+		if !pressed {
+			t.Errorf("Expected key %X to be pressed after overflowing the buffer", key)
+		}
+	}
+}
+
+/*
+TestKeysRaceWithEmulation presses and releases keys from several goroutines
+while the emulation loop runs a ROM that polls the keypad, so that go test
+-race catches any unguarded access to cpu.Keys.
+*/
+func TestKeysRaceWithEmulation(t *testing.T) {
+	a := NewApp()
+	a.settings = settings.DefaultSettings()
+	// SKP V0 ; ADD V0, 1 ; LD V2, 0x0F ; AND V0, V2 ; LD V1, K ; JP 0x200
+	a.loadROMFromData([]byte{0xE0, 0x9E, 0x70, 0x01, 0x62, 0x0F, 0x80, 0x22, 0xF1, 0x0A, 0x12, 0x00}, "keys.ch8")
+
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				key := (g*4 + i) % 16
+				a.KeyDown(key)
+				a.KeyUp(key)
+			}
+		}(g)
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	timers := timerSpreader{ticks: 1, cycles: 1 << 30}
+	for running := true; running; {
+		select {
+		case <-done:
+			running = false
+		default:
+		}
+		a.runCycle(&timers)
+	}
+	for {
+		a.applyKeyEvents()
+		a.mu.RLock()
+		empty := len(a.keys.pending) == 0 && len(a.keys.events) == 0
+		a.mu.RUnlock()
+		if empty {
+			break
+		}
+	}
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	for key, pressed := range a.cpu.Keys {
+		if pressed {
+			t.Errorf("Expected key %X to be released once every event was applied", key)
+		}
+	}
+}
+
+/*
+TestPollingRacesWithEmulation runs a ROM that writes memory and draws while
+other goroutines poll memory, the display and the CPU state and toggle
+breakpoints, so that go test -race catches any binding touching the CPU without
+the lock.
+*/
+func TestPollingRacesWithEmulation(t *testing.T) {
+	a := NewApp()
+	a.settings = settings.DefaultSettings()
+	// ADD V0, 1 ; LD I, 0x300 ; LD B, V0 ; DRW V0, V1, 3 ; JP 0x200
+	a.loadROMFromData([]byte{0x70, 0x01, 0xA3, 0x00, 0xF0, 0x33, 0xD0, 0x13, 0x12, 0x00}, "poll.ch8")
+
+	// The pollers run until the emulation finishes, so their reads overlap its
+	// writes; toggling breakpoints logs, so that one stops after a few rounds.
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	breakpointRounds := 0
+	polls := []func() bool{
+		func() bool { a.GetMemory(0x300, 3); return true },
+		func() bool { a.GetMemoryChangeMask(0, 0x1000); return true },
+		func() bool { a.GetInstructionAtPC(); return true },
+		func() bool { a.GetSessionStats(); return true },
+		func() bool {
+			if _, err := a.ExportFramebufferPNG(1, "", ""); err != nil {
+				t.Errorf("ExportFramebufferPNG failed: %v", err)
+			}
+			return true
+		},
+		func() bool {
+			a.SetBreakpoint(0xE00)
+			a.ClearBreakpoint(0xE00)
+			a.SetWatchpoint(0xE00)
+			a.ClearWatchpoint(0xE00)
+			breakpointRounds++
+			return breakpointRounds < 50
+		},
+	}
+	for _, poll := range polls {
+		wg.Add(1)
+		go func(poll func() bool) {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					if !poll() {
+						return
+					}
+				}
+			}
+		}(poll)
+	}
+	timers := timerSpreader{ticks: 1, cycles: 1 << 30}
+	for i := 0; i < 2000; i++ {
+		a.runCycle(&timers)
+	}
+	close(stop)
+	wg.Wait()
+
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.cpu.CycleCount != 2000 {
+		t.Errorf("Expected 2000 cycles to run, got %d", a.cpu.CycleCount)
+	}
+}
+
+/*
+TestLoadROMRacesWithEmulation loads ROMs from another goroutine while the
+emulation runs, so that go test -race catches a load resetting the CPU without
+the lock.
+*/
+func TestLoadROMRacesWithEmulation(t *testing.T) {
+	a := NewApp()
+	a.settings = settings.DefaultSettings()
+	rom := []byte{0x70, 0x01, 0xA3, 0x00, 0xF0, 0x33, 0x12, 0x00} // ADD V0, 1 ; LD I, 0x300 ; LD B, V0 ; JP 0x200
+	a.loadROMFromData(rom, "load.ch8")
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 20; i++ {
+			a.loadROMFromData(rom, "load.ch8")
+		}
+	}()
+	timers := timerSpreader{ticks: 1, cycles: 1 << 30}
+	for i := 0; i < 2000; i++ {
+		a.runCycle(&timers)
+	}
+	<-done
+
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.romName != "load.ch8" || !a.cpu.IsRunning {
+		t.Errorf("Expected load.ch8 to be loaded and running, got %q (running %v)", a.romName, a.cpu.IsRunning)
+	}
+}
+
+/*
+TestRunFrameWithFakeClock drives the frame loop by hand with a fixed clock and
+checks the exact number of instructions and timer ticks per frame while running,
+paused and at double speed.
+*/
+func TestRunFrameWithFakeClock(t *testing.T) {
+	a := NewApp()
+	a.settings = settings.DefaultSettings()
+	a.SetClockSpeed(600) // 10 instructions per frame
+	// LD V1, 60 ; LD DT, V1 ; ADD V0, 1 ; JP 0x204
+	a.loadROMFromData([]byte{0x61, 0x3C, 0xF1, 0x15, 0x70, 0x01, 0x12, 0x04}, "count.ch8")
+	clock := func() time.Time { return time.Unix(0, 0) }
+	frames := func(n int) {
+		for i := 0; i < n; i++ {
+			a.runFrame(clock)
+		}
+	}
+	check := func(when string, cycles uint64, delay byte) {
+		t.Helper()
+		a.mu.RLock()
+		defer a.mu.RUnlock()
+		if a.cpu.CycleCount != cycles || a.cpu.DelayTimer != delay {
+			t.Errorf("%s: expected %d cycles and DT %d, got %d and %d", when, cycles, delay, a.cpu.CycleCount, a.cpu.DelayTimer)
+		}
+	}
+
+	frames(3)
+	check("running", 30, 57)
+
+	a.TogglePause()
+	frames(2)
+	check("paused", 30, 57)
+
+	a.TogglePause()
+	a.SetSpeedMultiplier(2)
+	frames(1)
+	check("double speed", 50, 55)
+}
+
+/*
+TestResumeClearsWatchpointHit checks that resuming after a watchpoint pause
+clears the last watchpoint reported by the debugger state.
+*/
+func TestResumeClearsWatchpointHit(t *testing.T) {
+	a := NewApp()
+	a.settings = settings.DefaultSettings()
+	// LD I, 0x300 ; LD [I], V0 ; JP 0x204
+	a.loadROMFromData([]byte{0xA3, 0x00, 0xF0, 0x55, 0x12, 0x04}, "watch.ch8")
+	a.SetWatchpoint(0x300)
+	a.runFrame(func() time.Time { return time.Unix(0, 0) })
+	a.mu.RLock()
+	hit := a.cpu.GetState()["LastWatchpoint"]
+	a.mu.RUnlock()
+	if hit == nil {
+		t.Fatalf("Expected the watchpoint at 0x300 to pause emulation")
+	}
+
+	if a.TogglePause() {
+		t.Fatalf("Expected TogglePause to resume")
+	}
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if hit := a.cpu.GetState()["LastWatchpoint"]; hit != nil {
+		t.Errorf("Expected no last watchpoint after resuming, got %v", hit)
+	}
+}
+
+/*
+TestPauseSilencesTone checks that pausing forgets the tone being played, so the
+frontend's stopped tone is started again on resume while the sound timer runs.
+*/
+func TestPauseSilencesTone(t *testing.T) {
+	a := NewApp()
+	a.settings = settings.DefaultSettings()
+	a.SetClockSpeed(600)
+	// LD V1, 60 ; LD ST, V1 ; ADD V0, 1 ; JP 0x204
+	a.loadROMFromData([]byte{0x61, 0x3C, 0xF1, 0x18, 0x70, 0x01, 0x12, 0x04}, "beep.ch8")
+	clock := func() time.Time { return time.Unix(0, 0) }
+	a.runFrame(clock)
+	if a.soundTimer == 0 {
+		t.Fatalf("Expected the tone to be playing")
+	}
+
+	a.TogglePause()
+	if a.soundTimer != 0 || a.tone != (tone{}) {
+		t.Errorf("Expected pausing to forget the tone, got sound timer %d and %+v", a.soundTimer, a.tone)
+	}
+
+	a.TogglePause()
+	a.runFrame(clock)
+	if a.soundTimer != 58 {
+		t.Errorf("Expected the tone to resume with 58 ticks left, got %d", a.soundTimer)
+	}
+}
+
+/*
+TestCycleAccurateTimers checks that in the cycle-accurate timer mode the timers
+tick every ClockSpeed/60 instructions however the instructions are batched, and
+that a pitch change made while the tone sounds reaches the frontend at the tick.
+*/
+func TestCycleAccurateTimers(t *testing.T) {
+	a := NewApp()
+	a.settings = settings.DefaultSettings()
+	a.SetClockSpeed(600) // A tick every 10 instructions
+	a.cycleTimers = true
+	a.loadROMFromData([]byte{
+		0x61, 0x3C, // 0x200: LD V1, 60
+		0xF1, 0x15, // 0x202: LD DT, V1
+		0xF1, 0x18, // 0x204: LD ST, V1
+		0x62, 0x70, // 0x206: LD V2, 0x70
+		0xF2, 0x3A, // 0x208: PITCH V2
+		0x70, 0x01, // 0x20A: ADD V0, 1
+		0x12, 0x0A, // 0x20C: JP 0x20A
+	}, "tone.ch8")
+	cycles := func(n int) {
+		for i := 0; i < n; i++ {
+			a.runCycle(&timerSpreader{})
+		}
+	}
+
+	cycles(9)
+	if a.cpu.DelayTimer != 60 || a.tone.on {
+		t.Errorf("Expected DT 60 and no tone before the first tick, got DT %d and tone %v", a.cpu.DelayTimer, a.tone.on)
+	}
+	cycles(1)
+	if a.cpu.DelayTimer != 59 || a.cpu.SoundTimer != 59 {
+		t.Errorf("Expected DT and ST 59 after 10 instructions, got %d and %d", a.cpu.DelayTimer, a.cpu.SoundTimer)
+	}
+	if !a.tone.on || a.tone.pitch != 0x70 {
+		t.Errorf("Expected the tone on at pitch 0x70, got %+v", a.tone)
+	}
+	cycles(25)
+	if a.cpu.DelayTimer != 57 {
+		t.Errorf("Expected DT 57 after 35 instructions, got %d", a.cpu.DelayTimer)
+	}
+}
+
+/*
+TestBreakpointPausesTimers checks that a breakpoint stopping the CPU mid-frame
+pauses the whole app: the timers hold their values until the user resumes, and
+then carry on from where they stopped.
+*/
+func TestBreakpointPausesTimers(t *testing.T) {
+	a := NewApp()
+	a.settings = settings.DefaultSettings()
+	a.SetClockSpeed(600)
+	// LD V1, 60 ; LD DT, V1 ; LD ST, V1 ; ADD V0, 1 ; JP 0x206
+	a.loadROMFromData([]byte{0x61, 0x3C, 0xF1, 0x15, 0xF1, 0x18, 0x70, 0x01, 0x12, 0x06}, "timers.ch8")
+	a.SetBreakpoint(0x208)
+	clock := func() time.Time { return time.Unix(0, 0) }
+	snapshot := func() (uint64, byte, byte, bool) {
+		a.mu.RLock()
+		defer a.mu.RUnlock()
+		return a.cpu.CycleCount, a.cpu.DelayTimer, a.cpu.SoundTimer, a.paused()
+	}
+
+	a.runFrame(clock)
+	cycles, delay, sound, paused := snapshot()
+	if !paused {
+		t.Fatalf("Expected the breakpoint to pause the app")
+	}
+	for i := 0; i < 3; i++ {
+		a.runFrame(clock)
+	}
+	if c, d, s, _ := snapshot(); c != cycles || d != delay || s != sound {
+		t.Errorf("Expected %d cycles, DT %d and ST %d while stopped, got %d, %d and %d", cycles, delay, sound, c, d, s)
+	}
+
+	a.ClearBreakpoint(0x208)
+	if a.TogglePause() {
+		t.Fatalf("Expected TogglePause to resume after a breakpoint")
+	}
+	a.runFrame(clock)
+	if _, d, s, _ := snapshot(); d != delay-1 || s != sound-1 {
+		t.Errorf("Expected DT %d and ST %d after resuming, got %d and %d", delay-1, sound-1, d, s)
+	}
+}
+
+/*
+TestRunFrameDisplayWait checks that under the DisplayWait quirk each frame runs
+exactly one sprite draw, however many instructions the clock would allow.
+*/
+func TestRunFrameDisplayWait(t *testing.T) {
+	a := NewApp()
+	a.settings = settings.DefaultSettings()
+	a.SetClockSpeed(600)
+	// DRW V0, V0, 1 ; ADD V2, 1 ; JP 0x200
+	a.loadROMFromData([]byte{0xD0, 0x01, 0x72, 0x01, 0x12, 0x00}, "wait.ch8")
+	a.mu.Lock()
+	a.cpu.Quirks.DisplayWait = true
+	a.mu.Unlock()
+	clock := func() time.Time { return time.Unix(0, 0) }
+	for i := 0; i < 4; i++ {
+		a.runFrame(clock)
+	}
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.cpu.Registers[0x2] != 3 {
+		t.Errorf("Expected one draw per frame, so V2 = 3 after 4 frames, got %d", a.cpu.Registers[0x2])
+	}
+}
+
+/*
+TestRunLoopStopsWhenDone sends frame ticks to the loop by hand and checks that
+each runs one frame and that the loop returns once done is closed.
+*/
+func TestRunLoopStopsWhenDone(t *testing.T) {
+	a := NewApp()
+	a.settings = settings.DefaultSettings()
+	a.SetClockSpeed(600)
+	a.loadROMFromData([]byte{0x70, 0x01, 0x12, 0x00}, "loop.ch8") // ADD V0, 1 ; JP 0x200
+
+	ticks := make(chan time.Time)
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		a.runLoop(done, ticks, func() time.Time { return time.Unix(0, 0) })
+		close(finished)
+	}()
+	ticks <- time.Time{}
+	ticks <- time.Time{}
+	close(done)
+	select {
+	case <-finished:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the loop to return once done was closed")
+	}
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.cpu.CycleCount != 20 {
+		t.Errorf("Expected 2 frames of 10 instructions, got %d", a.cpu.CycleCount)
+	}
+}
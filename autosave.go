@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+/*
+autoSaveKeep is how many auto-save files are kept; older ones are deleted.
+*/
+const autoSaveKeep = 3
+
+/*
+autoSaveTimeFormat names auto-save files so that sorting by name sorts by age.
+*/
+const autoSaveTimeFormat = "20060102-150405.000"
+
+/*
+autoSaver decides when the next periodic auto-save is due. A zero interval
+disables auto-saving.
+*/
+type autoSaver struct {
+	interval time.Duration
+	last     time.Time
+}
+
+/*
+setInterval changes the auto-save period and restarts the countdown from now.
+*/
+func (s *autoSaver) setInterval(seconds int, now time.Time) {
+	if seconds < 0 {
+		seconds = 0
+	}
+	s.interval = time.Duration(seconds) * time.Second
+	s.last = now
+}
+
+/*
+due reports whether an auto-save should be written at now, and if so restarts
+the countdown.
+*/
+func (s *autoSaver) due(now time.Time) bool {
+	if s.interval <= 0 || now.Sub(s.last) < s.interval {
+		return false
+	}
+	s.last = now
+	return true
+}
+
+/*
+writeAutoSave writes data as a new auto-save file in dir and deletes all but the
+newest autoSaveKeep files. It returns the path written.
+*/
+func writeAutoSave(dir string, data []byte, now time.Time) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("could not create auto-save directory: %w", err)
+	}
+	path := filepath.Join(dir, "autosave-"+now.Format(autoSaveTimeFormat)+".c8pkg")
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write auto-save: %w", err)
+	}
+	files, err := autoSaveFiles(dir)
+	if err != nil {
+		return path, err
+	}
+	for len(files) > autoSaveKeep {
+		os.Remove(files[0])
+		files = files[1:]
+	}
+	return path, nil
+}
+
+/*
+latestAutoSave returns the newest auto-save file in dir, or "" if there is none.
+*/
+func latestAutoSave(dir string) (string, error) {
+	files, err := autoSaveFiles(dir)
+	if err != nil || len(files) == 0 {
+		return "", err
+	}
+	return files[len(files)-1], nil
+}
+
+/*
+autoSaveFiles lists the auto-save files in dir, oldest first. A missing
+directory simply has none.
+*/
+func autoSaveFiles(dir string) ([]string, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to list auto-saves: %w", err)
+	}
+	var files []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasPrefix(e.Name(), "autosave-") && strings.HasSuffix(e.Name(), ".c8pkg") {
+			files = append(files, filepath.Join(dir, e.Name()))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
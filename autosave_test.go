@@ -0,0 +1,67 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+/*
+TestAutoSaverInterval checks that auto-saves fall due once per interval with an
+injected clock, and never when the interval is zero.
+*/
+func TestAutoSaverInterval(t *testing.T) {
+	start := time.Unix(1000, 0)
+	var s autoSaver
+	s.setInterval(10, start)
+
+	if s.due(start.Add(9 * time.Second)) {
+		t.Error("Expected no auto-save before the interval has passed")
+	}
+	if !s.due(start.Add(10 * time.Second)) {
+		t.Fatal("Expected an auto-save once the interval has passed")
+	}
+	if s.due(start.Add(15 * time.Second)) {
+		t.Error("Expected the countdown to restart after an auto-save")
+	}
+	if !s.due(start.Add(20 * time.Second)) {
+		t.Error("Expected the next auto-save one interval later")
+	}
+
+	s.setInterval(0, start)
+	if s.due(start.Add(time.Hour)) {
+		t.Error("Expected a zero interval to disable auto-saving")
+	}
+}
+
+/*
+TestWriteAutoSaveKeepsNewest writes more auto-saves than are kept and checks that
+only the newest remain and latestAutoSave picks the last one.
+*/
+func TestWriteAutoSaveKeepsNewest(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "autosave")
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	if latest, err := latestAutoSave(dir); err != nil || latest != "" {
+		t.Fatalf("Expected no auto-save in a missing directory, got %q, %v", latest, err)
+	}
+	var last string
+	for i := 0; i < autoSaveKeep+2; i++ {
+		path, err := writeAutoSave(dir, []byte{byte(i)}, start.Add(time.Duration(i)*time.Second))
+		if err != nil {
+			t.Fatalf("writeAutoSave: %v", err)
+		}
+		last = path
+	}
+
+	files, err := autoSaveFiles(dir)
+	if err != nil {
+		t.Fatalf("autoSaveFiles: %v", err)
+	}
+	if len(files) != autoSaveKeep {
+		t.Errorf("Expected %d auto-saves kept, got %d", autoSaveKeep, len(files))
+	}
+	if latest, _ := latestAutoSave(dir); latest != last {
+		t.Errorf("Expected latest auto-save %s, got %s", last, latest)
+	}
+}
@@ -0,0 +1,24 @@
+package main
+
+import "time"
+
+/*
+runWithinBudget calls step up to cycles times, stopping early once budget has
+elapsed since the first call as measured by now. At least one step always runs so
+emulation keeps making progress, and a non-positive budget disables the check.
+step returns false when the CPU cannot make progress until the next frame (e.g.
+it is waiting for vblank); the rest of the batch is then dropped rather than
+deferred. It returns the number of steps run or dropped.
+*/
+func runWithinBudget(cycles int, budget time.Duration, now func() time.Time, step func() bool) int {
+	start := now()
+	for i := 0; i < cycles; i++ {
+		if budget > 0 && i > 0 && now().Sub(start) >= budget {
+			return i
+		}
+		if !step() {
+			return cycles
+		}
+	}
+	return cycles
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+/*
+TestRunWithinBudgetStopsOnSlowSteps simulates instructions taking 3ms each against
+an 8ms budget and checks that the loop stops after the budget is used up.
+*/
+func TestRunWithinBudgetStopsOnSlowSteps(t *testing.T) {
+	clock := time.Unix(0, 0)
+	now := func() time.Time { return clock }
+	steps := 0
+	slowStep := func() bool {
+		steps++
+		clock = clock.Add(3 * time.Millisecond)
+		return true
+	}
+
+	ran := runWithinBudget(100, 8*time.Millisecond, now, slowStep)
+	if ran != 3 || steps != 3 {
+		t.Errorf("Expected 3 steps within an 8ms budget, got %d (ran=%d)", steps, ran)
+	}
+}
+
+/*
+TestRunWithinBudgetFastAndDisabled checks that fast steps all run, that a zero
+budget disables the check, and that at least one step always runs.
+*/
+func TestRunWithinBudgetFastAndDisabled(t *testing.T) {
+	clock := time.Unix(0, 0)
+	now := func() time.Time { return clock }
+	slow := func() bool {
+		clock = clock.Add(time.Second)
+		return true
+	}
+
+	if ran := runWithinBudget(10, 8*time.Millisecond, now, func() bool { return true }); ran != 10 {
+		t.Errorf("Expected all 10 fast steps to run, got %d", ran)
+	}
+	if ran := runWithinBudget(5, 0, now, slow); ran != 5 {
+		t.Errorf("Expected a disabled budget to run all 5 steps, got %d", ran)
+	}
+	if ran := runWithinBudget(5, time.Nanosecond, now, slow); ran != 1 {
+		t.Errorf("Expected at least one step to run, got %d", ran)
+	}
+}
+
+/*
+TestRunWithinBudgetStallDropsBatch checks that a step reporting a stall ends the
+batch and that the remaining steps count as done rather than deferred.
+*/
+func TestRunWithinBudgetStallDropsBatch(t *testing.T) {
+	now := func() time.Time { return time.Unix(0, 0) }
+	steps := 0
+	ran := runWithinBudget(10, 0, now, func() bool {
+		steps++
+		return steps < 3
+	})
+	if steps != 3 || ran != 10 {
+		t.Errorf("Expected 3 steps and the batch marked done, got %d steps (ran=%d)", steps, ran)
+	}
+}
@@ -0,0 +1,36 @@
+package chip8
+
+import "math"
+
+// DefaultAudioPitch is the XO-CHIP pitch register value after reset. At this
+// pitch the audio pattern plays back at 4000 samples per second.
+const DefaultAudioPitch = 64
+
+// DefaultAudioPattern is the audio pattern after reset: a square wave with an
+// 8-sample period, i.e. a 500Hz tone at the default pitch, close to the classic
+// CHIP-8 buzzer.
+var DefaultAudioPattern = [16]byte{
+	0xF0, 0xF0, 0xF0, 0xF0, 0xF0, 0xF0, 0xF0, 0xF0,
+	0xF0, 0xF0, 0xF0, 0xF0, 0xF0, 0xF0, 0xF0, 0xF0,
+}
+
+// TimerFrequency is the rate in Hz at which the delay and sound timers count down.
+const TimerFrequency = 60
+
+// PitchToSampleRate converts an XO-CHIP pitch register value to the playback
+// rate of the 1-bit audio pattern in samples per second, using the XO-CHIP
+// formula 4000 * 2^((pitch - 64) / 48).
+func PitchToSampleRate(pitch byte) float64 {
+	return 4000 * math.Pow(2, (float64(pitch)-DefaultAudioPitch)/48)
+}
+
+// SampleRate returns the audio pattern playback rate for the current pitch register.
+func (c *Chip8) SampleRate() float64 {
+	return PitchToSampleRate(c.AudioPitch)
+}
+
+// SoundDurationMs returns how long the tone will keep sounding, in
+// milliseconds, if the sound timer counts down undisturbed.
+func (c *Chip8) SoundDurationMs() float64 {
+	return float64(c.SoundTimer) * 1000 / TimerFrequency
+}
@@ -0,0 +1,99 @@
+package chip8
+
+import (
+	"math"
+	"testing"
+)
+
+/*
+TestPitchToSampleRate checks the XO-CHIP pitch formula at the default pitch and one
+octave (48 steps) either side of it.
+*/
+func TestPitchToSampleRate(t *testing.T) {
+	cases := []struct {
+		pitch byte
+		want  float64
+	}{
+		{64, 4000},
+		{112, 8000},
+		{16, 2000},
+		{0, 4000 * math.Pow(2, -64.0/48)},
+		{255, 4000 * math.Pow(2, 191.0/48)},
+	}
+	for _, tc := range cases {
+		if got := PitchToSampleRate(tc.pitch); math.Abs(got-tc.want) > 1e-6 {
+			t.Errorf("pitch %d: expected %.4f Hz, got %.4f Hz", tc.pitch, tc.want, got)
+		}
+	}
+}
+
+/*
+TestResetAudioPitch checks that Reset restores the default pitch.
+*/
+func TestResetAudioPitch(t *testing.T) {
+	c := New()
+	c.AudioPitch = 200
+	c.Reset()
+	if c.AudioPitch != DefaultAudioPitch {
+		t.Errorf("Expected AudioPitch %d after Reset, got %d", DefaultAudioPitch, c.AudioPitch)
+	}
+	if c.SampleRate() != 4000 {
+		t.Errorf("Expected 4000 Hz after Reset, got %f", c.SampleRate())
+	}
+}
+
+/*
+TestAudioPatternOpcodes checks that F002 loads the 16-byte pattern from I and
+FX3A sets the pitch, and that Reset restores the default pattern and pitch.
+*/
+func TestAudioPatternOpcodes(t *testing.T) {
+	c := New()
+	c.I = 0x300
+	for i := 0; i < 16; i++ {
+		c.Memory[0x300+i] = byte(i * 17)
+	}
+	c.Registers[0x4] = 112
+	copy(c.Memory[ProgramStart:], []byte{0xF0, 0x02, 0xF4, 0x3A})
+	c.IsRunning = true
+
+	c.EmulateCycle()
+	c.EmulateCycle()
+
+	for i, b := range c.AudioBuffer {
+		if b != byte(i*17) {
+			t.Fatalf("AudioBuffer[%d]: expected 0x%02X, got 0x%02X", i, byte(i*17), b)
+		}
+	}
+	if c.AudioPitch != 112 {
+		t.Errorf("Expected pitch 112, got %d", c.AudioPitch)
+	}
+
+	c.Reset()
+	if c.AudioBuffer != DefaultAudioPattern || c.AudioPitch != DefaultAudioPitch {
+		t.Error("Expected Reset to restore the default pattern and pitch")
+	}
+}
+
+/*
+TestUpdateTimersReportsSound checks that UpdateTimers reports the tone as active
+until the sound timer runs out, and that SoundDurationMs follows the 60Hz timer.
+*/
+func TestUpdateTimersReportsSound(t *testing.T) {
+	c := New()
+	c.SoundTimer = 3
+
+	if got := c.SoundDurationMs(); got != 50 {
+		t.Errorf("Expected 50ms of sound for 3 ticks, got %v", got)
+	}
+	for i := 0; i < 2; i++ {
+		if !c.UpdateTimers() {
+			t.Fatalf("Expected sound to be active after tick %d", i+1)
+		}
+	}
+	if c.UpdateTimers() {
+		t.Error("Expected sound to stop once the timer reaches zero")
+	}
+	if c.UpdateTimers() {
+		t.Error("Expected sound to stay off with the timer at zero")
+	}
+}
@@ -0,0 +1,66 @@
+package chip8
+
+import "fmt"
+
+// BreakCondition is a register test attached to a breakpoint: the breakpoint only
+// halts when V[Register] compared against Value with Op holds.
+type BreakCondition struct {
+	Register byte   `json:"register"`
+	Op       string `json:"op"`
+	Value    byte   `json:"value"`
+}
+
+// Holds reports whether the condition is true for the given registers.
+func (bc BreakCondition) Holds(registers [16]byte) bool {
+	v := registers[bc.Register&0xF]
+	switch bc.Op {
+	case "==":
+		return v == bc.Value
+	case "!=":
+		return v != bc.Value
+	case ">":
+		return v > bc.Value
+	case "<":
+		return v < bc.Value
+	case ">=":
+		return v >= bc.Value
+	case "<=":
+		return v <= bc.Value
+	}
+	return false
+}
+
+// validBreakOp reports whether op is a comparison BreakCondition understands.
+func validBreakOp(op string) bool {
+	switch op {
+	case "==", "!=", ">", "<", ">=", "<=":
+		return true
+	}
+	return false
+}
+
+// SetConditionalBreakpoint sets a breakpoint at addr that only halts when
+// V[register] op value holds at the time the address is reached. Hits where the
+// condition fails do not count towards a skip count.
+func (c *Chip8) SetConditionalBreakpoint(addr uint16, register byte, op string, value byte) error {
+	if register > 0xF {
+		return fmt.Errorf("invalid register V%X", register)
+	}
+	if !validBreakOp(op) {
+		return fmt.Errorf("invalid comparison %q", op)
+	}
+	if c.BreakConditions == nil {
+		c.BreakConditions = make(map[uint16]BreakCondition)
+	}
+	c.Breakpoints[addr] = true
+	c.BreakConditions[addr] = BreakCondition{Register: register, Op: op, Value: value}
+	delete(c.breakpointHits, addr)
+	return nil
+}
+
+// breakConditionMet reports whether the breakpoint at addr may halt: true when it
+// has no condition attached, otherwise the condition's result.
+func (c *Chip8) breakConditionMet(addr uint16) bool {
+	cond, ok := c.BreakConditions[addr]
+	return !ok || cond.Holds(c.Registers)
+}
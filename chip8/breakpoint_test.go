@@ -0,0 +1,52 @@
+package chip8
+
+import "testing"
+
+/*
+TestConditionalBreakpoint checks that a conditional breakpoint halts only once
+its register comparison holds, for each supported operator.
+*/
+func TestConditionalBreakpoint(t *testing.T) {
+	tests := []struct {
+		op    string
+		value byte
+		want  byte // V0 when the breakpoint halts
+	}{
+		{"==", 3, 3},
+		{"!=", 0, 1},
+		{">", 4, 5},
+		{"<", 1, 0},
+	}
+	for _, tt := range tests {
+		c := New()
+		// 0x200: ADD V0, 1 ; 0x202: JP 0x200
+		copy(c.Memory[ProgramStart:], []byte{0x70, 0x01, 0x12, 0x00})
+		if err := c.SetConditionalBreakpoint(ProgramStart, 0, tt.op, tt.value); err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.op, err)
+		}
+		c.IsRunning = true
+		for i := 0; i < 40 && c.IsRunning; i++ {
+			c.EmulateCycle()
+		}
+		if c.IsRunning {
+			t.Errorf("Expected V0 %s %d to halt the CPU", tt.op, tt.value)
+			continue
+		}
+		if c.Registers[0] != tt.want {
+			t.Errorf("Expected V0 %s %d to halt with V0 = %d, got %d", tt.op, tt.value, tt.want, c.Registers[0])
+		}
+	}
+
+	c := New()
+	if err := c.SetConditionalBreakpoint(ProgramStart, 0x10, "==", 0); err == nil {
+		t.Error("Expected an error for register V16")
+	}
+	if err := c.SetConditionalBreakpoint(ProgramStart, 0, "=~", 0); err == nil {
+		t.Error("Expected an error for an unknown operator")
+	}
+	c.SetConditionalBreakpoint(ProgramStart, 0, "==", 1)
+	c.Reset()
+	if len(c.BreakConditions) != 0 {
+		t.Error("Expected Reset to clear breakpoint conditions")
+	}
+}
@@ -1,34 +1,85 @@
 package chip8
 
 import (
+	"errors"
 	"fmt"
+	"hash/fnv"
+	"math/bits"
 	"math/rand"
-	"time"
 )
 
 const (
 	DisplayWidth  = 64
 	DisplayHeight = 32
+	HiResWidth    = 128 // SUPER-CHIP high-resolution mode
+	HiResHeight   = 64
 	ProgramStart  = 0x200
 	FontSetStart  = 0x50
 )
 
+// CoreVersion identifies this emulator core. Save states record it so a state
+// can be traced back to the build that wrote it.
+const CoreVersion = "1.2.0"
+
 // Chip8 represents the state of the CHIP-8 emulator
 type Chip8 struct {
-	Memory      [4096]byte
-	Registers   [16]byte
-	I           uint16
-	PC          uint16
-	Display     [DisplayWidth * DisplayHeight]byte
-	DelayTimer  byte
-	SoundTimer  byte
-	Stack       [16]uint16
-	SP          byte
-	Keys        [16]bool
-	DrawFlag    bool
-	IsRunning   bool
-	Breakpoints map[uint16]bool // Map to store breakpoint addresses
-	randSource  rand.Source
+	Memory           []byte // DefaultMemorySize bytes unless set with WithMemorySize
+	Registers        [16]byte
+	I                uint16
+	PC               uint16
+	Display          [HiResWidth * HiResHeight]byte // Active frame is the first Width()*Height() bytes, row-major
+	Plane2           [HiResWidth * HiResHeight]byte // XO-CHIP second bit plane, laid out like Display
+	SelectedPlanes   byte                           // XO-CHIP plane mask set by FN01: bit 0 is Display, bit 1 is Plane2
+	HiRes            bool                           // SUPER-CHIP 128x64 mode, toggled by 00FF/00FE
+	DelayTimer       byte
+	SoundTimer       byte
+	AudioPitch       byte     // XO-CHIP pitch register; sets the audio pattern sample rate
+	AudioBuffer      [16]byte // XO-CHIP 1-bit audio pattern, 128 samples played MSB first while the sound timer runs
+	Stack            [16]uint16
+	SP               byte
+	StackHighWater   byte // Deepest SP reached since the last reset
+	Keys             [16]bool
+	DrawFlag         bool
+	ScreenCleared    bool // Set by CLS and cleared by the next draw: the screen is blank pending redraw
+	IsRunning        bool
+	Halted           bool                      // The ROM jumped to itself (1NNN at NNN); cleared by Reset or a key press
+	StopOnHalt       bool                      // Stall cycles while Halted instead of spinning on the jump
+	Breakpoints      map[uint16]bool           // Map to store breakpoint addresses
+	BreakpointSkips  map[uint16]int            // Hits a breakpoint lets through before halting; absent means halt on the first
+	BreakConditions  map[uint16]BreakCondition // Register tests a breakpoint must pass to halt; absent means unconditional
+	Watchpoints      map[uint16]bool           // Memory addresses whose writes by the ROM pause emulation
+	WatchpointHit    bool                      // Set when a watchpoint paused emulation; WatchpointAddr says which
+	WatchpointAddr   uint16                    // Address of the last triggered watchpoint
+	CycleCount       uint64                    // Instructions executed since the last reset
+	FrameCount       uint64                    // Timer ticks (60Hz frames) since the last reset
+	CollisionCount   uint64                    // DRW instructions that set VF for a collision since the last reset
+	Quirks           Quirks                    // Interpreter-specific behaviour switches
+	Strict           bool                      // Treat opcodes outside the documented instruction set as faults
+	HaltOnUnknown    bool                      // Halt on unknown opcodes instead of skipping them
+	HaltOnOverflow   bool                      // Halt when DRW or FX33/FX55/FX65 run past the end of memory instead of wrapping
+	LastError        string                    // Description of the last fault or unknown opcode, if any
+	ORDraw           bool                      // Diagnostic only: DRW ORs pixels in, never erasing or reporting collisions
+	DryRunDraw       bool                      // Diagnostic only: DRW sets VF for collisions but leaves the display untouched
+	ResetFillPattern []byte                    // Repeated across memory by Reset instead of zeros; makes uninitialised reads visible
+	RandSeed         int64                     // Seed of the RND generator
+	RandDraws        uint64                    // Values drawn from the RND generator since it was seeded
+	KeyWaitHeld      bool                      // FX0A under WaitKeyOnRelease saw KeyWaitKey go down and waits for its release
+	KeyWaitKey       byte                      // Key FX0A is waiting to see released; valid while KeyWaitHeld
+
+	soundActiveFrames uint64          // Timer ticks during which the sound timer was nonzero
+	protected         map[uint16]bool // Read-only addresses; survives Reset
+	opcodesUsed       map[uint16]bool // Distinct instruction kinds executed since the last reset
+	trace             *traceBuffer    // Recent instructions; nil when tracing is off
+	breakpointHits    map[uint16]int  // Times each breakpoint address has been reached since the last reset
+	resumeFrom        uint16          // Breakpoint address that last halted the CPU
+	resuming          bool            // Let the next cycle run past the breakpoint at resumeFrom
+	waitingForVBlank  bool            // DRW under the DisplayWait quirk; cycles stall until the next timer tick
+	hooks             []opcodeHook    // Custom instructions, checked before the built-in decode; survives Reset
+	rng               *rand.Rand      // RND generator; rebuilt when it is out of step with RandSeed and RandDraws
+	rngSeed           int64           // Seed rng was built from
+	rngDraws          uint64          // Values drawn from rng
+	pinnedSeed        *int64          // Seed Reset uses instead of the clock; set by WithSeed
+	cycleErr          error           // Fault or unknown opcode met by the instruction EmulateCycle is running
 }
 
 // FontSet (keep as is)
@@ -51,10 +102,16 @@ var FontSet = []byte{
 	0xF0, 0x80, 0xF0, 0x80, 0x80, // F
 }
 
-// New creates and initializes a new Chip8 emulator
-func New() *Chip8 {
+// New creates and initializes a new Chip8 emulator with DefaultMemorySize bytes
+// of memory, unless an option says otherwise.
+func New(opts ...Option) *Chip8 {
 	c := &Chip8{}
 	c.Breakpoints = make(map[uint16]bool) // Initialize the map
+	c.protected = make(map[uint16]bool)
+	c.Quirks = DefaultQuirks()
+	for _, opt := range opts {
+		opt(c)
+	}
 	c.Reset()
 	return c
 }
@@ -64,15 +121,40 @@ func (c *Chip8) Reset() {
 	c.PC = ProgramStart
 	c.I = 0
 	c.SP = 0
+	c.StackHighWater = 0
+	c.CycleCount = 0
+	c.FrameCount = 0
+	c.CollisionCount = 0
+	c.soundActiveFrames = 0
+	c.opcodesUsed = make(map[uint16]bool)
+	if c.trace != nil {
+		c.SetTracing(true)
+	}
 	c.DelayTimer = 0
 	c.SoundTimer = 0
+	c.AudioPitch = DefaultAudioPitch
+	c.AudioBuffer = DefaultAudioPattern
 	c.DrawFlag = false
+	c.ScreenCleared = false
 	c.IsRunning = false
+	c.Halted = false
+	c.LastError = ""
 
 	// Clear memory, registers, display, and stack
-	c.Memory = [4096]byte{}
+	if len(c.Memory) == 0 {
+		c.Memory = make([]byte, DefaultMemorySize)
+	}
+	clear(c.Memory)
+	if len(c.ResetFillPattern) > 0 {
+		for i := range c.Memory {
+			c.Memory[i] = c.ResetFillPattern[i%len(c.ResetFillPattern)]
+		}
+	}
 	c.Registers = [16]byte{}
-	c.Display = [DisplayWidth * DisplayHeight]byte{}
+	c.Display = [HiResWidth * HiResHeight]byte{}
+	c.Plane2 = [HiResWidth * HiResHeight]byte{}
+	c.SelectedPlanes = 1
+	c.HiRes = false
 	c.Stack = [16]uint16{}
 	c.Keys = [16]bool{}
 
@@ -84,13 +166,23 @@ func (c *Chip8) Reset() {
 			delete(c.Breakpoints, k)
 		}
 	}
+	c.BreakpointSkips = nil
+	c.BreakConditions = nil
+	c.breakpointHits = nil
+	c.Watchpoints = nil
+	c.WatchpointHit = false
+	c.WatchpointAddr = 0
+	c.resuming = false
+	c.waitingForVBlank = false
+	c.KeyWaitHeld = false
+	c.KeyWaitKey = 0
 
 	// Load font set into memory
 	for i := 0; i < len(FontSet); i++ {
 		c.Memory[FontSetStart+i] = FontSet[i]
 	}
 
-	c.randSource = rand.NewSource(time.Now().UnixNano())
+	c.reseed()
 }
 
 // LoadROM (keep as is)
@@ -104,76 +196,187 @@ func (c *Chip8) LoadROM(data []byte) error {
 	return nil
 }
 
-// EmulateCycle (keep as is)
-func (c *Chip8) EmulateCycle() {
-	if !c.IsRunning {
+// CycleResult describes what one EmulateCycle call did.
+type CycleResult struct {
+	Drew   bool   // The instruction changed the display
+	Halted bool   // The CPU will not run further instructions on its own: paused by a breakpoint, watchpoint or fault, or Halted on a self-jump
+	Err    error  // The fault or unknown opcode met by the instruction, if any; also recorded in LastError
+	Opcode uint16 // The instruction executed; 0 if none ran
+}
+
+// EmulateCycle executes the instruction at PC, unless the CPU is paused,
+// stalled or stopped at a breakpoint, and reports what happened. DrawFlag,
+// LastError and the other fields it has always set are still updated, but the
+// result is the preferred way to find out what a cycle did.
+func (c *Chip8) EmulateCycle() CycleResult {
+	drawn := c.DrawFlag
+	c.DrawFlag = false
+	c.cycleErr = nil
+	ran := c.CycleCount
+	opcode := c.cycle()
+	res := CycleResult{
+		Drew:   c.DrawFlag,
+		Halted: !c.IsRunning || c.Halted,
+		Err:    c.cycleErr,
+	}
+	if c.CycleCount != ran {
+		res.Opcode = opcode
+	}
+	c.DrawFlag = c.DrawFlag || drawn
+	c.cycleErr = nil
+	return res
+}
+
+// cycle runs one fetch-decode-execute step and returns the opcode fetched, if any.
+func (c *Chip8) cycle() (opcode uint16) {
+	if !c.IsRunning || c.waitingForVBlank || (c.Halted && c.StopOnHalt) {
 		return
 	}
 
-	// Check for breakpoint at current PC
-	if c.Breakpoints[c.PC] {
+	// Running off the end of memory leaves PC one past it. Wrap it like the
+	// address bus would, or fault in strict mode or under HaltOnOverflow.
+	if int(c.PC) >= len(c.Memory) {
+		if c.Strict || c.HaltOnOverflow {
+			c.fault(fmt.Sprintf("PC 0x%04X runs past the end of memory (%d bytes)", c.PC, len(c.Memory)))
+			return
+		}
+		c.PC = c.wrapAddr(int(c.PC))
+	}
+
+	// Check for breakpoint at current PC. After halting on a breakpoint, the next
+	// cycle executes the instruction there instead of halting again immediately.
+	if c.Breakpoints[c.PC] && !(c.resuming && c.resumeFrom == c.PC) && c.breakConditionMet(c.PC) && c.countBreakpointHit(c.PC) {
 		c.IsRunning = false // Pause emulation
+		c.resumeFrom = c.PC
+		c.resuming = true
 		return
 	}
+	c.resuming = false
 
 	// Fetch opcode
-	opcode := uint16(c.Memory[c.PC])<<8 | uint16(c.Memory[c.PC+1])
+	opcode = uint16(c.Memory[c.PC])<<8 | uint16(c.Memory[(int(c.PC)+1)%len(c.Memory)])
 
 	// Decode opcode parts
-	vx := (opcode & 0x0F00) >> 8
-	vy := (opcode & 0x00F0) >> 4
-	nnn := opcode & 0x0FFF
-	nn := byte(opcode & 0x00FF)
-	n := byte(opcode & 0x000F)
+	f := decode(opcode)
+	vx, vy, nnn, nn, n := f.x, f.y, f.nnn, f.nn, f.n
 
 	// Increment PC before execution (most common case)
 	c.PC += 2
+	c.CycleCount++
+	if c.opcodesUsed == nil { // Not carried over by save states
+		c.opcodesUsed = make(map[uint16]bool)
+	}
+	c.opcodesUsed[opcodeKind(opcode)] = true
+	if c.trace != nil {
+		c.trace.add(TraceEntry{Cycle: c.CycleCount, PC: c.PC - 2, Opcode: opcode, Mnemonic: DisassembleWithQuirks(opcode, c.Quirks)})
+	}
+	if handler := c.customHandler(opcode); handler != nil {
+		handler(c, opcode)
+		return
+	}
 
 	switch opcode & 0xF000 {
 	// ... (all opcode cases remain the same)
 	case 0x0000:
 		switch opcode & 0x00FF {
+		case 0x00C0, 0x00C1, 0x00C2, 0x00C3, 0x00C4, 0x00C5, 0x00C6, 0x00C7,
+			0x00C8, 0x00C9, 0x00CA, 0x00CB, 0x00CC, 0x00CD, 0x00CE, 0x00CF: // SCD n: scroll down n lines
+			c.scroll(0, c.scrollDistance(int(n)))
 		case 0x00E0: // CLS
-			for i := range c.Display {
-				c.Display[i] = 0
+			for i, plane := range c.planes() {
+				if c.SelectedPlanes&(1<<i) != 0 {
+					*plane = [HiResWidth * HiResHeight]byte{}
+				}
 			}
 			c.DrawFlag = true
+			c.ScreenCleared = true
 		case 0x00EE: // RET
+			if c.SP == 0 {
+				c.fault(fmt.Sprintf("stack underflow: RET at 0x%04X with an empty stack", c.PC-2))
+				break
+			}
+			if int(c.SP) > len(c.Stack) {
+				c.fault(fmt.Sprintf("stack pointer %d out of range: RET at 0x%04X with a %d-entry stack", c.SP, c.PC-2, len(c.Stack)))
+				break
+			}
 			c.SP--
 			c.PC = c.Stack[c.SP]
+		case 0x00FB: // SCR: scroll right 4 pixels
+			c.scroll(c.scrollDistance(4), 0)
+		case 0x00FC: // SCL: scroll left 4 pixels
+			c.scroll(-c.scrollDistance(4), 0)
+		case 0x00FE: // LOW: SUPER-CHIP low-resolution mode
+			c.setHiRes(false)
+		case 0x00FF: // HIGH: SUPER-CHIP high-resolution mode
+			c.setHiRes(true)
+		default: // SYS addr: call into machine code, ignored by modern interpreters
+			if c.Strict {
+				// In a modern ROM this almost always means data is being executed
+				c.fault(fmt.Sprintf("SYS 0x%03X at 0x%04X is not supported in strict mode", nnn, c.PC-2))
+			}
 		}
 	case 0x1000: // JP addr
+		// A jump to itself is the usual way for a ROM to stop. With
+		// StopOnHalt the CPU then stalls rather than spinning, so the
+		// debugger and the cycle count stay quiet.
+		if nnn == c.PC-2 {
+			c.Halted = true
+		}
 		c.PC = nnn
 	case 0x2000: // CALL addr
+		if int(c.SP) >= len(c.Stack) {
+			c.fault(fmt.Sprintf("stack overflow: CALL 0x%03X at 0x%04X exceeds %d nested calls", nnn, c.PC-2, len(c.Stack)))
+			break
+		}
 		c.Stack[c.SP] = c.PC
 		c.SP++
+		if c.SP > c.StackHighWater {
+			c.StackHighWater = c.SP
+		}
 		c.PC = nnn
 	case 0x3000: // SE Vx, byte
 		if c.Registers[vx] == nn {
-			c.PC += 2
+			c.skipNext()
 		}
 	case 0x4000: // SNE Vx, byte
 		if c.Registers[vx] != nn {
-			c.PC += 2
+			c.skipNext()
 		}
 	case 0x5000: // SE Vx, Vy
+		if n != 0 {
+			c.unknownOpcode(opcode)
+			break
+		}
 		if c.Registers[vx] == c.Registers[vy] {
-			c.PC += 2
+			c.skipNext()
 		}
 	case 0x6000: // LD Vx, byte
 		c.Registers[vx] = nn
-	case 0x7000: // ADD Vx, byte
-		c.Registers[vx] += nn
+	case 0x7000: // ADD Vx, byte (VF is unaffected unless the AddByteSetsVF quirk is on)
+		sum := uint16(c.Registers[vx]) + uint16(nn)
+		c.Registers[vx] = byte(sum)
+		if c.Quirks.AddByteSetsVF && vx != 0xF { // When VF is the target the sum wins
+			c.Registers[0xF] = byte(sum >> 8)
+		}
 	case 0x8000:
 		switch n {
 		case 0x0: // LD Vx, Vy
 			c.Registers[vx] = c.Registers[vy]
 		case 0x1: // OR Vx, Vy
 			c.Registers[vx] |= c.Registers[vy]
+			if c.Quirks.VFResetOnLogic {
+				c.Registers[0xF] = 0
+			}
 		case 0x2: // AND Vx, Vy
 			c.Registers[vx] &= c.Registers[vy]
+			if c.Quirks.VFResetOnLogic {
+				c.Registers[0xF] = 0
+			}
 		case 0x3: // XOR Vx, Vy
 			c.Registers[vx] ^= c.Registers[vy]
+			if c.Quirks.VFResetOnLogic {
+				c.Registers[0xF] = 0
+			}
 		case 0x4: // ADD Vx, Vy
 			if uint16(c.Registers[vx])+uint16(c.Registers[vy]) > 255 {
 				c.Registers[0xF] = 1
@@ -189,8 +392,14 @@ func (c *Chip8) EmulateCycle() {
 			}
 			c.Registers[vx] -= c.Registers[vy]
 		case 0x6: // SHR Vx {, Vy}
-			c.Registers[0xF] = c.Registers[vx] & 0x1
-			c.Registers[vx] >>= 1
+			flag := c.shiftSource(vx, vy) & 0x1
+			if c.Quirks.ShiftFlagLast {
+				c.Registers[vx] = c.shiftSource(vx, vy) >> 1
+				c.Registers[0xF] = flag
+			} else {
+				c.Registers[0xF] = flag
+				c.Registers[vx] = c.shiftSource(vx, vy) >> 1
+			}
 		case 0x7: // SUBN Vx, Vy
 			if c.Registers[vy] > c.Registers[vx] {
 				c.Registers[0xF] = 1
@@ -199,60 +408,114 @@ func (c *Chip8) EmulateCycle() {
 			}
 			c.Registers[vx] = c.Registers[vy] - c.Registers[vx]
 		case 0xE: // SHL Vx {, Vy}
-			c.Registers[0xF] = c.Registers[vx] >> 7
-			c.Registers[vx] <<= 1
+			flag := c.shiftSource(vx, vy) >> 7
+			if c.Quirks.ShiftFlagLast {
+				c.Registers[vx] = c.shiftSource(vx, vy) << 1
+				c.Registers[0xF] = flag
+			} else {
+				c.Registers[0xF] = flag
+				c.Registers[vx] = c.shiftSource(vx, vy) << 1
+			}
+		default:
+			c.unknownOpcode(opcode)
 		}
 	case 0x9000: // SNE Vx, Vy
+		if n != 0 {
+			c.unknownOpcode(opcode)
+			break
+		}
 		if c.Registers[vx] != c.Registers[vy] {
-			c.PC += 2
+			c.skipNext()
 		}
 	case 0xA000: // LD I, addr
 		c.I = nnn
-	case 0xB000: // JP V0, addr
-		c.PC = nnn + uint16(c.Registers[0])
+	case 0xB000: // JP V0, addr (JP Vx, addr under the JumpQuirk)
+		// The target can run past the end of memory. Wrap it like the 12-bit
+		// address bus would, or fault in strict mode.
+		offset := c.Registers[0]
+		if c.Quirks.JumpQuirk {
+			offset = c.Registers[vx]
+		}
+		target := nnn + uint16(offset)
+		if int(target) >= len(c.Memory) && c.Strict {
+			c.fault(fmt.Sprintf("JP V0, 0x%03X at 0x%04X jumps past the end of memory (0x%04X)", nnn, c.PC-2, target))
+			break
+		}
+		c.PC = uint16(int(target) % len(c.Memory))
 	case 0xC000: // RND Vx, byte
-		r := rand.New(c.randSource)
-		c.Registers[vx] = byte(r.Intn(256)) & nn
+		c.Registers[vx] = c.randomByte() & nn
 	case 0xD000: // DRW Vx, Vy, nibble
-		xCoord := uint16(c.Registers[vx])
-		yCoord := uint16(c.Registers[vy])
-		height := uint16(n)
+		addr := c.I
+		// DXY0 draws a SUPER-CHIP 16x16 sprite, two bytes per row, in hi-res
+		// mode and, under LargeSpritesInLowRes, in low-res mode too.
+		rows, wide := uint16(n), false
+		if n == 0 && (c.HiRes || c.Quirks.LargeSpritesInLowRes) {
+			rows, wide = 16, true
+		}
+		size := rows
+		if wide {
+			size *= 2
+		}
+		if !c.checkRange("DRW", addr, int(size)*bits.OnesCount8(c.SelectedPlanes&0x3)) {
+			break
+		}
 		c.Registers[0xF] = 0
-
-		for yline := uint16(0); yline < height; yline++ {
-			spriteByte := c.Memory[c.I+yline]
-			for xline := uint16(0); xline < 8; xline++ {
-				if (spriteByte & (0x80 >> xline)) != 0 {
-					finalX := (xCoord + xline) % DisplayWidth
-					finalY := (yCoord + yline) % DisplayHeight
-					index := finalY*DisplayWidth + finalX
-
-					if index < uint16(len(c.Display)) {
-						if c.Display[index] == 1 {
-							c.Registers[0xF] = 1
-						}
-						c.Display[index] ^= 1
-					}
-				}
+		// Draw to each selected plane in turn; XO-CHIP reads the sprite for the
+		// second plane straight after the first.
+		for i, plane := range c.planes() {
+			if c.SelectedPlanes&(1<<i) == 0 {
+				continue
 			}
+			if c.drawSprite(plane, c.Registers[vx], c.Registers[vy], addr, rows, wide) {
+				c.Registers[0xF] = 1
+			}
+			addr += size
+		}
+		if c.Registers[0xF] == 1 {
+			c.CollisionCount++
 		}
 		c.DrawFlag = true
+		c.ScreenCleared = false
+		c.waitingForVBlank = c.Quirks.DisplayWait
 	case 0xE000:
 		switch nn {
 		case 0x9E: // SKP Vx
 			if c.Keys[c.Registers[vx]] {
-				c.PC += 2
+				c.skipNext()
 			}
 		case 0xA1: // SKNP Vx
 			if !c.Keys[c.Registers[vx]] {
-				c.PC += 2
+				c.skipNext()
 			}
+		default:
+			c.unknownOpcode(opcode)
 		}
 	case 0xF000:
 		switch nn {
+		case 0x00: // LD I, long: XO-CHIP 16-bit load of I from the next word
+			if vx != 0 {
+				c.unknownOpcode(opcode)
+				break
+			}
+			c.I = uint16(c.Memory[int(c.PC)%len(c.Memory)])<<8 | uint16(c.Memory[(int(c.PC)+1)%len(c.Memory)])
+			c.PC += 2
+		case 0x01: // PLANE n: XO-CHIP bit plane select
+			c.SelectedPlanes = byte(vx) & 0x3
+		case 0x02: // AUDIO: XO-CHIP load audio pattern from [I]
+			if vx != 0 {
+				c.unknownOpcode(opcode)
+				break
+			}
+			for i := range c.AudioBuffer {
+				c.AudioBuffer[i] = c.Memory[(int(c.I)+i)%len(c.Memory)]
+			}
 		case 0x07: // LD Vx, DT
 			c.Registers[vx] = c.DelayTimer
 		case 0x0A: // LD Vx, K
+			if c.Quirks.WaitKeyOnRelease {
+				c.waitForKeyRelease(vx)
+				break
+			}
 			keyPress := false
 			for i, pressed := range c.Keys {
 				if pressed {
@@ -270,38 +533,427 @@ func (c *Chip8) EmulateCycle() {
 			c.SoundTimer = c.Registers[vx]
 		case 0x1E: // ADD I, Vx
 			c.I += uint16(c.Registers[vx])
+		case 0x3A: // PITCH Vx: XO-CHIP audio pitch
+			c.AudioPitch = c.Registers[vx]
 		case 0x29: // LD F, Vx
 			c.I = uint16(c.Registers[vx])*5 + FontSetStart
 		case 0x33: // LD B, Vx
-			c.Memory[c.I] = c.Registers[vx] / 100
-			c.Memory[c.I+1] = (c.Registers[vx] / 10) % 10
-			c.Memory[c.I+2] = c.Registers[vx] % 10
+			if !c.checkRange("LD B", c.I, 3) {
+				break
+			}
+			digits := [3]byte{c.Registers[vx] / 100, (c.Registers[vx] / 10) % 10, c.Registers[vx] % 10}
+			for i, d := range digits {
+				if !c.writeMemory(c.wrapAddr(int(c.I)+i), d) {
+					break
+				}
+			}
 		case 0x55: // LD [I], Vx
+			if !c.checkRange("LD [I]", c.I, int(vx)+1) {
+				break
+			}
 			for i := uint16(0); i <= vx; i++ {
-				c.Memory[c.I+i] = c.Registers[i]
+				if !c.writeMemory(c.wrapAddr(int(c.I)+int(i)), c.Registers[i]) {
+					return
+				}
+			}
+			if c.Quirks.IncrementIOnStore {
+				c.I += vx + 1
 			}
-			// Original interpreters incremented I after this operation. Many ROMs depend on this quirk.
-			c.I += vx + 1
 		case 0x65: // LD Vx, [I]
+			if !c.checkRange("LD Vx, [I]", c.I, int(vx)+1) {
+				break
+			}
 			for i := uint16(0); i <= vx; i++ {
-				c.Registers[i] = c.Memory[c.I+i]
+				c.Registers[i] = c.Memory[c.wrapAddr(int(c.I)+int(i))]
+			}
+			if c.Quirks.IncrementIOnStore {
+				c.I += vx + 1
 			}
-			// Original interpreters also incremented I here.
-			c.I += vx + 1
+		default:
+			c.unknownOpcode(opcode)
 		}
 	default:
-		fmt.Printf("Unknown opcode: 0x%04X\n", opcode)
+		c.unknownOpcode(opcode)
+	}
+	return
+}
+
+// shiftSource returns the register 8XY6/8XYE shift: VY under the ShiftUsesVY
+// quirk, otherwise VX itself.
+func (c *Chip8) shiftSource(vx, vy uint16) byte {
+	if c.Quirks.ShiftUsesVY {
+		return c.Registers[vy]
+	}
+	return c.Registers[vx]
+}
+
+// unknownOpcode handles an opcode the decoder does not recognise. It is recorded
+// in LastError; in strict mode or with HaltOnUnknown the CPU also halts,
+// otherwise the opcode is skipped.
+func (c *Chip8) unknownOpcode(opcode uint16) {
+	msg := fmt.Sprintf("unknown opcode 0x%04X at 0x%04X", opcode, c.PC-2)
+	if c.Strict || c.HaltOnUnknown {
+		c.fault(msg)
+		return
+	}
+	c.LastError = msg
+	c.cycleErr = errors.New(msg)
+}
+
+// writeMemory stores value at addr on behalf of a ROM instruction. Writes into a
+// protected range are refused and fault the CPU; it reports whether the write happened.
+// A write to a watched address pauses the CPU once the current instruction finishes.
+func (c *Chip8) writeMemory(addr uint16, value byte) bool {
+	if c.protected[addr] {
+		c.fault(fmt.Sprintf("write of 0x%02X to protected address 0x%04X at 0x%04X", value, addr, c.PC-2))
+		return false
+	}
+	c.Memory[addr] = value
+	if c.Watchpoints[addr] {
+		c.IsRunning = false
+		c.WatchpointHit = true
+		c.WatchpointAddr = addr
+	}
+	return true
+}
+
+// SetBreakpointSkip sets a breakpoint at addr that lets the first skip hits
+// through and halts from the next one on. The hit count for addr starts over.
+func (c *Chip8) SetBreakpointSkip(addr uint16, skip int) {
+	if skip < 0 {
+		skip = 0
+	}
+	if c.BreakpointSkips == nil {
+		c.BreakpointSkips = make(map[uint16]int)
+	}
+	c.Breakpoints[addr] = true
+	c.BreakpointSkips[addr] = skip
+	delete(c.breakpointHits, addr)
+}
+
+// countBreakpointHit records that the breakpoint at addr was reached and reports
+// whether it should halt the CPU.
+func (c *Chip8) countBreakpointHit(addr uint16) bool {
+	if c.breakpointHits == nil {
+		c.breakpointHits = make(map[uint16]int)
+	}
+	c.breakpointHits[addr]++
+	return c.breakpointHits[addr] > c.BreakpointSkips[addr]
+}
+
+// ProtectRange marks the inclusive address range [start, end] as read-only, so any
+// store into it by a ROM instruction faults instead of succeeding. Protection is
+// kept across Reset so a range can be set up once and survive ROM reloads.
+func (c *Chip8) ProtectRange(start, end uint16) {
+	if c.protected == nil {
+		c.protected = make(map[uint16]bool)
+	}
+	for addr := int(start); addr <= int(end); addr++ {
+		c.protected[uint16(addr)] = true
+	}
+}
+
+// UnprotectRange makes the inclusive address range [start, end] writable again.
+func (c *Chip8) UnprotectRange(start, end uint16) {
+	for addr := int(start); addr <= int(end); addr++ {
+		delete(c.protected, uint16(addr))
+	}
+}
+
+// wrapAddr wraps an address computed from I into memory, as the original
+// interpreter's address arithmetic did.
+func (c *Chip8) wrapAddr(addr int) uint16 {
+	return uint16(addr % len(c.Memory))
+}
+
+// checkRange reports whether an instruction may access count bytes from start.
+// Accesses running past the end of memory wrap around to address 0, unless
+// HaltOnOverflow or strict mode is set, in which case the CPU faults instead.
+func (c *Chip8) checkRange(op string, start uint16, count int) bool {
+	if int(start)+count <= len(c.Memory) || !(c.Strict || c.HaltOnOverflow) {
+		return true
+	}
+	c.fault(fmt.Sprintf("%s at 0x%04X runs past the end of memory (I=0x%04X, %d bytes)", op, c.PC-2, start, count))
+	return false
+}
+
+// fault halts the CPU and records the reason in LastError.
+func (c *Chip8) fault(msg string) {
+	c.LastError = msg
+	c.IsRunning = false
+	c.cycleErr = errors.New(msg)
+}
+
+// RunWithInput starts the CPU and executes up to cycles instructions, setting Keys
+// from inputFn before each one. inputFn receives the CycleCount of the instruction
+// about to run. It stops early if the CPU halts (breakpoint, fault, etc.).
+func (c *Chip8) RunWithInput(cycles int, inputFn func(cycle uint64) [16]bool) {
+	c.IsRunning = true
+	for i := 0; i < cycles && c.IsRunning; i++ {
+		c.Keys = inputFn(c.CycleCount)
+		c.EmulateCycle()
+	}
+}
+
+// StepWithOverride executes exactly one instruction with register reg temporarily
+// set to value, ignoring the run state and any breakpoint at PC. All other side
+// effects of the instruction are kept. Afterwards reg is restored to its original
+// value unless the instruction changed it; an instruction that happens to write
+// the override value itself is indistinguishable from one that did not write reg,
+// so reg is restored in that case too.
+func (c *Chip8) StepWithOverride(reg int, value byte) error {
+	if reg < 0 || reg >= len(c.Registers) {
+		return fmt.Errorf("register index %d out of range", reg)
+	}
+	original := c.Registers[reg]
+	c.Registers[reg] = value
+	c.Step()
+	if c.Registers[reg] == value {
+		c.Registers[reg] = original
+	}
+	return nil
+}
+
+// Step executes one instruction regardless of IsRunning and breakpoints, for
+// single-stepping in a debugger. The run state is left as it was unless the
+// instruction faulted.
+func (c *Chip8) Step() {
+	wasRunning := c.IsRunning
+	c.IsRunning = true
+	c.resumeFrom, c.resuming = c.PC, true
+	c.EmulateCycle()
+	if c.IsRunning {
+		c.IsRunning = wasRunning
+	}
+}
+
+// waitForKeyRelease runs FX0A under the WaitKeyOnRelease quirk: the first key
+// found down is remembered, and Vx is only written, and the instruction only
+// completes, once that key goes up. Until then the instruction repeats.
+func (c *Chip8) waitForKeyRelease(vx uint16) {
+	if c.KeyWaitHeld {
+		if !c.Keys[c.KeyWaitKey] {
+			c.Registers[vx] = c.KeyWaitKey
+			c.KeyWaitHeld = false
+			return
+		}
+	} else {
+		for i, pressed := range c.Keys {
+			if pressed {
+				c.KeyWaitHeld = true
+				c.KeyWaitKey = byte(i)
+				break
+			}
+		}
 	}
+	c.PC -= 2
+}
+
+// WaitingForVBlank reports whether a DRW under the DisplayWait quirk is holding
+// the CPU until the next timer tick.
+func (c *Chip8) WaitingForVBlank() bool {
+	return c.waitingForVBlank
 }
 
 // UpdateTimers decrements the delay and sound timers if they are greater than 0.
-func (c *Chip8) UpdateTimers() {
+// It reports whether the sound timer is still running afterwards, i.e. whether
+// the tone should keep playing. A timer tick is the vertical blank, so it also
+// releases a DRW waiting under the DisplayWait quirk.
+func (c *Chip8) UpdateTimers() bool {
+	c.FrameCount++
+	c.waitingForVBlank = false
 	if c.DelayTimer > 0 {
 		c.DelayTimer--
 	}
 	if c.SoundTimer > 0 {
+		c.soundActiveFrames++
 		c.SoundTimer--
 	}
+	return c.SoundTimer > 0
+}
+
+// DistinctOpcodes returns how many different kinds of instruction have executed
+// since the last reset (e.g. every LD Vx, byte counts once as 6XNN).
+func (c *Chip8) DistinctOpcodes() int {
+	return len(c.opcodesUsed)
+}
+
+// opcodeKind masks out an opcode's operands, leaving the bits that select the
+// instruction, so that all uses of the same instruction share one key.
+func opcodeKind(opcode uint16) uint16 {
+	switch opcode & 0xF000 {
+	case 0x0000:
+		switch {
+		case opcode&0xFFF0 == 0x00C0, opcode&0xFFF0 == 0x00D0:
+			return opcode & 0xFFF0
+		case opcode&0xFF00 == 0x0000 && opcode >= 0x00E0:
+			return opcode
+		default:
+			return 0x0000
+		}
+	case 0x5000, 0x8000, 0x9000:
+		return opcode & 0xF00F
+	case 0xE000, 0xF000:
+		return opcode & 0xF0FF
+	default:
+		return opcode & 0xF000
+	}
+}
+
+// SoundActiveFrames returns how many timer ticks since the last reset had the
+// sound timer running, i.e. how many 60Hz frames the ROM produced sound for.
+func (c *Chip8) SoundActiveFrames() uint64 {
+	return c.soundActiveFrames
+}
+
+// Width returns the width in pixels of the active display mode.
+func (c *Chip8) Width() int {
+	if c.HiRes {
+		return HiResWidth
+	}
+	return DisplayWidth
+}
+
+// Height returns the height in pixels of the active display mode.
+func (c *Chip8) Height() int {
+	if c.HiRes {
+		return HiResHeight
+	}
+	return DisplayHeight
+}
+
+// Frame returns the active framebuffer: Width()*Height() bytes, one per pixel,
+// row-major. It aliases Display.
+func (c *Chip8) Frame() []byte {
+	return c.Display[:c.Width()*c.Height()]
+}
+
+// scrollDistance converts a SUPER-CHIP scroll amount, given in hi-res pixels, to
+// pixels of the active mode. SUPER-CHIP's low-res mode is the hi-res screen with
+// doubled pixels, so it moves half as far (rounding down); the LowResScrollFull
+// quirk scrolls the full amount instead, as XO-CHIP does.
+func (c *Chip8) scrollDistance(hiResPixels int) int {
+	if c.HiRes || c.Quirks.LowResScrollFull {
+		return hiResPixels
+	}
+	return hiResPixels / 2
+}
+
+// scroll shifts the selected planes by dx columns and dy rows, clearing the
+// pixels scrolled in.
+func (c *Chip8) scroll(dx, dy int) {
+	w, h := c.Width(), c.Height()
+	for i, plane := range c.planes() {
+		if c.SelectedPlanes&(1<<i) == 0 {
+			continue
+		}
+		var shifted [HiResWidth * HiResHeight]byte
+		for y := 0; y < h; y++ {
+			sy := y - dy
+			if sy < 0 || sy >= h {
+				continue
+			}
+			for x := 0; x < w; x++ {
+				if sx := x - dx; sx >= 0 && sx < w {
+					shifted[y*w+x] = plane[sy*w+sx]
+				}
+			}
+		}
+		*plane = shifted
+	}
+	c.DrawFlag = true
+}
+
+// planes returns the bit planes in FN01 mask order.
+func (c *Chip8) planes() [2]*[HiResWidth * HiResHeight]byte {
+	return [2]*[HiResWidth * HiResHeight]byte{&c.Display, &c.Plane2}
+}
+
+// drawSprite XORs rows of sprite data from addr into plane at (x, y), wrapping
+// or clipping at the screen edges, and reports whether any lit pixel was
+// erased. Rows are one byte wide, or two bytes (16 pixels) when wide is set.
+func (c *Chip8) drawSprite(plane *[HiResWidth * HiResHeight]byte, x, y byte, addr, rows uint16, wide bool) bool {
+	width, height := uint16(c.Width()), uint16(c.Height())
+	// The starting coordinate always wraps; under ClipSprites the pixels
+	// that then run off the edge are dropped rather than wrapped.
+	startX, startY := uint16(x)%width, uint16(y)%height
+	rowBytes, rowPixels := uint16(1), uint16(8)
+	if wide {
+		rowBytes, rowPixels = 2, 16
+	}
+	collision := false
+	for yline := uint16(0); yline < rows; yline++ {
+		finalY := startY + yline
+		if finalY >= height {
+			if c.Quirks.ClipSprites {
+				break
+			}
+			finalY %= height
+		}
+		rowAddr := int(addr) + int(yline*rowBytes)
+		spriteRow := uint16(c.Memory[c.wrapAddr(rowAddr)]) << 8
+		if wide {
+			spriteRow |= uint16(c.Memory[c.wrapAddr(rowAddr+1)])
+		}
+		for xline := uint16(0); xline < rowPixels; xline++ {
+			if (spriteRow & (0x8000 >> xline)) != 0 {
+				finalX := startX + xline
+				if finalX >= width {
+					if c.Quirks.ClipSprites {
+						continue
+					}
+					finalX %= width
+				}
+				index := finalY*width + finalX
+
+				if c.DryRunDraw {
+					if plane[index] == 1 {
+						collision = true
+					}
+					continue
+				}
+				if c.ORDraw {
+					plane[index] = 1
+					continue
+				}
+				if plane[index] == 1 {
+					collision = true
+				}
+				plane[index] ^= 1
+			}
+		}
+	}
+	return collision
+}
+
+// Pixels returns a copy of the active frame combining both bit planes: each
+// byte holds bit 0 from Display and bit 1 from Plane2, so classic programs only
+// produce 0 and 1.
+func (c *Chip8) Pixels() []byte {
+	out := make([]byte, c.Width()*c.Height())
+	for i := range out {
+		out[i] = c.Display[i] | c.Plane2[i]<<1
+	}
+	return out
+}
+
+// setHiRes switches display mode and clears the screen, as the mode change
+// invalidates the pixel layout.
+func (c *Chip8) setHiRes(on bool) {
+	c.HiRes = on
+	c.Display = [HiResWidth * HiResHeight]byte{}
+	c.Plane2 = [HiResWidth * HiResHeight]byte{}
+	c.DrawFlag = true
+	c.ScreenCleared = true
+}
+
+// DisplayHash returns an FNV-1a hash of the display buffer, for cheaply telling
+// whether the picture changed between frames.
+func (c *Chip8) DisplayHash() uint64 {
+	h := fnv.New64a()
+	h.Write(c.Display[:])
+	h.Write(c.Plane2[:])
+	return h.Sum64()
 }
 
 // ClearDrawFlag resets the draw flag.
@@ -309,13 +961,20 @@ func (c *Chip8) ClearDrawFlag() {
 	c.DrawFlag = false
 }
 
+// DisassembleWithQuirks is Disassemble for a CPU running with quirks q. Under
+// the JumpQuirk, BXNN reads as JP VX, XNN, naming the register it really adds.
+func DisassembleWithQuirks(opcode uint16, q Quirks) string {
+	if q.JumpQuirk && opcode&0xF000 == 0xB000 {
+		f := decode(opcode)
+		return fmt.Sprintf("JP V%X, 0x%03X", f.x, f.nnn)
+	}
+	return Disassemble(opcode)
+}
+
 // Disassemble (keep as is, but remove the extra '}' that was causing the error)
 func Disassemble(opcode uint16) string {
-	vx := (opcode & 0x0F00) >> 8
-	vy := (opcode & 0x00F0) >> 4
-	nnn := opcode & 0x0FFF
-	nn := byte(opcode & 0x00FF)
-	n := byte(opcode & 0x000F)
+	f := decode(opcode)
+	vx, vy, nnn, nn, n := f.x, f.y, f.nnn, f.nn, f.n
 
 	switch opcode & 0xF000 {
 	// ... (all cases remain the same)
@@ -325,7 +984,18 @@ func Disassemble(opcode uint16) string {
 			return fmt.Sprintf("CLS") // Removed opcode prefix for cleaner look
 		case 0x00EE:
 			return fmt.Sprintf("RET")
+		case 0x00FB:
+			return "SCR"
+		case 0x00FC:
+			return "SCL"
+		case 0x00FE:
+			return "LOW"
+		case 0x00FF:
+			return "HIGH"
 		default:
+			if opcode&0xFFF0 == 0x00C0 {
+				return fmt.Sprintf("SCD %d", n)
+			}
 			return fmt.Sprintf("SYS 0x%03X", nnn)
 		}
 	case 0x1000:
@@ -337,6 +1007,9 @@ func Disassemble(opcode uint16) string {
 	case 0x4000:
 		return fmt.Sprintf("SNE V%X, 0x%02X", vx, nn)
 	case 0x5000:
+		if n != 0 {
+			return fmt.Sprintf("UNKNOWN %04X", opcode)
+		}
 		return fmt.Sprintf("SE V%X, V%X", vx, vy)
 	case 0x6000:
 		return fmt.Sprintf("LD V%X, 0x%02X", vx, nn)
@@ -366,6 +1039,9 @@ func Disassemble(opcode uint16) string {
 			return fmt.Sprintf("UNKNOWN 8xx%X", n)
 		}
 	case 0x9000:
+		if n != 0 {
+			return fmt.Sprintf("UNKNOWN %04X", opcode)
+		}
 		return fmt.Sprintf("SNE V%X, V%X", vx, vy)
 	case 0xA000:
 		return fmt.Sprintf("LD I, 0x%03X", nnn)
@@ -386,6 +1062,18 @@ func Disassemble(opcode uint16) string {
 		}
 	case 0xF000:
 		switch nn {
+		case 0x00:
+			if vx == 0 {
+				return "LD I, long"
+			}
+			return fmt.Sprintf("UNKNOWN Fx%02X", nn)
+		case 0x01:
+			return fmt.Sprintf("PLANE %d", vx)
+		case 0x02:
+			if vx == 0 {
+				return "AUDIO"
+			}
+			return fmt.Sprintf("UNKNOWN Fx%02X", nn)
 		case 0x07:
 			return fmt.Sprintf("LD V%X, DT", vx)
 		case 0x0A:
@@ -394,6 +1082,8 @@ func Disassemble(opcode uint16) string {
 			return fmt.Sprintf("LD DT, V%X", vx)
 		case 0x18:
 			return fmt.Sprintf("LD ST, V%X", vx)
+		case 0x3A:
+			return fmt.Sprintf("PITCH V%X", vx)
 		case 0x1E:
 			return fmt.Sprintf("ADD I, V%X", vx)
 		case 0x29:
@@ -422,7 +1112,7 @@ func (c *Chip8) GetState() map[string]interface{} {
 		addr := int(c.PC) + (i * 2)
 		if addr >= ProgramStart && addr < len(c.Memory)-1 {
 			opcode := uint16(c.Memory[addr])<<8 | uint16(c.Memory[addr+1])
-			line := fmt.Sprintf("0x%04X: %s", addr, Disassemble(opcode))
+			line := fmt.Sprintf("0x%04X: %s", addr, DisassembleWithQuirks(opcode, c.Quirks))
 			if addr == int(c.PC) {
 				line = "► " + line
 			}
@@ -440,16 +1130,28 @@ func (c *Chip8) GetState() map[string]interface{} {
 	for k, v := range c.Breakpoints {
 		breakpointsCopy[k] = v
 	}
+	var lastWatchpoint interface{} // nil until a watchpoint triggers
+	if c.WatchpointHit {
+		lastWatchpoint = c.WatchpointAddr
+	}
 
 	return map[string]interface{}{
-		"PC":          c.PC,
-		"I":           c.I,
-		"SP":          c.SP,
-		"DelayTimer":  c.DelayTimer,
-		"SoundTimer":  c.SoundTimer,
-		"Registers":   registersCopy,
-		"Stack":       stackCopy,
-		"Disassembly": disassembly,
-		"Breakpoints": breakpointsCopy, // *** FIX: Add breakpoints to state ***
+		"PC":             c.PC,
+		"I":              c.I,
+		"SP":             c.SP,
+		"StackHighWater": c.StackHighWater,
+		"DelayTimer":     c.DelayTimer,
+		"SoundTimer":     c.SoundTimer,
+		"Registers":      registersCopy,
+		"Stack":          stackCopy,
+		"Disassembly":    disassembly,
+		"Breakpoints":    breakpointsCopy, // *** FIX: Add breakpoints to state ***
+		"LastError":      c.LastError,
+		"HiRes":          c.HiRes,
+		"SelectedPlanes": c.SelectedPlanes,
+		"LastWatchpoint": lastWatchpoint,
+		"Halted":         c.Halted,
+		"CycleCount":     c.CycleCount,
+		"FrameCount":     c.FrameCount,
 	}
 }
@@ -1,6 +1,7 @@
 package chip8
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -217,3 +218,1488 @@ func TestOpcodeDXYN(t *testing.T) {
 		t.Errorf("Expected VF to be 1 after collision, got %d", c.Registers[0xF])
 	}
 }
+
+/*
+TestOpcode5XY1Strict checks that 5XY1 is treated as an unknown opcode in strict
+mode rather than being executed as SE Vx, Vy.
+*/
+func TestOpcode5XY1Strict(t *testing.T) {
+	c := New()
+	c.Strict = true
+	c.Registers[0x1] = 0x42
+	c.Registers[0x2] = 0x42
+	c.PC = ProgramStart
+	c.Memory[ProgramStart] = 0x51
+	c.Memory[ProgramStart+1] = 0x21
+	c.IsRunning = true
+
+	c.EmulateCycle()
+
+	if c.IsRunning {
+		t.Error("Expected CPU to halt on 5XY1 in strict mode")
+	}
+	if c.LastError == "" {
+		t.Error("Expected LastError to be set")
+	}
+	if c.PC != ProgramStart+2 {
+		t.Errorf("Expected PC to be 0x%X (no skip), got 0x%X", ProgramStart+2, c.PC)
+	}
+}
+
+/*
+TestOpcode9XY1Strict checks that 9XY1 is treated as an unknown opcode in strict mode.
+*/
+func TestOpcode9XY1Strict(t *testing.T) {
+	c := New()
+	c.Strict = true
+	c.Registers[0x1] = 0x01
+	c.Registers[0x2] = 0x02
+	c.PC = ProgramStart
+	c.Memory[ProgramStart] = 0x91
+	c.Memory[ProgramStart+1] = 0x21
+	c.IsRunning = true
+
+	c.EmulateCycle()
+
+	if c.IsRunning {
+		t.Error("Expected CPU to halt on 9XY1 in strict mode")
+	}
+	if c.PC != ProgramStart+2 {
+		t.Errorf("Expected PC to be 0x%X (no skip), got 0x%X", ProgramStart+2, c.PC)
+	}
+}
+
+/*
+TestOpcode5XY1Lenient checks that without strict mode 5XY1 is still an unknown
+opcode rather than a compare: the CPU records the error, keeps running and does
+not skip, matching what Disassemble shows.
+*/
+func TestOpcode5XY1Lenient(t *testing.T) {
+	c := New()
+	c.Registers[0x1] = 0x42
+	c.Registers[0x2] = 0x42
+	c.PC = ProgramStart
+	c.Memory[ProgramStart] = 0x51
+	c.Memory[ProgramStart+1] = 0x21
+	c.IsRunning = true
+
+	c.EmulateCycle()
+
+	if !c.IsRunning {
+		t.Error("Expected CPU to keep running outside strict mode")
+	}
+	if c.LastError == "" {
+		t.Error("Expected LastError to be set")
+	}
+	if c.PC != ProgramStart+2 {
+		t.Errorf("Expected PC to be 0x%X (no skip), got 0x%X", ProgramStart+2, c.PC)
+	}
+	if got := Disassemble(0x5121); got != "UNKNOWN 5121" {
+		t.Errorf("Expected Disassemble to show UNKNOWN 5121, got %q", got)
+	}
+}
+
+/*
+TestStackHighWater runs three nested CALLs followed by their RETs and checks that
+the high-water mark records the deepest nesting, and that Reset clears it.
+*/
+func TestStackHighWater(t *testing.T) {
+	c := New()
+	rom := []byte{
+		0x22, 0x04, // 0x200: CALL 0x204
+		0x12, 0x02, // 0x202: JP 0x202
+		0x22, 0x08, // 0x204: CALL 0x208
+		0x00, 0xEE, // 0x206: RET
+		0x22, 0x0C, // 0x208: CALL 0x20C
+		0x00, 0xEE, // 0x20A: RET
+		0x00, 0xEE, // 0x20C: RET
+	}
+	if err := c.LoadROM(rom); err != nil {
+		t.Fatalf("LoadROM failed: %v", err)
+	}
+	c.IsRunning = true
+
+	for i := 0; i < 6; i++ {
+		c.EmulateCycle()
+	}
+
+	if c.SP != 0 {
+		t.Errorf("Expected SP to be 0 after returning, got %d", c.SP)
+	}
+	if c.StackHighWater != 3 {
+		t.Errorf("Expected StackHighWater to be 3, got %d", c.StackHighWater)
+	}
+	if got := c.GetState()["StackHighWater"]; got != byte(3) {
+		t.Errorf("Expected GetState StackHighWater to be 3, got %v", got)
+	}
+
+	c.Reset()
+	if c.StackHighWater != 0 {
+		t.Errorf("Expected StackHighWater to be 0 after Reset, got %d", c.StackHighWater)
+	}
+}
+
+/*
+TestSelfJumpHalts checks that a JP to its own address sets Halted, that with
+StopOnHalt further cycles then do nothing, and that Reset clears the flag.
+*/
+func TestSelfJumpHalts(t *testing.T) {
+	c := New()
+	c.StopOnHalt = true
+	copy(c.Memory[ProgramStart:], []byte{0x60, 0x01, 0x12, 0x02}) // LD V0, 1 ; JP 0x202
+	c.IsRunning = true
+
+	c.EmulateCycle()
+	if c.Halted {
+		t.Fatal("Expected no halt before the self-jump")
+	}
+	c.EmulateCycle()
+	if !c.Halted || c.PC != ProgramStart+2 {
+		t.Fatalf("Expected a halt at 0x%X, got Halted=%v PC 0x%X", ProgramStart+2, c.Halted, c.PC)
+	}
+	c.EmulateCycle()
+	if c.CycleCount != 2 {
+		t.Errorf("Expected no cycles while halted, got %d in total", c.CycleCount)
+	}
+	if !c.IsRunning {
+		t.Error("Expected a halt to leave IsRunning set")
+	}
+
+	c.Reset()
+	if c.Halted {
+		t.Error("Expected Reset to clear Halted")
+	}
+
+	c.StopOnHalt = false
+	copy(c.Memory[ProgramStart:], []byte{0x12, 0x00}) // JP 0x200
+	c.IsRunning = true
+	c.EmulateCycle()
+	c.EmulateCycle()
+	if !c.Halted || c.CycleCount != 2 {
+		t.Errorf("Expected the halt to be detected while spinning, got Halted=%v after %d cycles", c.Halted, c.CycleCount)
+	}
+}
+
+/*
+TestStackOverflowHalts runs a ROM that calls itself forever and checks that the
+17th CALL halts the CPU with an error instead of panicking.
+*/
+func TestStackOverflowHalts(t *testing.T) {
+	c := New()
+	copy(c.Memory[ProgramStart:], []byte{0x22, 0x00}) // CALL 0x200
+	c.IsRunning = true
+
+	for i := 0; i < 17; i++ {
+		c.EmulateCycle()
+	}
+
+	if c.IsRunning {
+		t.Fatal("Expected the CPU to halt on stack overflow")
+	}
+	if int(c.SP) != len(c.Stack) {
+		t.Errorf("Expected SP to stay at %d, got %d", len(c.Stack), c.SP)
+	}
+	if got, _ := c.GetState()["LastError"].(string); !strings.Contains(got, "stack overflow") {
+		t.Errorf("Expected GetState LastError to report a stack overflow, got %q", got)
+	}
+}
+
+/*
+TestStackUnderflowHalts checks that RET with an empty stack halts the CPU with an
+error instead of wrapping SP.
+*/
+func TestStackUnderflowHalts(t *testing.T) {
+	c := New()
+	copy(c.Memory[ProgramStart:], []byte{0x00, 0xEE}) // RET
+	c.IsRunning = true
+
+	c.EmulateCycle()
+
+	if c.IsRunning {
+		t.Fatal("Expected the CPU to halt on stack underflow")
+	}
+	if c.SP != 0 {
+		t.Errorf("Expected SP to stay at 0, got %d", c.SP)
+	}
+	if !strings.Contains(c.LastError, "stack underflow") {
+		t.Errorf("Expected LastError to report a stack underflow, got %q", c.LastError)
+	}
+}
+
+/*
+TestReturnWithCorruptSPHalts checks that RET with a stack pointer past the end
+of the stack, as a corrupt save state could leave it, halts the CPU instead of
+indexing out of range.
+*/
+func TestReturnWithCorruptSPHalts(t *testing.T) {
+	c := New()
+	copy(c.Memory[ProgramStart:], []byte{0x00, 0xEE}) // RET
+	c.SP = 40
+	c.IsRunning = true
+
+	c.EmulateCycle()
+
+	if c.IsRunning {
+		t.Fatal("Expected the CPU to halt on an out-of-range stack pointer")
+	}
+	if !strings.Contains(c.LastError, "stack pointer 40") {
+		t.Errorf("Expected LastError to report the stack pointer, got %q", c.LastError)
+	}
+}
+
+/*
+TestOpcode7XNNOverflowLeavesVF checks that by default an overflowing ADD Vx, byte
+wraps the register and does not touch VF.
+*/
+func TestOpcode7XNNOverflowLeavesVF(t *testing.T) {
+	c := New()
+	c.Registers[0x3] = 0xF0
+	c.Registers[0xF] = 0x07
+	c.PC = ProgramStart
+	c.Memory[ProgramStart] = 0x73
+	c.Memory[ProgramStart+1] = 0x20
+	c.IsRunning = true
+
+	c.EmulateCycle()
+
+	if c.Registers[0x3] != 0x10 {
+		t.Errorf("Expected V[3] to be 0x10, got 0x%X", c.Registers[0x3])
+	}
+	if c.Registers[0xF] != 0x07 {
+		t.Errorf("Expected VF to be unchanged (0x07), got 0x%X", c.Registers[0xF])
+	}
+}
+
+/*
+TestOpcode7XNNAddByteSetsVF checks that with the AddByteSetsVF quirk an overflowing
+ADD Vx, byte sets VF to 1 and a non-overflowing one clears it.
+*/
+func TestOpcode7XNNAddByteSetsVF(t *testing.T) {
+	c := New()
+	c.Quirks.AddByteSetsVF = true
+	c.Registers[0x3] = 0xF0
+	c.PC = ProgramStart
+	c.Memory[ProgramStart] = 0x73
+	c.Memory[ProgramStart+1] = 0x20
+	c.Memory[ProgramStart+2] = 0x73
+	c.Memory[ProgramStart+3] = 0x01
+	c.IsRunning = true
+
+	c.EmulateCycle()
+
+	if c.Registers[0x3] != 0x10 {
+		t.Errorf("Expected V[3] to be 0x10, got 0x%X", c.Registers[0x3])
+	}
+	if c.Registers[0xF] != 1 {
+		t.Errorf("Expected VF to be 1 after overflow, got %d", c.Registers[0xF])
+	}
+
+	c.EmulateCycle()
+
+	if c.Registers[0xF] != 0 {
+		t.Errorf("Expected VF to be 0 without overflow, got %d", c.Registers[0xF])
+	}
+}
+
+/*
+TestSoundActiveFrames loads the sound timer with 5 via FX18 and checks that running
+more timer ticks than that counts exactly 5 sound-active frames.
+*/
+func TestSoundActiveFrames(t *testing.T) {
+	c := New()
+	c.Registers[0x2] = 5
+	c.PC = ProgramStart
+	c.Memory[ProgramStart] = 0xF2
+	c.Memory[ProgramStart+1] = 0x18
+	c.IsRunning = true
+
+	c.EmulateCycle()
+	for i := 0; i < 10; i++ {
+		c.UpdateTimers()
+	}
+
+	if got := c.SoundActiveFrames(); got != 5 {
+		t.Errorf("Expected 5 sound-active frames, got %d", got)
+	}
+
+	c.Reset()
+	if got := c.SoundActiveFrames(); got != 0 {
+		t.Errorf("Expected 0 sound-active frames after Reset, got %d", got)
+	}
+}
+
+/*
+TestShiftVFOrdering runs SHR and SHL with VF as the target register and checks the
+result under both settings of the ShiftFlagLast quirk.
+*/
+func TestShiftVFOrdering(t *testing.T) {
+	cases := []struct {
+		name      string
+		low       byte
+		flagLast  bool
+		initialVF byte
+		expectVF  byte
+	}{
+		// SHR VF: 0x03 -> flag 1. Flag first: VF=1 then 1>>1 = 0. Flag last: VF=1.
+		{"SHR flag first", 0x06, false, 0x03, 0x00},
+		{"SHR flag last", 0x06, true, 0x03, 0x01},
+		// SHL VF: 0x81 -> flag 1. Flag first: VF=1 then 1<<1 = 2. Flag last: VF=1.
+		{"SHL flag first", 0x0E, false, 0x81, 0x02},
+		{"SHL flag last", 0x0E, true, 0x81, 0x01},
+	}
+	for _, tc := range cases {
+		c := New()
+		c.Quirks.ShiftFlagLast = tc.flagLast
+		c.Registers[0xF] = tc.initialVF
+		c.PC = ProgramStart
+		c.Memory[ProgramStart] = 0x8F
+		c.Memory[ProgramStart+1] = 0xF0 | tc.low
+		c.IsRunning = true
+
+		c.EmulateCycle()
+
+		if c.Registers[0xF] != tc.expectVF {
+			t.Errorf("%s: expected VF to be 0x%02X, got 0x%02X", tc.name, tc.expectVF, c.Registers[0xF])
+		}
+	}
+}
+
+/*
+TestShiftFlagLastOrdinaryRegister checks that ShiftFlagLast does not change the
+result when the target register is not VF.
+*/
+func TestShiftFlagLastOrdinaryRegister(t *testing.T) {
+	for _, flagLast := range []bool{false, true} {
+		c := New()
+		c.Quirks.ShiftFlagLast = flagLast
+		c.Quirks.ShiftUsesVY = false
+		c.Registers[0x1] = 0x81
+		c.PC = ProgramStart
+		c.Memory[ProgramStart] = 0x81
+		c.Memory[ProgramStart+1] = 0x0E
+		c.IsRunning = true
+
+		c.EmulateCycle()
+
+		if c.Registers[0x1] != 0x02 || c.Registers[0xF] != 1 {
+			t.Errorf("flagLast=%v: expected V1=0x02 VF=1, got V1=0x%02X VF=%d", flagLast, c.Registers[0x1], c.Registers[0xF])
+		}
+	}
+}
+
+/*
+TestProtectRangeBlocksStores checks that FX55 and FX33 cannot write into a protected
+range, that the CPU faults with the offending address recorded, and that
+UnprotectRange restores normal behaviour.
+*/
+func TestProtectRangeBlocksStores(t *testing.T) {
+	c := New()
+	c.ProtectRange(0x300, 0x30F)
+	c.Registers[0x0] = 0xAA
+	c.I = 0x300
+	c.PC = ProgramStart
+	c.Memory[ProgramStart] = 0xF0
+	c.Memory[ProgramStart+1] = 0x55
+	c.IsRunning = true
+
+	c.EmulateCycle()
+
+	if c.Memory[0x300] != 0 {
+		t.Errorf("Expected protected byte to stay 0, got 0x%X", c.Memory[0x300])
+	}
+	if c.IsRunning {
+		t.Error("Expected CPU to halt on a protected write")
+	}
+	if !strings.Contains(c.LastError, "0x0300") {
+		t.Errorf("Expected LastError to mention 0x0300, got %q", c.LastError)
+	}
+
+	c.LastError = ""
+	c.Registers[0x1] = 123
+	c.I = 0x30E
+	c.PC = ProgramStart
+	c.Memory[ProgramStart] = 0xF1
+	c.Memory[ProgramStart+1] = 0x33
+	c.IsRunning = true
+
+	c.EmulateCycle()
+
+	if c.Memory[0x30E] != 0 || c.Memory[0x30F] != 0 {
+		t.Error("Expected BCD digits not to be written into the protected range")
+	}
+	if c.Memory[0x310] != 0 {
+		t.Error("Expected BCD store to stop at the protected range")
+	}
+
+	c.UnprotectRange(0x300, 0x30F)
+	c.LastError = ""
+	c.I = 0x300
+	c.PC = ProgramStart
+	c.Memory[ProgramStart] = 0xF0
+	c.Memory[ProgramStart+1] = 0x55
+	c.IsRunning = true
+
+	c.EmulateCycle()
+
+	if c.Memory[0x300] != 0xAA {
+		t.Errorf("Expected unprotected write to succeed, got 0x%X", c.Memory[0x300])
+	}
+	if c.LastError != "" {
+		t.Errorf("Expected no error after unprotecting, got %q", c.LastError)
+	}
+}
+
+/*
+TestIRangeWrapsAtEndOfMemory runs DRW, LD B, LD [I] and LD Vx, [I] with I near
+the top of memory and checks they wrap around to address 0 instead of running
+off the end.
+*/
+func TestIRangeWrapsAtEndOfMemory(t *testing.T) {
+	run := func(opcode uint16, i uint16) *Chip8 {
+		c := New()
+		c.LoadROM([]byte{byte(opcode >> 8), byte(opcode)})
+		c.IsRunning = true
+		c.I = i
+		for r := range c.Registers {
+			c.Registers[r] = byte(r + 1)
+		}
+		c.Registers[0x0], c.Registers[0x1] = 0, 0
+		c.Memory[0xFFF] = 0x80
+		c.Memory[0x000] = 0x80
+		c.EmulateCycle()
+		if c.LastError != "" {
+			t.Errorf("Opcode 0x%04X: expected no error, got %q", opcode, c.LastError)
+		}
+		return c
+	}
+
+	c := run(0xD012, 0xFFF) // DRW V0, V1, 2
+	if c.Display[0] != 1 || c.Display[DisplayWidth] != 1 {
+		t.Errorf("Expected DRW to read its second row from address 0")
+	}
+	c = run(0xF233, 0xFFE) // LD B, V2 (V2 = 3)
+	if c.Memory[0xFFE] != 0 || c.Memory[0xFFF] != 0 || c.Memory[0x000] != 3 {
+		t.Errorf("Expected LD B to wrap its last digit to address 0, got %v %v", c.Memory[0xFFE:], c.Memory[:1])
+	}
+	c = run(0xF355, 0xFFE) // LD [I], V3
+	if c.Memory[0xFFF] != 0 || c.Memory[0x000] != 3 || c.Memory[0x001] != 4 {
+		t.Errorf("Expected LD [I] to wrap to address 0, got %v %v", c.Memory[0xFFE:], c.Memory[:2])
+	}
+	c = run(0xF165, 0xFFF) // LD V1, [I]
+	if c.Registers[0x0] != 0x80 || c.Registers[0x1] != 0x80 {
+		t.Errorf("Expected LD V1, [I] to read 0xFFF then 0x000, got %v", c.Registers[:2])
+	}
+}
+
+/*
+TestPCWrapsAtEndOfMemory runs a ROM that jumps to the last instruction in memory.
+Executing it leaves PC one past the end, from where execution wraps to address 0,
+or, in strict mode or under HaltOnOverflow, the CPU halts with an error.
+*/
+func TestPCWrapsAtEndOfMemory(t *testing.T) {
+	for _, halt := range []bool{false, true} {
+		c := New()
+		c.HaltOnOverflow = halt
+		copy(c.Memory[ProgramStart:], []byte{0x1F, 0xFE}) // JP 0xFFE
+		copy(c.Memory[0xFFE:], []byte{0x60, 0x07})        // LD V0, 7
+		c.IsRunning = true
+
+		for i := 0; i < 3; i++ {
+			c.EmulateCycle()
+		}
+
+		if c.Registers[0x0] != 7 {
+			t.Errorf("halt=%v: expected the last instruction in memory to run, got V0 %d", halt, c.Registers[0x0])
+		}
+		if halt {
+			if c.IsRunning || !strings.Contains(c.LastError, "past the end of memory") {
+				t.Errorf("halt=%v: expected a halt past the end of memory, got running %v and error %q", halt, c.IsRunning, c.LastError)
+			}
+			continue
+		}
+		if !c.IsRunning || c.PC != 0x002 {
+			t.Errorf("halt=%v: expected execution to wrap to 0x000 and continue, got running %v and PC 0x%04X", halt, c.IsRunning, c.PC)
+		}
+	}
+}
+
+/*
+TestIRangeOverflowHalts checks that with HaltOnOverflow the same accesses fault
+with a LastError instead of wrapping, leaving memory and the display untouched.
+*/
+func TestIRangeOverflowHalts(t *testing.T) {
+	for _, opcode := range []uint16{0xD012, 0xF233, 0xF355, 0xF165} {
+		c := New()
+		c.HaltOnOverflow = true
+		c.LoadROM([]byte{byte(opcode >> 8), byte(opcode)})
+		c.IsRunning = true
+		c.I = 0xFFF
+		c.Registers[0x2], c.Registers[0x3] = 0xFF, 0xFF
+		c.Memory[0xFFF] = 0xFF
+		res := c.EmulateCycle()
+		if !strings.Contains(c.LastError, "past the end of memory") || res.Err == nil {
+			t.Errorf("Opcode 0x%04X: expected an overflow fault, got %q", opcode, c.LastError)
+		}
+		if c.IsRunning {
+			t.Errorf("Opcode 0x%04X: expected the CPU to halt", opcode)
+		}
+		if c.Memory[0x000] != 0 || c.Display[0] != 0 {
+			t.Errorf("Opcode 0x%04X: expected memory and display to be untouched", opcode)
+		}
+	}
+}
+
+/*
+TestEmulateCycleResult checks what EmulateCycle reports for a draw, a plain
+instruction, a breakpoint, an unknown opcode that halts and a self-jump, and
+that DrawFlag still accumulates across cycles for callers that poll it.
+*/
+func TestEmulateCycleResult(t *testing.T) {
+	c := New()
+	c.HaltOnUnknown = true
+	c.StopOnHalt = true
+	rom := []byte{
+		0xD0, 0x15, // 0x200: DRW V0, V1, 5
+		0x60, 0x01, // 0x202: LD V0, 1
+		0x12, 0x06, // 0x204: JP 0x206
+		0x12, 0x06, // 0x206: JP 0x206
+		0xFF, 0xFF, // 0x208: unknown
+	}
+	if err := c.LoadROM(rom); err != nil {
+		t.Fatalf("LoadROM failed: %v", err)
+	}
+	c.IsRunning = true
+
+	res := c.EmulateCycle()
+	if !res.Drew || res.Halted || res.Err != nil || res.Opcode != 0xD015 {
+		t.Errorf("Expected a draw of 0xD015, got %+v", res)
+	}
+	res = c.EmulateCycle()
+	if res.Drew || res.Halted || res.Opcode != 0x6001 {
+		t.Errorf("Expected LD V0, 1 without a draw, got %+v", res)
+	}
+	if !c.DrawFlag {
+		t.Error("Expected DrawFlag to stay set until cleared")
+	}
+
+	c.Breakpoints[0x204] = true
+	res = c.EmulateCycle()
+	if !res.Halted || res.Opcode != 0 {
+		t.Errorf("Expected the breakpoint to halt without running an instruction, got %+v", res)
+	}
+	delete(c.Breakpoints, 0x204)
+	c.IsRunning = true
+	c.EmulateCycle()
+	res = c.EmulateCycle()
+	if !res.Halted || res.Err != nil || res.Opcode != 0x1206 {
+		t.Errorf("Expected the self-jump to report a halt, got %+v", res)
+	}
+
+	c.Halted = false
+	c.PC = 0x208
+	res = c.EmulateCycle()
+	if !res.Halted || res.Err == nil || res.Err.Error() != c.LastError || res.Opcode != 0xFFFF {
+		t.Errorf("Expected the unknown opcode to report an error and halt, got %+v", res)
+	}
+}
+
+/*
+TestWaitKeyOnRelease checks that under the WaitKeyOnRelease quirk FX0A keeps
+waiting while a key is held and only stores it and moves on once it is released,
+and that a second key pressed meanwhile does not replace the first.
+*/
+func TestWaitKeyOnRelease(t *testing.T) {
+	c := New()
+	c.Quirks.WaitKeyOnRelease = true
+	rom := []byte{
+		0xF3, 0x0A, // 0x200: LD V3, K
+		0x12, 0x02, // 0x202: JP 0x202
+	}
+	if err := c.LoadROM(rom); err != nil {
+		t.Fatalf("LoadROM failed: %v", err)
+	}
+	c.IsRunning = true
+
+	c.Keys[0x7] = true
+	c.EmulateCycle()
+	c.Keys[0x2] = true
+	c.EmulateCycle()
+	if c.PC != ProgramStart {
+		t.Fatalf("Expected PC to wait at 0x%X while the key is held, got 0x%X", ProgramStart, c.PC)
+	}
+	if !c.KeyWaitHeld || c.KeyWaitKey != 0x7 {
+		t.Errorf("Expected key 7 to be pending release, got held=%v key=%d", c.KeyWaitHeld, c.KeyWaitKey)
+	}
+	if c.Registers[0x3] != 0 {
+		t.Errorf("Expected V3 to stay 0 until the release, got %d", c.Registers[0x3])
+	}
+
+	c.Keys[0x7] = false
+	c.EmulateCycle()
+	if c.Registers[0x3] != 0x7 {
+		t.Errorf("Expected V3 to be 7 after the release, got %d", c.Registers[0x3])
+	}
+	if c.PC != ProgramStart+2 {
+		t.Errorf("Expected PC to move on to 0x%X, got 0x%X", ProgramStart+2, c.PC)
+	}
+	if c.KeyWaitHeld {
+		t.Errorf("Expected no key to be pending after the release")
+	}
+}
+
+/*
+TestRunWithInput runs a ROM that waits for a key with FX0A and then stores it, and
+checks it blocks until the scripted input presses key 7 at cycle 5.
+*/
+func TestRunWithInput(t *testing.T) {
+	c := New()
+	rom := []byte{
+		0xF0, 0x0A, // 0x200: LD V0, K
+		0x12, 0x02, // 0x202: JP 0x202
+	}
+	if err := c.LoadROM(rom); err != nil {
+		t.Fatalf("LoadROM failed: %v", err)
+	}
+
+	c.RunWithInput(5, func(cycle uint64) [16]bool {
+		return [16]bool{}
+	})
+	if c.PC != ProgramStart {
+		t.Fatalf("Expected PC to wait at 0x%X, got 0x%X", ProgramStart, c.PC)
+	}
+
+	c.RunWithInput(3, func(cycle uint64) [16]bool {
+		var keys [16]bool
+		if cycle >= 5 {
+			keys[0x7] = true
+		}
+		return keys
+	})
+	if c.Registers[0x0] != 0x7 {
+		t.Errorf("Expected V0 to be 7, got %d", c.Registers[0x0])
+	}
+	if c.PC != ProgramStart+2 {
+		t.Errorf("Expected PC to be at the halt loop 0x%X, got 0x%X", ProgramStart+2, c.PC)
+	}
+}
+
+/*
+TestOpcode0NNN checks that SYS addr is skipped as a no-op by default and faults in
+strict mode.
+*/
+func TestOpcode0NNN(t *testing.T) {
+	for _, strict := range []bool{false, true} {
+		c := New()
+		c.Strict = strict
+		c.PC = ProgramStart
+		c.Memory[ProgramStart] = 0x03
+		c.Memory[ProgramStart+1] = 0x45
+		c.IsRunning = true
+
+		c.EmulateCycle()
+
+		if c.PC != ProgramStart+2 {
+			t.Errorf("strict=%v: expected PC to be 0x%X, got 0x%X", strict, ProgramStart+2, c.PC)
+		}
+		if c.IsRunning == strict {
+			t.Errorf("strict=%v: expected IsRunning to be %v", strict, !strict)
+		}
+		if strict && c.LastError == "" {
+			t.Error("Expected LastError to be set in strict mode")
+		}
+		if !strict && c.LastError != "" {
+			t.Errorf("Expected no error outside strict mode, got %q", c.LastError)
+		}
+	}
+}
+
+/*
+TestDistinctOpcodes checks that repeated uses of the same instruction with
+different operands count once, and that Reset clears the tally.
+*/
+func TestDistinctOpcodes(t *testing.T) {
+	c := New()
+	rom := []byte{
+		0x60, 0x01, // LD V0, 0x01
+		0x61, 0x02, // LD V1, 0x02
+		0x80, 0x14, // ADD V0, V1
+		0x80, 0x15, // SUB V0, V1
+		0x12, 0x08, // JP 0x208
+	}
+	if err := c.LoadROM(rom); err != nil {
+		t.Fatalf("LoadROM failed: %v", err)
+	}
+	c.IsRunning = true
+	for i := 0; i < 7; i++ {
+		c.EmulateCycle()
+	}
+
+	if got := c.DistinctOpcodes(); got != 4 {
+		t.Errorf("Expected 4 distinct opcodes (6XNN, 8XY4, 8XY5, 1NNN), got %d", got)
+	}
+	c.Reset()
+	if got := c.DistinctOpcodes(); got != 0 {
+		t.Errorf("Expected 0 distinct opcodes after Reset, got %d", got)
+	}
+}
+
+/*
+TestOpcodeEXNNUnknown checks that an EXNN opcode with an unrecognised low byte
+faults in strict mode and is otherwise skipped without side effects.
+*/
+func TestOpcodeEXNNUnknown(t *testing.T) {
+	for _, strict := range []bool{false, true} {
+		c := New()
+		c.Strict = strict
+		c.Registers[0x2] = 0x3
+		c.Keys[0x3] = true
+		c.PC = ProgramStart
+		c.Memory[ProgramStart] = 0xE2
+		c.Memory[ProgramStart+1] = 0x42
+		c.IsRunning = true
+
+		c.EmulateCycle()
+
+		if c.PC != ProgramStart+2 {
+			t.Errorf("strict=%v: expected PC to be 0x%X, got 0x%X", strict, ProgramStart+2, c.PC)
+		}
+		if strict && (c.IsRunning || c.LastError == "") {
+			t.Error("Expected CPU to fault on unknown EXNN in strict mode")
+		}
+		if !strict && !c.IsRunning {
+			t.Error("Expected CPU to keep running outside strict mode")
+		}
+	}
+}
+
+/*
+TestHaltOnUnknown checks that a garbage opcode is recorded in LastError, and that
+it halts the CPU only when HaltOnUnknown is set.
+*/
+func TestHaltOnUnknown(t *testing.T) {
+	for _, halt := range []bool{false, true} {
+		c := New()
+		c.HaltOnUnknown = halt
+		copy(c.Memory[ProgramStart:], []byte{0xF1, 0xFF})
+		c.IsRunning = true
+
+		c.EmulateCycle()
+
+		if c.IsRunning == halt {
+			t.Errorf("halt=%v: expected IsRunning=%v", halt, !halt)
+		}
+		if !strings.Contains(c.LastError, "0xF1FF") || !strings.Contains(c.LastError, "0x0200") {
+			t.Errorf("halt=%v: expected LastError to name the opcode and address, got %q", halt, c.LastError)
+		}
+	}
+}
+
+/*
+TestResetFillPattern checks that Reset fills memory outside the font and ROM with
+the configured pattern, and that the font and ROM are still loaded over it.
+*/
+func TestResetFillPattern(t *testing.T) {
+	c := New()
+	c.ResetFillPattern = []byte{0xDE, 0xAD, 0xBE, 0xEF}
+	c.Reset()
+	rom := []byte{0x12, 0x00}
+	if err := c.LoadROM(rom); err != nil {
+		t.Fatalf("LoadROM failed: %v", err)
+	}
+
+	if c.Memory[FontSetStart] != FontSet[0] {
+		t.Errorf("Expected font byte 0x%02X, got 0x%02X", FontSet[0], c.Memory[FontSetStart])
+	}
+	if c.Memory[ProgramStart] != rom[0] || c.Memory[ProgramStart+1] != rom[1] {
+		t.Error("Expected ROM bytes to overwrite the fill pattern")
+	}
+	for _, addr := range []int{0x000, 0x1FF, ProgramStart + len(rom), 0xFFF} {
+		want := c.ResetFillPattern[addr%len(c.ResetFillPattern)]
+		if c.Memory[addr] != want {
+			t.Errorf("Memory[0x%03X]: expected 0x%02X, got 0x%02X", addr, want, c.Memory[addr])
+		}
+	}
+}
+
+/*
+TestDrawXORvsOR draws the same sprite twice at one spot and compares normal XOR
+drawing with the diagnostic OR mode.
+*/
+func TestDrawXORvsOR(t *testing.T) {
+	for _, or := range []bool{false, true} {
+		c := New()
+		c.ORDraw = or
+		c.I = 0x300
+		c.Memory[0x300] = 0xF0
+		// DRW V0, V0, 1 twice
+		copy(c.Memory[ProgramStart:], []byte{0xD0, 0x01, 0xD0, 0x01})
+		c.IsRunning = true
+
+		c.EmulateCycle()
+		c.EmulateCycle()
+
+		if or {
+			if c.Registers[0xF] != 0 {
+				t.Errorf("OR mode: expected VF 0, got %d", c.Registers[0xF])
+			}
+			if c.Display[0] != 1 || c.Display[3] != 1 {
+				t.Error("OR mode: expected pixels to stay lit after overdraw")
+			}
+		} else {
+			if c.Registers[0xF] != 1 {
+				t.Errorf("XOR mode: expected VF 1 on collision, got %d", c.Registers[0xF])
+			}
+			if c.Display[0] != 0 || c.Display[3] != 0 {
+				t.Error("XOR mode: expected overdraw to erase the sprite")
+			}
+		}
+	}
+}
+
+/*
+TestWatchpointPausesOnWrite checks that FX55 writing to a watched address pauses
+the CPU after the instruction completes and that GetState reports the address.
+*/
+func TestWatchpointPausesOnWrite(t *testing.T) {
+	c := New()
+	c.Watchpoints = map[uint16]bool{0x301: true}
+	c.I = 0x300
+	c.Registers[0x0] = 0xAA
+	c.Registers[0x1] = 0xBB
+	c.Registers[0x2] = 0xCC
+	// LD [I], V2; LD V3, 0x01
+	copy(c.Memory[ProgramStart:], []byte{0xF2, 0x55, 0x63, 0x01})
+	c.IsRunning = true
+
+	if c.GetState()["LastWatchpoint"] != nil {
+		t.Error("Expected no watchpoint reported before one triggers")
+	}
+	c.EmulateCycle()
+	if c.IsRunning {
+		t.Fatal("Expected the write to a watched address to pause the CPU")
+	}
+	if c.Memory[0x302] != 0xCC {
+		t.Error("Expected the store to complete before pausing")
+	}
+	if got := c.GetState()["LastWatchpoint"]; got != uint16(0x301) {
+		t.Errorf("Expected LastWatchpoint 0x301, got %v", got)
+	}
+	c.EmulateCycle()
+	if c.Registers[0x3] != 0 {
+		t.Error("Expected no further instructions to run while paused")
+	}
+}
+
+/*
+TestDryRunDrawCollision checks that dry-run drawing sets VF where a sprite would
+overlap lit pixels but leaves the display buffer unchanged.
+*/
+func TestDryRunDrawCollision(t *testing.T) {
+	c := New()
+	c.I = 0x300
+	c.Memory[0x300] = 0xF0
+	c.Display[2] = 1
+	c.DryRunDraw = true
+	// DRW V0, V0, 1 over the lit pixel, then DRW V1, V0, 1 clear of it
+	c.Registers[0x1] = 10
+	copy(c.Memory[ProgramStart:], []byte{0xD0, 0x01, 0xD1, 0x01})
+	c.IsRunning = true
+
+	before := c.Display
+	c.EmulateCycle()
+	if c.Registers[0xF] != 1 {
+		t.Errorf("Expected VF 1 for an overlapping dry-run draw, got %d", c.Registers[0xF])
+	}
+	c.EmulateCycle()
+	if c.Registers[0xF] != 0 {
+		t.Errorf("Expected VF 0 for a clear dry-run draw, got %d", c.Registers[0xF])
+	}
+	if c.Display != before {
+		t.Error("Expected dry-run drawing to leave the display unchanged")
+	}
+}
+
+/*
+TestBreakpointSkipCount runs a loop over a breakpoint with skip=3 and checks that
+the CPU halts only on the fourth time the address is reached, and that resuming
+executes the instruction instead of halting again straight away.
+*/
+func TestBreakpointSkipCount(t *testing.T) {
+	c := New()
+	// 0x200: ADD V0, 1 ; 0x202: JP 0x200
+	copy(c.Memory[ProgramStart:], []byte{0x70, 0x01, 0x12, 0x00})
+	c.SetBreakpointSkip(ProgramStart, 3)
+	c.IsRunning = true
+
+	for i := 0; i < 20 && c.IsRunning; i++ {
+		c.EmulateCycle()
+	}
+	if c.IsRunning {
+		t.Fatal("Expected the breakpoint to halt the CPU")
+	}
+	if c.Registers[0] != 3 {
+		t.Errorf("Expected to halt on the fourth hit with V0 = 3, got V0 = %d", c.Registers[0])
+	}
+
+	c.IsRunning = true
+	c.EmulateCycle()
+	if !c.IsRunning || c.Registers[0] != 4 {
+		t.Errorf("Expected resume to execute the instruction, got V0 = %d running=%v", c.Registers[0], c.IsRunning)
+	}
+
+	c.Reset()
+	if len(c.BreakpointSkips) != 0 || len(c.breakpointHits) != 0 {
+		t.Error("Expected Reset to clear breakpoint skip counts")
+	}
+}
+
+/*
+TestStepWithOverride checks that an override changes the outcome of the stepped
+instruction, that an unwritten register reverts, and that a written one keeps
+the instruction's result.
+*/
+func TestStepWithOverride(t *testing.T) {
+	// SE V1, 0x05 skips only when V1 is 5; V1 is normally 0.
+	c := New()
+	copy(c.Memory[ProgramStart:], []byte{0x31, 0x05})
+	if err := c.StepWithOverride(1, 0x05); err != nil {
+		t.Fatalf("StepWithOverride failed: %v", err)
+	}
+	if c.PC != ProgramStart+4 {
+		t.Errorf("Expected the override to take the skip to 0x%X, got 0x%X", ProgramStart+4, c.PC)
+	}
+	if c.Registers[1] != 0 {
+		t.Errorf("Expected V1 to revert to 0, got %d", c.Registers[1])
+	}
+	if c.IsRunning {
+		t.Error("Expected the CPU to stay stopped after stepping")
+	}
+
+	// ADD V2, 0x03 writes V2, so the result sticks.
+	c = New()
+	copy(c.Memory[ProgramStart:], []byte{0x72, 0x03})
+	c.StepWithOverride(2, 0x10)
+	if c.Registers[2] != 0x13 {
+		t.Errorf("Expected V2 to keep the result 0x13, got 0x%02X", c.Registers[2])
+	}
+
+	if err := c.StepWithOverride(16, 0); err == nil {
+		t.Error("Expected an error for an out of range register")
+	}
+}
+
+/*
+TestQuirkShiftUsesVY checks that 8XY6 shifts VY into VX with the quirk on and
+shifts VX in place with it off.
+*/
+func TestQuirkShiftUsesVY(t *testing.T) {
+	for _, usesVY := range []bool{false, true} {
+		c := New()
+		c.Quirks.ShiftUsesVY = usesVY
+		c.Registers[0x1] = 0x10
+		c.Registers[0x2] = 0x05
+		copy(c.Memory[ProgramStart:], []byte{0x81, 0x26}) // SHR V1, V2
+		c.IsRunning = true
+
+		c.EmulateCycle()
+
+		wantV1, wantVF := byte(0x08), byte(0)
+		if usesVY {
+			wantV1, wantVF = 0x02, 1
+		}
+		if c.Registers[0x1] != wantV1 || c.Registers[0xF] != wantVF {
+			t.Errorf("usesVY=%v: expected V1=0x%02X VF=%d, got V1=0x%02X VF=%d", usesVY, wantV1, wantVF, c.Registers[0x1], c.Registers[0xF])
+		}
+	}
+}
+
+/*
+TestQuirkIncrementIOnStore checks whether FX55 and FX65 advance I.
+*/
+func TestQuirkIncrementIOnStore(t *testing.T) {
+	for _, increment := range []bool{false, true} {
+		c := New()
+		c.Quirks.IncrementIOnStore = increment
+		c.I = 0x300
+		copy(c.Memory[ProgramStart:], []byte{0xF2, 0x55, 0xF2, 0x65}) // LD [I], V2; LD V2, [I]
+		c.IsRunning = true
+
+		c.EmulateCycle()
+		c.EmulateCycle()
+
+		want := uint16(0x300)
+		if increment {
+			want = 0x306
+		}
+		if c.I != want {
+			t.Errorf("increment=%v: expected I=0x%03X, got 0x%03X", increment, want, c.I)
+		}
+	}
+}
+
+/*
+TestQuirkVFResetOnLogic checks that OR, AND and XOR clear VF only with the quirk on.
+*/
+func TestQuirkVFResetOnLogic(t *testing.T) {
+	for _, low := range []byte{0x1, 0x2, 0x3} {
+		for _, reset := range []bool{false, true} {
+			c := New()
+			c.Quirks.VFResetOnLogic = reset
+			c.Registers[0xF] = 0x7
+			copy(c.Memory[ProgramStart:], []byte{0x81, 0x20 | low})
+			c.IsRunning = true
+
+			c.EmulateCycle()
+
+			want := byte(0x7)
+			if reset {
+				want = 0
+			}
+			if c.Registers[0xF] != want {
+				t.Errorf("8XY%X reset=%v: expected VF=%d, got %d", low, reset, want, c.Registers[0xF])
+			}
+		}
+	}
+}
+
+/*
+TestQuirkJump checks that B2F0 jumps to 0x2F0 plus V0 without the quirk, and to
+0x2F0 plus V2 with it.
+*/
+func TestQuirkJump(t *testing.T) {
+	for _, quirk := range []bool{false, true} {
+		c := New()
+		c.Quirks.JumpQuirk = quirk
+		c.Registers[0x0] = 0x04
+		c.Registers[0x2] = 0x10
+		copy(c.Memory[ProgramStart:], []byte{0xB2, 0xF0})
+		c.IsRunning = true
+
+		c.EmulateCycle()
+
+		want := uint16(0x2F4)
+		if quirk {
+			want = 0x300
+		}
+		if c.PC != want {
+			t.Errorf("JumpQuirk=%v: expected PC 0x%03X, got 0x%03X", quirk, want, c.PC)
+		}
+	}
+}
+
+/*
+TestQuirkDisplayWait checks that with the quirk on, DXYN stalls the CPU until the
+next timer tick, and that without it the next instruction runs straight away.
+*/
+func TestQuirkDisplayWait(t *testing.T) {
+	for _, wait := range []bool{false, true} {
+		c := New()
+		c.Quirks.DisplayWait = wait
+		// DRW V0, V0, 1; LD V1, 0x05
+		copy(c.Memory[ProgramStart:], []byte{0xD0, 0x01, 0x61, 0x05})
+		c.IsRunning = true
+
+		c.EmulateCycle()
+		c.EmulateCycle()
+
+		if wait {
+			if c.PC != ProgramStart+2 || c.Registers[0x1] != 0 {
+				t.Errorf("wait=true: expected the CPU to stall after DRW, PC=0x%X V1=%d", c.PC, c.Registers[0x1])
+			}
+			c.UpdateTimers()
+			c.EmulateCycle()
+		}
+		if c.Registers[0x1] != 0x05 {
+			t.Errorf("wait=%v: expected LD after DRW to run, V1=%d", wait, c.Registers[0x1])
+		}
+	}
+}
+
+/*
+TestQuirkClipSpritesWrapsStart draws an 8-pixel-wide row at X=63 and X=127 in
+low-res mode. With clipping on, only the first column is drawn, at column 63 in
+both cases since the start coordinate wraps; with it off, the rest of the row
+wraps round to the left edge.
+*/
+func TestQuirkClipSpritesWrapsStart(t *testing.T) {
+	for _, clip := range []bool{false, true} {
+		for _, x := range []byte{63, 127} {
+			c := New()
+			c.Quirks.ClipSprites = clip
+			c.I = 0x300
+			c.Memory[0x300] = 0xFF
+			c.Registers[0x0] = x
+			// DRW V0, V1, 1
+			copy(c.Memory[ProgramStart:], []byte{0xD0, 0x11})
+			c.IsRunning = true
+
+			c.EmulateCycle()
+
+			if c.Display[63] != 1 {
+				t.Errorf("clip=%v x=%d: expected the first column drawn at 63", clip, x)
+			}
+			for col := 0; col < 7; col++ {
+				want := byte(1)
+				if clip {
+					want = 0
+				}
+				if c.Display[col] != want {
+					t.Errorf("clip=%v x=%d: expected column %d to be %d, got %d", clip, x, col, want, c.Display[col])
+				}
+			}
+		}
+	}
+}
+
+/*
+TestQuirkClipSpritesBottomEdge draws a 4-row sprite at Y=30 in low-res mode. With
+clipping on, rows 30 and 31 are drawn and the rest are dropped; with it off, the
+last two rows wrap round to the top of the screen.
+*/
+func TestQuirkClipSpritesBottomEdge(t *testing.T) {
+	for _, clip := range []bool{false, true} {
+		c := New()
+		c.Quirks.ClipSprites = clip
+		c.I = 0x300
+		copy(c.Memory[0x300:], []byte{0x80, 0x80, 0x80, 0x80})
+		c.Registers[0x1] = 30
+		// DRW V0, V1, 4
+		copy(c.Memory[ProgramStart:], []byte{0xD0, 0x14})
+		c.IsRunning = true
+
+		c.EmulateCycle()
+
+		for _, row := range []int{30, 31} {
+			if c.Display[row*DisplayWidth] != 1 {
+				t.Errorf("clip=%v: expected row %d to be drawn", clip, row)
+			}
+		}
+		want := byte(1)
+		if clip {
+			want = 0
+		}
+		for _, row := range []int{0, 1} {
+			if c.Display[row*DisplayWidth] != want {
+				t.Errorf("clip=%v: expected row %d to be %d, got %d", clip, row, want, c.Display[row*DisplayWidth])
+			}
+		}
+	}
+}
+
+/*
+TestDefaultQuirksMatchCOSMAC checks that a new CPU starts with the COSMAC VIP quirks.
+*/
+func TestDefaultQuirksMatchCOSMAC(t *testing.T) {
+	q := New().Quirks
+	if !q.ShiftUsesVY || !q.IncrementIOnStore || !q.VFResetOnLogic || !q.ClipSprites || q.AddByteSetsVF || q.ShiftFlagLast {
+		t.Errorf("Expected COSMAC VIP defaults, got %+v", q)
+	}
+}
+
+/*
+TestHiResMode checks that 00FF switches to 128x64, that DXYN then draws beyond
+the low-res bounds, and that 00FE and Reset return to 64x32.
+*/
+func TestHiResMode(t *testing.T) {
+	c := New()
+	c.I = 0x300
+	c.Memory[0x300] = 0x80
+	c.Registers[0] = 100 // X
+	c.Registers[1] = 40  // Y
+	// HIGH; DRW V0, V1, 1; LOW
+	copy(c.Memory[ProgramStart:], []byte{0x00, 0xFF, 0xD0, 0x11, 0x00, 0xFE})
+	c.IsRunning = true
+
+	c.EmulateCycle()
+	if !c.HiRes || c.Width() != HiResWidth || c.Height() != HiResHeight {
+		t.Fatalf("Expected 128x64 after 00FF, got %dx%d", c.Width(), c.Height())
+	}
+	c.EmulateCycle()
+	if c.Display[40*HiResWidth+100] != 1 {
+		t.Error("Expected pixel (100, 40) to be set in hi-res mode")
+	}
+	if len(c.Frame()) != HiResWidth*HiResHeight {
+		t.Errorf("Expected a %d byte frame, got %d", HiResWidth*HiResHeight, len(c.Frame()))
+	}
+
+	c.EmulateCycle()
+	if c.HiRes || len(c.Frame()) != DisplayWidth*DisplayHeight {
+		t.Error("Expected 00FE to return to 64x32")
+	}
+
+	c.HiRes = true
+	c.Reset()
+	if c.HiRes {
+		t.Error("Expected Reset to return to low-res mode")
+	}
+}
+
+/*
+TestSCHIPScroll checks 00CN, 00FB and 00FC on a single lit pixel in hi-res mode,
+and the halved distance in low-res mode unless LowResScrollFull is set.
+*/
+func TestSCHIPScroll(t *testing.T) {
+	cases := []struct {
+		name       string
+		hiRes      bool
+		scrollFull bool
+		opcode     uint16
+		dx, dy     int
+	}{
+		{"hi-res down 3", true, false, 0x00C3, 0, 3},
+		{"hi-res right", true, false, 0x00FB, 4, 0},
+		{"hi-res left", true, false, 0x00FC, -4, 0},
+		{"low-res down 3", false, false, 0x00C3, 0, 1},
+		{"low-res right", false, false, 0x00FB, 2, 0},
+		{"low-res left", false, false, 0x00FC, -2, 0},
+		{"low-res right full", false, true, 0x00FB, 4, 0},
+	}
+	for _, tc := range cases {
+		c := New()
+		c.HiRes = tc.hiRes
+		c.Quirks.LowResScrollFull = tc.scrollFull
+		w := c.Width()
+		x, y := 10, 5
+		c.Display[y*w+x] = 1
+		c.Memory[ProgramStart] = byte(tc.opcode >> 8)
+		c.Memory[ProgramStart+1] = byte(tc.opcode)
+		c.IsRunning = true
+
+		c.EmulateCycle()
+
+		if !c.DrawFlag {
+			t.Errorf("%s: expected DrawFlag to be set", tc.name)
+		}
+		want := (y+tc.dy)*w + x + tc.dx
+		for i, px := range c.Frame() {
+			if (i == want) != (px == 1) {
+				t.Errorf("%s: expected only pixel %d lit, pixel %d is %d", tc.name, want, i, px)
+				break
+			}
+		}
+	}
+}
+
+/*
+TestSCHIPScrollClearsEdge checks that pixels scrolled off the edge are dropped
+rather than wrapped.
+*/
+func TestSCHIPScrollClearsEdge(t *testing.T) {
+	c := New()
+	c.HiRes = true
+	c.Display[HiResWidth-1] = 1 // top-right pixel
+	copy(c.Memory[ProgramStart:], []byte{0x00, 0xFB})
+	c.IsRunning = true
+
+	c.EmulateCycle()
+
+	for i, px := range c.Frame() {
+		if px != 0 {
+			t.Fatalf("Expected an empty frame, pixel %d is lit", i)
+		}
+	}
+}
+
+/*
+TestJumpOffsetOverflow checks BNNN with a target past the end of memory: the
+address wraps normally and faults in strict mode.
+*/
+func TestJumpOffsetOverflow(t *testing.T) {
+	for _, strict := range []bool{false, true} {
+		c := New()
+		c.Strict = strict
+		c.Registers[0] = 0x20
+		copy(c.Memory[ProgramStart:], []byte{0xBF, 0xF0}) // JP V0, 0xFF0 -> 0x1010
+		c.IsRunning = true
+
+		c.EmulateCycle()
+
+		if strict {
+			if c.IsRunning || c.LastError == "" {
+				t.Error("Expected strict mode to fault on the overflowing jump")
+			}
+			if c.PC != ProgramStart+2 {
+				t.Errorf("Expected PC to stay at 0x%X after the fault, got 0x%X", ProgramStart+2, c.PC)
+			}
+		} else if c.PC != 0x010 {
+			t.Errorf("Expected the target to wrap to 0x010, got 0x%03X", c.PC)
+		}
+	}
+}
+
+/*
+TestXOCHIPPlanes checks FN01 plane selection: drawing to plane 2 only, drawing to
+both planes with consecutive sprite data, per-plane collision and CLS clearing
+only the selected planes.
+*/
+func TestXOCHIPPlanes(t *testing.T) {
+	c := New()
+	c.I = 0x300
+	c.Memory[0x300] = 0x80 // plane 1 sprite: leftmost pixel
+	c.Memory[0x301] = 0x40 // plane 2 sprite: second pixel
+	copy(c.Memory[ProgramStart:], []byte{
+		0xF2, 0x01, // PLANE 2
+		0xD0, 0x01, // DRW V0, V0, 1
+		0xF3, 0x01, // PLANE 3
+		0xD0, 0x01, // DRW V0, V0, 1
+		0xF1, 0x01, // PLANE 1
+		0x00, 0xE0, // CLS
+	})
+	c.IsRunning = true
+
+	c.EmulateCycle()
+	c.EmulateCycle()
+	if c.Display[0] != 0 || c.Plane2[0] != 1 {
+		t.Fatalf("Expected only plane 2 drawn, got display=%d plane2=%d", c.Display[0], c.Plane2[0])
+	}
+
+	c.EmulateCycle()
+	c.EmulateCycle()
+	// Plane 1 gets 0x80 (pixel 0); plane 2 gets 0x40 (pixel 1) and keeps pixel 0.
+	if c.Display[0] != 1 || c.Plane2[1] != 1 || c.Plane2[0] != 1 {
+		t.Errorf("Expected both planes drawn from consecutive sprite rows, got %v %v", c.Display[:2], c.Plane2[:2])
+	}
+	if c.Registers[0xF] != 0 {
+		t.Errorf("Expected no collision, got VF=%d", c.Registers[0xF])
+	}
+	if px := c.Pixels(); px[0] != 3 || px[1] != 2 {
+		t.Errorf("Expected combined pixels [3 2], got %v", px[:2])
+	}
+
+	c.EmulateCycle()
+	c.EmulateCycle()
+	if c.Display[0] != 0 || c.Plane2[0] != 1 {
+		t.Error("Expected CLS to clear only the selected plane")
+	}
+}
+
+/*
+TestXOCHIPPlaneCollision checks that a collision on the second plane sets VF.
+*/
+func TestXOCHIPPlaneCollision(t *testing.T) {
+	c := New()
+	c.I = 0x300
+	c.Memory[0x300] = 0x80
+	c.Plane2[0] = 1
+	copy(c.Memory[ProgramStart:], []byte{0xF2, 0x01, 0xD0, 0x01})
+	c.IsRunning = true
+
+	c.EmulateCycle()
+	c.EmulateCycle()
+
+	if c.Registers[0xF] != 1 || c.Plane2[0] != 0 {
+		t.Errorf("Expected a plane 2 collision erasing the pixel, got VF=%d pixel=%d", c.Registers[0xF], c.Plane2[0])
+	}
+}
+
+/*
+TestCycleAndFrameCounters checks that instructions and timer ticks are counted,
+reported by GetState and cleared by Reset.
+*/
+func TestCycleAndFrameCounters(t *testing.T) {
+	c := New()
+	// 0x200: ADD V0, 1 ; 0x202: JP 0x200
+	c.LoadROM([]byte{0x70, 0x01, 0x12, 0x00})
+	c.IsRunning = true
+	for i := 0; i < 10; i++ {
+		c.EmulateCycle()
+	}
+	c.UpdateTimers()
+	c.UpdateTimers()
+
+	state := c.GetState()
+	if state["CycleCount"] != uint64(10) {
+		t.Errorf("Expected CycleCount 10, got %v", state["CycleCount"])
+	}
+	if state["FrameCount"] != uint64(2) {
+		t.Errorf("Expected FrameCount 2, got %v", state["FrameCount"])
+	}
+
+	c.Reset()
+	if c.CycleCount != 0 || c.FrameCount != 0 {
+		t.Errorf("Expected Reset to clear the counters, got %d cycles and %d frames", c.CycleCount, c.FrameCount)
+	}
+}
+
+/*
+TestLargeSprite draws a 16x16 X with DXY0 in hi-res mode and checks every pixel
+of it, then draws it again to check the collision flag and that it erases.
+*/
+func TestLargeSprite(t *testing.T) {
+	c := New()
+	c.setHiRes(true)
+	c.I = 0x300
+	for row := 0; row < 16; row++ {
+		bits := uint16(0x8000)>>row | uint16(0x0001)<<row
+		c.Memory[0x300+2*row] = byte(bits >> 8)
+		c.Memory[0x300+2*row+1] = byte(bits)
+	}
+	c.Registers[0x0] = 10
+	c.Registers[0x1] = 5
+	// DRW V0, V1, 0 ; DRW V0, V1, 0
+	copy(c.Memory[ProgramStart:], []byte{0xD0, 0x10, 0xD0, 0x10})
+	c.IsRunning = true
+
+	c.EmulateCycle()
+
+	for row := 0; row < 16; row++ {
+		for col := 0; col < 16; col++ {
+			want := byte(0)
+			if col == row || col == 15-row {
+				want = 1
+			}
+			if got := c.Display[(5+row)*HiResWidth+10+col]; got != want {
+				t.Errorf("Expected pixel (%d, %d) to be %d, got %d", col, row, want, got)
+			}
+		}
+	}
+	if c.Registers[0xF] != 0 {
+		t.Errorf("Expected VF 0 after the first draw, got %d", c.Registers[0xF])
+	}
+
+	c.EmulateCycle()
+
+	if c.Registers[0xF] != 1 {
+		t.Errorf("Expected VF 1 after drawing over the sprite, got %d", c.Registers[0xF])
+	}
+	for i, px := range c.Display {
+		if px != 0 {
+			t.Fatalf("Expected the second draw to erase the sprite, pixel %d is still set", i)
+		}
+	}
+}
+
+/*
+TestLargeSpriteLowRes checks that low-res DXY0 draws nothing unless the
+LargeSpritesInLowRes quirk is on, in which case it draws a full 16x16 block.
+*/
+func TestLargeSpriteLowRes(t *testing.T) {
+	for _, large := range []bool{false, true} {
+		c := New()
+		c.Quirks.LargeSpritesInLowRes = large
+		c.I = 0x300
+		for i := 0; i < 32; i++ {
+			c.Memory[0x300+i] = 0xFF
+		}
+		// DRW V0, V1, 0
+		copy(c.Memory[ProgramStart:], []byte{0xD0, 0x10})
+		c.IsRunning = true
+
+		c.EmulateCycle()
+
+		lit := 0
+		for _, px := range c.Display {
+			lit += int(px)
+		}
+		want := 0
+		if large {
+			want = 16 * 16
+		}
+		if lit != want {
+			t.Errorf("large=%v: expected %d pixels lit, got %d", large, want, lit)
+		}
+		if large && (c.Display[0] != 1 || c.Display[15*DisplayWidth+15] != 1 || c.Display[16] != 0) {
+			t.Errorf("large=%v: expected the block to cover columns and rows 0-15", large)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package chip8
+
+// fields holds the operand fields of an opcode. Which ones are meaningful
+// depends on the instruction; e.g. 6XNN uses x and nn.
+type fields struct {
+	high uint16 // Top nibble, selecting the instruction group
+	x    uint16 // Second nibble, usually a register index
+	y    uint16 // Third nibble, usually a register index
+	n    byte   // Lowest nibble
+	nn   byte   // Lowest byte
+	nnn  uint16 // Lowest 12 bits, usually an address
+}
+
+// decode splits an opcode into its fields.
+func decode(opcode uint16) fields {
+	return fields{
+		high: opcode >> 12,
+		x:    (opcode & 0x0F00) >> 8,
+		y:    (opcode & 0x00F0) >> 4,
+		n:    byte(opcode & 0x000F),
+		nn:   byte(opcode & 0x00FF),
+		nnn:  opcode & 0x0FFF,
+	}
+}
+
+// DecodeFields returns the named fields of any opcode ("opcode", "high", "x",
+// "y", "n", "nn" and "nnn"), for showing how an instruction breaks down. The
+// fields are extracted whether or not the instruction uses them.
+func DecodeFields(opcode uint16) map[string]uint16 {
+	f := decode(opcode)
+	return map[string]uint16{
+		"opcode": opcode,
+		"high":   f.high,
+		"x":      f.x,
+		"y":      f.y,
+		"n":      uint16(f.n),
+		"nn":     uint16(f.nn),
+		"nnn":    f.nnn,
+	}
+}
+
+// InstructionInfo describes the instruction at an address with its fields
+// already decoded, for debugger inspectors.
+type InstructionInfo struct {
+	Address     uint16 `json:"address"`
+	Opcode      uint16 `json:"opcode"`
+	HighByte    byte   `json:"highByte"` // Byte at Address
+	LowByte     byte   `json:"lowByte"`  // Byte at Address+1
+	Mnemonic    string `json:"mnemonic"`
+	VX          uint16 `json:"vx"`
+	VY          uint16 `json:"vy"`
+	NNN         uint16 `json:"nnn"`
+	NN          byte   `json:"nn"`
+	N           byte   `json:"n"`
+	Description string `json:"description"`
+}
+
+// InstructionAtPC decodes the instruction the CPU will execute next.
+func (c *Chip8) InstructionAtPC() InstructionInfo {
+	high := c.Memory[int(c.PC)%len(c.Memory)]
+	low := c.Memory[(int(c.PC)+1)%len(c.Memory)]
+	opcode := uint16(high)<<8 | uint16(low)
+	f := decode(opcode)
+	return InstructionInfo{
+		Address:     c.PC,
+		Opcode:      opcode,
+		HighByte:    high,
+		LowByte:     low,
+		Mnemonic:    DisassembleWithQuirks(opcode, c.Quirks),
+		VX:          f.x,
+		VY:          f.y,
+		NNN:         f.nnn,
+		NN:          f.nn,
+		N:           f.n,
+		Description: describe(opcode),
+	}
+}
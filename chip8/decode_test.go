@@ -0,0 +1,59 @@
+package chip8
+
+import "testing"
+
+/*
+TestDecodeFields checks the decoded fields of several representative opcodes.
+*/
+func TestDecodeFields(t *testing.T) {
+	tests := []struct {
+		opcode uint16
+		want   map[string]uint16
+	}{
+		{0x00E0, map[string]uint16{"opcode": 0x00E0, "high": 0x0, "x": 0x0, "y": 0xE, "n": 0x0, "nn": 0xE0, "nnn": 0x0E0}},
+		{0x1234, map[string]uint16{"opcode": 0x1234, "high": 0x1, "x": 0x2, "y": 0x3, "n": 0x4, "nn": 0x34, "nnn": 0x234}},
+		{0x6A2B, map[string]uint16{"opcode": 0x6A2B, "high": 0x6, "x": 0xA, "y": 0x2, "n": 0xB, "nn": 0x2B, "nnn": 0xA2B}},
+		{0x8C5E, map[string]uint16{"opcode": 0x8C5E, "high": 0x8, "x": 0xC, "y": 0x5, "n": 0xE, "nn": 0x5E, "nnn": 0xC5E}},
+		{0xD125, map[string]uint16{"opcode": 0xD125, "high": 0xD, "x": 0x1, "y": 0x2, "n": 0x5, "nn": 0x25, "nnn": 0x125}},
+		{0xFF65, map[string]uint16{"opcode": 0xFF65, "high": 0xF, "x": 0xF, "y": 0x6, "n": 0x5, "nn": 0x65, "nnn": 0xF65}},
+	}
+	for _, tc := range tests {
+		got := DecodeFields(tc.opcode)
+		if len(got) != len(tc.want) {
+			t.Errorf("%04X: expected %d fields, got %d", tc.opcode, len(tc.want), len(got))
+		}
+		for name, want := range tc.want {
+			if got[name] != want {
+				t.Errorf("%04X: expected %s=0x%X, got 0x%X", tc.opcode, name, want, got[name])
+			}
+		}
+	}
+}
+
+/*
+TestInstructionAtPC checks the decoded fields, bytes and description of the
+instruction at the program counter.
+*/
+func TestInstructionAtPC(t *testing.T) {
+	c := New()
+	c.Memory[ProgramStart] = 0xD1
+	c.Memory[ProgramStart+1] = 0x25
+
+	got := c.InstructionAtPC()
+	want := InstructionInfo{
+		Address:     ProgramStart,
+		Opcode:      0xD125,
+		HighByte:    0xD1,
+		LowByte:     0x25,
+		Mnemonic:    "DRW V1, V2, 5",
+		VX:          0x1,
+		VY:          0x2,
+		NNN:         0x125,
+		NN:          0x25,
+		N:           0x5,
+		Description: "Draw an N-row sprite from I at (VX, VY); VF is set on collision",
+	}
+	if got != want {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+}
@@ -0,0 +1,103 @@
+package chip8
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Instruction is one line of a program listing produced by DisassembleProgram.
+type Instruction struct {
+	Address     uint16 `json:"address"`
+	Bytes       string `json:"bytes"`                 // Raw bytes in hex, e.g. "6A2B"
+	Mnemonic    string `json:"mnemonic"`              // Jump and call targets inside the program use their label
+	Label       string `json:"label,omitempty"`       // Set when another instruction jumps to or calls this address
+	Target      uint16 `json:"target,omitempty"`      // Destination of a JP or CALL
+	TargetLabel string `json:"targetLabel,omitempty"` // Label of Target when it lies inside the program
+}
+
+// DisassembleProgram decodes data, loaded at startAddr, into a full listing.
+// Destinations of JP and CALL that fall inside the program get synthetic labels
+// such as L_0234. Code and data cannot be told apart statically, so every
+// aligned word is decoded as an instruction; a trailing odd byte is listed as DB.
+// Instructions read as they execute under quirks q.
+func DisassembleProgram(data []byte, startAddr uint16, q Quirks) []Instruction {
+	end := int(startAddr) + len(data)
+	var listing []Instruction
+	labels := make(map[uint16]string)
+	for i := 0; i < len(data); i += 2 {
+		addr := startAddr + uint16(i)
+		if i+1 == len(data) {
+			listing = append(listing, Instruction{
+				Address:  addr,
+				Bytes:    fmt.Sprintf("%02X", data[i]),
+				Mnemonic: fmt.Sprintf("DB 0x%02X", data[i]),
+			})
+			break
+		}
+		opcode := uint16(data[i])<<8 | uint16(data[i+1])
+		inst := Instruction{
+			Address:  addr,
+			Bytes:    fmt.Sprintf("%04X", opcode),
+			Mnemonic: DisassembleWithQuirks(opcode, q),
+		}
+		var verb string
+		switch opcode & 0xF000 {
+		case 0x1000:
+			verb = "JP"
+		case 0x2000:
+			verb = "CALL"
+		}
+		if verb != "" {
+			inst.Target = opcode & 0x0FFF
+			// Only aligned targets inside the program line up with a listed instruction
+			if int(inst.Target) >= int(startAddr) && int(inst.Target) < end && (inst.Target-startAddr)%2 == 0 {
+				inst.TargetLabel = fmt.Sprintf("L_%04X", inst.Target)
+				inst.Mnemonic = verb + " " + inst.TargetLabel
+				labels[inst.Target] = inst.TargetLabel
+			}
+		}
+		listing = append(listing, inst)
+	}
+	for i := range listing {
+		listing[i].Label = labels[listing[i].Address]
+	}
+	return listing
+}
+
+// Line is a disassembled instruction split into parts for syntax highlighting.
+type Line struct {
+	Address  uint16   `json:"address"`
+	Mnemonic string   `json:"mnemonic"` // e.g. "LD"
+	Operands []string `json:"operands"` // e.g. ["V5", "0x2A"]; empty for CLS, RET, ...
+	Bytes    string   `json:"bytes"`    // Raw bytes in hex, e.g. "652A"
+}
+
+// DisassembleLines decodes count instructions of memory starting at start,
+// stopping early at the end of memory, as they execute under quirks q.
+func DisassembleLines(memory []byte, start, count uint16, q Quirks) []Line {
+	lines := make([]Line, 0, count)
+	for addr := int(start); addr+1 < len(memory) && len(lines) < int(count); addr += 2 {
+		opcode := uint16(memory[addr])<<8 | uint16(memory[addr+1])
+		mnemonic, operands := SplitInstruction(DisassembleWithQuirks(opcode, q))
+		lines = append(lines, Line{
+			Address:  uint16(addr),
+			Mnemonic: mnemonic,
+			Operands: operands,
+			Bytes:    fmt.Sprintf("%04X", opcode),
+		})
+	}
+	return lines
+}
+
+// SplitInstruction splits a Disassemble result such as "LD V5, 0x2A" into its
+// mnemonic and operands.
+func SplitInstruction(text string) (string, []string) {
+	parts := strings.SplitN(text, " ", 2)
+	operands := []string{}
+	if len(parts) == 2 {
+		for _, op := range strings.Split(parts[1], ",") {
+			operands = append(operands, strings.TrimSpace(op))
+		}
+	}
+	return parts[0], operands
+}
@@ -0,0 +1,84 @@
+package chip8
+
+import (
+	"reflect"
+	"testing"
+)
+
+/*
+TestDisassembleProgramLabels checks that jump and call destinations inside the
+program get labels on both ends, that outside targets keep their address, and
+that a trailing odd byte is listed as data.
+*/
+func TestDisassembleProgramLabels(t *testing.T) {
+	rom := []byte{
+		0x22, 0x06, // 0x200: CALL 0x206
+		0x12, 0x02, // 0x202: JP 0x202
+		0x13, 0x00, // 0x204: JP 0x300 (outside the program)
+		0x00, 0xEE, // 0x206: RET
+		0xAB, // 0x208: trailing byte
+	}
+	listing := DisassembleProgram(rom, ProgramStart, DefaultQuirks())
+
+	if len(listing) != 5 {
+		t.Fatalf("Expected 5 instructions, got %d", len(listing))
+	}
+	want := []Instruction{
+		{Address: 0x200, Bytes: "2206", Mnemonic: "CALL L_0206", Target: 0x206, TargetLabel: "L_0206"},
+		{Address: 0x202, Bytes: "1202", Mnemonic: "JP L_0202", Label: "L_0202", Target: 0x202, TargetLabel: "L_0202"},
+		{Address: 0x204, Bytes: "1300", Mnemonic: "JP 0x300", Target: 0x300},
+		{Address: 0x206, Bytes: "00EE", Mnemonic: "RET", Label: "L_0206"},
+		{Address: 0x208, Bytes: "AB", Mnemonic: "DB 0xAB"},
+	}
+	for i, w := range want {
+		if listing[i] != w {
+			t.Errorf("Line %d: expected %+v, got %+v", i, w, listing[i])
+		}
+	}
+}
+
+/*
+TestDisassembleLines checks that lines are split into mnemonic and operands, and
+that decoding stops at the end of memory.
+*/
+func TestDisassembleLines(t *testing.T) {
+	var mem [0x206]byte
+	copy(mem[0x200:], []byte{0x65, 0x2A, 0x00, 0xE0, 0xD1, 0x25})
+
+	lines := DisassembleLines(mem[:], 0x200, 10, DefaultQuirks())
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 lines before the end of memory, got %d", len(lines))
+	}
+	want := []Line{
+		{Address: 0x200, Mnemonic: "LD", Operands: []string{"V5", "0x2A"}, Bytes: "652A"},
+		{Address: 0x202, Mnemonic: "CLS", Operands: []string{}, Bytes: "00E0"},
+		{Address: 0x204, Mnemonic: "DRW", Operands: []string{"V1", "V2", "5"}, Bytes: "D125"},
+	}
+	if !reflect.DeepEqual(lines, want) {
+		t.Errorf("Expected %+v, got %+v", want, lines)
+	}
+}
+
+/*
+TestDisassembleJumpQuirk checks that BNNN reads as JP V0 by default and as JP VX
+under the JumpQuirk, in the plain and structured listings alike.
+*/
+func TestDisassembleJumpQuirk(t *testing.T) {
+	if got := DisassembleWithQuirks(0xB345, DefaultQuirks()); got != "JP V0, 0x345" {
+		t.Errorf("Expected JP V0, 0x345 without the quirk, got %q", got)
+	}
+	q := DefaultQuirks()
+	q.JumpQuirk = true
+	if got := DisassembleWithQuirks(0xB345, q); got != "JP V3, 0x345" {
+		t.Errorf("Expected JP V3, 0x345 under the quirk, got %q", got)
+	}
+
+	mem := []byte{0xB3, 0x45}
+	if got := DisassembleProgram(mem, 0, q)[0].Mnemonic; got != "JP V3, 0x345" {
+		t.Errorf("Expected the program listing to follow the quirk, got %q", got)
+	}
+	line := DisassembleLines(mem, 0, 1, q)[0]
+	if line.Mnemonic != "JP" || !reflect.DeepEqual(line.Operands, []string{"V3", "0x345"}) {
+		t.Errorf("Expected JP [V3 0x345] in the structured listing, got %s %v", line.Mnemonic, line.Operands)
+	}
+}
@@ -0,0 +1,37 @@
+package chip8
+
+import "fmt"
+
+// opcodeHook is a custom instruction registered with RegisterOpcodeHandler.
+type opcodeHook struct {
+	mask  uint16
+	match uint16
+	fn    func(c *Chip8, opcode uint16)
+}
+
+// RegisterOpcodeHandler makes EmulateCycle run fn for every opcode where
+// opcode&mask == match, for prototyping instructions without editing the core.
+// Custom handlers are checked before the built-in decode and win over it, so a
+// handler can also replace a built-in instruction. If several handlers match,
+// the first registered runs. When fn is called PC already points at the next
+// instruction. Handlers survive Reset.
+func (c *Chip8) RegisterOpcodeHandler(mask, match uint16, fn func(c *Chip8, opcode uint16)) error {
+	if fn == nil {
+		return fmt.Errorf("opcode handler for %04X/%04X is nil", match, mask)
+	}
+	if match&^mask != 0 {
+		return fmt.Errorf("opcode pattern %04X has bits outside mask %04X and can never match", match, mask)
+	}
+	c.hooks = append(c.hooks, opcodeHook{mask: mask, match: match, fn: fn})
+	return nil
+}
+
+// customHandler returns the registered handler for opcode, or nil if none matches.
+func (c *Chip8) customHandler(opcode uint16) func(c *Chip8, opcode uint16) {
+	for _, h := range c.hooks {
+		if opcode&h.mask == h.match {
+			return h.fn
+		}
+	}
+	return nil
+}
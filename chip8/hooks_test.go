@@ -0,0 +1,54 @@
+package chip8
+
+import "testing"
+
+/*
+TestRegisterOpcodeHandler registers a custom instruction in the unused 5XY1 slot
+and checks that it runs only for matching opcodes, with PC already advanced.
+*/
+func TestRegisterOpcodeHandler(t *testing.T) {
+	c := New()
+	// 5XY1: VX = VX * VY
+	err := c.RegisterOpcodeHandler(0xF00F, 0x5001, func(c *Chip8, opcode uint16) {
+		f := decode(opcode)
+		c.Registers[f.x] *= c.Registers[f.y]
+	})
+	if err != nil {
+		t.Fatalf("RegisterOpcodeHandler failed: %v", err)
+	}
+	var seenPC uint16
+	c.RegisterOpcodeHandler(0xFFFF, 0x0123, func(c *Chip8, opcode uint16) { seenPC = c.PC })
+
+	c.Registers[0x1] = 6
+	c.Registers[0x2] = 7
+	// MUL V1, V2 ; SE V1, V2 (built-in) ; custom 0123
+	copy(c.Memory[ProgramStart:], []byte{0x51, 0x21, 0x51, 0x20, 0x01, 0x23})
+	c.IsRunning = true
+
+	c.EmulateCycle()
+	if c.Registers[0x1] != 42 {
+		t.Errorf("Expected the custom handler to set V1 to 42, got %d", c.Registers[0x1])
+	}
+	c.EmulateCycle()
+	if c.PC != ProgramStart+4 {
+		t.Errorf("Expected the built-in 5XY0 to run for a non-matching opcode, PC is 0x%X", c.PC)
+	}
+	c.EmulateCycle()
+	if seenPC != ProgramStart+6 {
+		t.Errorf("Expected PC to point past the custom instruction, got 0x%X", seenPC)
+	}
+}
+
+/*
+TestRegisterOpcodeHandlerRejectsBadPatterns checks that a nil handler and a
+pattern that can never match are refused.
+*/
+func TestRegisterOpcodeHandlerRejectsBadPatterns(t *testing.T) {
+	c := New()
+	if err := c.RegisterOpcodeHandler(0xF000, 0x5000, nil); err == nil {
+		t.Error("Expected an error for a nil handler")
+	}
+	if err := c.RegisterOpcodeHandler(0xF000, 0x5001, func(*Chip8, uint16) {}); err == nil {
+		t.Error("Expected an error for a match outside the mask")
+	}
+}
@@ -0,0 +1,87 @@
+package chip8
+
+// Machine identifies a CHIP-8 platform variant.
+type Machine string
+
+const (
+	MachineCHIP8  Machine = "chip8"
+	MachineSCHIP  Machine = "schip"
+	MachineXOCHIP Machine = "xochip"
+)
+
+// Valid reports whether m is one of the supported platforms.
+func (m Machine) Valid() bool {
+	switch m {
+	case MachineCHIP8, MachineSCHIP, MachineXOCHIP:
+		return true
+	}
+	return false
+}
+
+// DetectMachine scans a ROM image for opcodes that only exist on later platforms
+// and returns the platform it most likely targets. XO-CHIP is a superset of
+// SUPER-CHIP, so XO-CHIP markers win. The scan decodes every aligned word,
+// including sprite data, so the result is a suggestion rather than a certainty.
+func DetectMachine(rom []byte) Machine {
+	machine := MachineCHIP8
+	for i := 0; i+1 < len(rom); i += 2 {
+		opcode := uint16(rom[i])<<8 | uint16(rom[i+1])
+		switch {
+		case isXOCHIPMarker(opcode):
+			return MachineXOCHIP
+		case isSCHIPMarker(opcode):
+			machine = MachineSCHIP
+		}
+	}
+	return machine
+}
+
+// MachineQuirks returns the quirk set a platform's interpreters expect.
+func MachineQuirks(m Machine) Quirks {
+	switch m {
+	case MachineSCHIP:
+		return Quirks{ShiftFlagLast: true, ClipSprites: true, JumpQuirk: true, LargeSpritesInLowRes: true}
+	case MachineXOCHIP:
+		return Quirks{ShiftFlagLast: true, ShiftUsesVY: true, IncrementIOnStore: true, LowResScrollFull: true, LargeSpritesInLowRes: true}
+	default:
+		return DefaultQuirks()
+	}
+}
+
+// isSCHIPMarker reports whether opcode is one of the SUPER-CHIP additions:
+// scrolling (00Cn, 00FB, 00FC), exit (00FD), resolution (00FE, 00FF),
+// 16x16 sprites (Dxy0), big font (Fx30) and RPL flags (Fx75, Fx85).
+func isSCHIPMarker(opcode uint16) bool {
+	switch {
+	case opcode&0xFFF0 == 0x00C0 && opcode != 0x00C0:
+		return true
+	case opcode >= 0x00FB && opcode <= 0x00FF:
+		return true
+	case opcode&0xF00F == 0xD000:
+		return true
+	}
+	if opcode&0xF000 == 0xF000 {
+		switch opcode & 0x00FF {
+		case 0x30, 0x75, 0x85:
+			return true
+		}
+	}
+	return false
+}
+
+// isXOCHIPMarker reports whether opcode is one of the XO-CHIP additions:
+// scroll up (00Dn), register ranges (5xy2, 5xy3), long I load (F000),
+// plane select (Fn01), audio pattern (F002) and pitch (Fx3A).
+func isXOCHIPMarker(opcode uint16) bool {
+	switch {
+	case opcode&0xFFF0 == 0x00D0 && opcode != 0x00D0:
+		return true
+	case opcode&0xF00F == 0x5002, opcode&0xF00F == 0x5003:
+		return true
+	case opcode == 0xF000, opcode == 0xF002:
+		return true
+	case opcode&0xF0FF == 0xF001, opcode&0xF0FF == 0xF03A:
+		return true
+	}
+	return false
+}
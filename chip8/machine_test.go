@@ -0,0 +1,63 @@
+package chip8
+
+import "testing"
+
+/*
+TestDetectMachine checks that ROMs containing SUPER-CHIP or XO-CHIP marker opcodes
+are recognised, and that a plain ROM stays classic CHIP-8.
+*/
+func TestDetectMachine(t *testing.T) {
+	cases := []struct {
+		name string
+		rom  []byte
+		want Machine
+	}{
+		{"plain", []byte{0x00, 0xE0, 0x60, 0x05, 0xD0, 0x15, 0x12, 0x00}, MachineCHIP8},
+		{"schip hires", []byte{0x00, 0xE0, 0x00, 0xFF, 0x12, 0x00}, MachineSCHIP},
+		{"schip big sprite", []byte{0xA2, 0x10, 0xD0, 0x10}, MachineSCHIP},
+		{"schip big font", []byte{0xF3, 0x30}, MachineSCHIP},
+		{"xochip planes", []byte{0x00, 0xFF, 0xF2, 0x01}, MachineXOCHIP},
+		{"xochip long load", []byte{0xF0, 0x00, 0x12, 0x34}, MachineXOCHIP},
+	}
+	for _, tc := range cases {
+		if got := DetectMachine(tc.rom); got != tc.want {
+			t.Errorf("%s: expected %q, got %q", tc.name, tc.want, got)
+		}
+	}
+}
+
+/*
+TestMachineQuirks checks that SUPER-CHIP gets the shift quirk its interpreters use
+while classic CHIP-8 keeps the default quirks.
+*/
+func TestMachineQuirks(t *testing.T) {
+	if !MachineQuirks(MachineSCHIP).ShiftFlagLast {
+		t.Error("Expected SCHIP quirks to write VF last on shifts")
+	}
+	if MachineQuirks(MachineCHIP8) != DefaultQuirks() {
+		t.Error("Expected classic CHIP-8 to use the default quirks")
+	}
+}
+
+/*
+TestQuirkPresets checks that every preset resolves to a quirk set, that the
+COSMAC VIP preset waits for the display, and that unknown names are rejected.
+*/
+func TestQuirkPresets(t *testing.T) {
+	for _, p := range QuirkPresets() {
+		if _, ok := p.Quirks(); !ok {
+			t.Errorf("Expected preset %q to be known", p)
+		}
+	}
+	vip, _ := PresetCOSMACVIP.Quirks()
+	if !vip.DisplayWait || !vip.VFResetOnLogic || !vip.ClipSprites {
+		t.Errorf("Expected the COSMAC VIP preset to wait, reset VF and clip, got %+v", vip)
+	}
+	xo, _ := PresetXOCHIP.Quirks()
+	if xo.ClipSprites || !xo.IncrementIOnStore {
+		t.Errorf("Expected the XO-CHIP preset to wrap sprites and increment I, got %+v", xo)
+	}
+	if _, ok := QuirkPreset("nes").Quirks(); ok {
+		t.Error("Expected an unknown preset to be rejected")
+	}
+}
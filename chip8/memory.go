@@ -0,0 +1,79 @@
+package chip8
+
+import "maps"
+
+const (
+	DefaultMemorySize = 4096  // Address space of the original CHIP-8 and SUPER-CHIP
+	XOCHIPMemorySize  = 65536 // XO-CHIP's full 16-bit address space
+)
+
+// Option configures a Chip8 built by New.
+type Option func(*Chip8)
+
+// WithMemorySize sets the size of memory in bytes. Sizes outside
+// DefaultMemorySize to XOCHIPMemorySize are clamped into that range, since I
+// and PC are 16-bit and the font and program area must fit.
+func WithMemorySize(size int) Option {
+	return func(c *Chip8) {
+		if size < DefaultMemorySize {
+			size = DefaultMemorySize
+		}
+		if size > XOCHIPMemorySize {
+			size = XOCHIPMemorySize
+		}
+		c.Memory = make([]byte, size)
+	}
+}
+
+// MemorySize returns the size of memory in bytes.
+func (c *Chip8) MemorySize() int {
+	return len(c.Memory)
+}
+
+// Clone returns a deep copy of the CPU that shares no state with the original:
+// memory, breakpoints, watchpoints, protection, statistics and the trace are all
+// copied, so either side can be changed or run on another goroutine without
+// affecting the other. The clone's RND generator continues the original's
+// stream from the same point. Opcode hooks are copied, but the handler
+// functions themselves are shared.
+func (c *Chip8) Clone() *Chip8 {
+	clone := *c
+	clone.Memory = append([]byte(nil), c.Memory...)
+	clone.ResetFillPattern = append([]byte(nil), c.ResetFillPattern...)
+	clone.Breakpoints = maps.Clone(c.Breakpoints)
+	clone.BreakpointSkips = maps.Clone(c.BreakpointSkips)
+	clone.BreakConditions = maps.Clone(c.BreakConditions)
+	clone.Watchpoints = maps.Clone(c.Watchpoints)
+	clone.protected = maps.Clone(c.protected)
+	clone.opcodesUsed = maps.Clone(c.opcodesUsed)
+	clone.breakpointHits = maps.Clone(c.breakpointHits)
+	clone.hooks = append([]opcodeHook(nil), c.hooks...)
+	if c.trace != nil {
+		clone.trace = &traceBuffer{entries: append([]TraceEntry(nil), c.trace.entries...), next: c.trace.next, full: c.trace.full}
+	}
+	if c.pinnedSeed != nil {
+		seed := *c.pinnedSeed
+		clone.pinnedSeed = &seed
+	}
+	clone.rng = nil // Rebuilt from RandSeed and RandDraws on the clone's next RND
+	return &clone
+}
+
+// CopySetup copies the protected address ranges and opcode hooks of from onto c.
+// Save states record neither, and both survive Reset, so a CPU decoded from a
+// save state takes them from the CPU it replaces.
+func (c *Chip8) CopySetup(from *Chip8) {
+	c.protected = maps.Clone(from.protected)
+	c.hooks = append([]opcodeHook(nil), from.hooks...)
+}
+
+// skipNext skips the next instruction. XO-CHIP's F000 NNNN is four bytes long,
+// so it is skipped as a whole rather than landing on its address word.
+func (c *Chip8) skipNext() {
+	next := uint16(c.Memory[int(c.PC)%len(c.Memory)])<<8 | uint16(c.Memory[(int(c.PC)+1)%len(c.Memory)])
+	if next == 0xF000 {
+		c.PC += 4
+		return
+	}
+	c.PC += 2
+}
@@ -0,0 +1,157 @@
+package chip8
+
+import "testing"
+
+/*
+TestWithMemorySize checks the default size, that the option sets and clamps the
+size, that Reset keeps it, and that LoadROM accepts ROMs up to the new capacity.
+*/
+func TestWithMemorySize(t *testing.T) {
+	if got := New().MemorySize(); got != DefaultMemorySize {
+		t.Errorf("Expected a default memory size of %d, got %d", DefaultMemorySize, got)
+	}
+	if got := New(WithMemorySize(100)).MemorySize(); got != DefaultMemorySize {
+		t.Errorf("Expected a small size to be clamped to %d, got %d", DefaultMemorySize, got)
+	}
+
+	c := New(WithMemorySize(XOCHIPMemorySize))
+	c.Reset()
+	if c.MemorySize() != XOCHIPMemorySize {
+		t.Fatalf("Expected %d bytes after Reset, got %d", XOCHIPMemorySize, c.MemorySize())
+	}
+	rom := make([]byte, 8192)
+	if err := c.LoadROM(rom); err != nil {
+		t.Errorf("Expected an 8KB ROM to fit in 64KB, got %v", err)
+	}
+	if err := New().LoadROM(rom); err == nil {
+		t.Error("Expected an 8KB ROM not to fit in 4KB")
+	}
+}
+
+/*
+TestOpcodeF000LoadsLongI checks that F000 NNNN loads the 16-bit word after it
+into I and continues after that word.
+*/
+func TestOpcodeF000LoadsLongI(t *testing.T) {
+	c := New(WithMemorySize(XOCHIPMemorySize))
+	copy(c.Memory[ProgramStart:], []byte{0xF0, 0x00, 0xAB, 0xCD})
+	c.IsRunning = true
+
+	c.EmulateCycle()
+
+	if c.I != 0xABCD {
+		t.Errorf("Expected I to be 0xABCD, got 0x%04X", c.I)
+	}
+	if c.PC != ProgramStart+4 {
+		t.Errorf("Expected PC to be 0x%X, got 0x%X", ProgramStart+4, c.PC)
+	}
+}
+
+/*
+TestSkipOverF000 checks that a skip lands after the whole four-byte F000 NNNN
+rather than on its address word.
+*/
+func TestSkipOverF000(t *testing.T) {
+	c := New(WithMemorySize(XOCHIPMemorySize))
+	// SE V0, 0; F000 NNNN
+	copy(c.Memory[ProgramStart:], []byte{0x30, 0x00, 0xF0, 0x00, 0x12, 0x34})
+	c.IsRunning = true
+
+	c.EmulateCycle()
+
+	if c.PC != ProgramStart+6 {
+		t.Errorf("Expected PC to be 0x%X, got 0x%X", ProgramStart+6, c.PC)
+	}
+}
+
+/*
+TestCloneCopiesMemory checks that writes to a clone's memory leave the original
+untouched.
+*/
+func TestCloneCopiesMemory(t *testing.T) {
+	c := New()
+	clone := c.Clone()
+	clone.Memory[0x300] = 0xFF
+	if c.Memory[0x300] != 0 {
+		t.Error("Expected the original memory to be unchanged")
+	}
+}
+
+/*
+TestCloneIsIndependent checks that a clone shares no state with the original:
+changing the clone's registers, display, breakpoints, watchpoints, protection
+and trace leaves the original untouched, and the other way round.
+*/
+func TestCloneIsIndependent(t *testing.T) {
+	c := New()
+	c.SetTracing(true)
+	c.Breakpoints[0x208] = true
+	c.Watchpoints = map[uint16]bool{0x300: true}
+	c.ProtectRange(0x400, 0x401)
+	if err := c.SetConditionalBreakpoint(0x202, 0, "==", 1); err != nil {
+		t.Fatalf("SetConditionalBreakpoint failed: %v", err)
+	}
+	c.LoadROM([]byte{0x60, 0x01, 0x12, 0x02}) // LD V0, 1 ; JP 0x202
+	c.IsRunning = true
+
+	clone := c.Clone()
+	clone.Registers[0x5] = 0xAA
+	clone.Display[0] = 1
+	clone.Breakpoints[0x204] = true
+	clone.Watchpoints[0x301] = true
+	delete(clone.BreakConditions, 0x202)
+	clone.UnprotectRange(0x400, 0x401)
+	clone.EmulateCycle()
+
+	if c.Registers[0x5] != 0 || c.Display[0] != 0 {
+		t.Error("Expected the original registers and display to be unchanged")
+	}
+	if c.Breakpoints[0x204] || c.Watchpoints[0x301] {
+		t.Error("Expected the original breakpoints and watchpoints to be unchanged")
+	}
+	if _, ok := c.BreakConditions[0x202]; !ok {
+		t.Error("Expected the original break condition to survive")
+	}
+	if !c.protected[0x400] {
+		t.Error("Expected the original protection to survive")
+	}
+	if len(c.Trace()) != 0 || len(clone.Trace()) != 1 {
+		t.Errorf("Expected only the clone to trace its instruction, got %d and %d entries", len(c.Trace()), len(clone.Trace()))
+	}
+
+	c.Breakpoints[0x206] = true
+	if clone.Breakpoints[0x206] {
+		t.Error("Expected the clone's breakpoints to be unchanged by the original")
+	}
+}
+
+/*
+TestCopySetup checks that protected ranges and opcode hooks carry over to
+another CPU, and that changing them there leaves the original untouched.
+*/
+func TestCopySetup(t *testing.T) {
+	c := New()
+	c.ProtectRange(0x300, 0x300)
+	ran := false
+	c.RegisterOpcodeHandler(0xFFFF, 0x0123, func(c *Chip8, opcode uint16) { ran = true })
+
+	other := New()
+	other.CopySetup(c)
+	other.I = 0x300
+	// custom 0123 ; LD [I], V0
+	other.LoadROM([]byte{0x01, 0x23, 0xF0, 0x55})
+	other.IsRunning = true
+	other.EmulateCycle()
+	if !ran {
+		t.Error("Expected the opcode hook to carry over")
+	}
+	other.EmulateCycle()
+	if other.IsRunning || other.LastError == "" {
+		t.Error("Expected the write to the protected address to fault")
+	}
+
+	other.UnprotectRange(0x300, 0x300)
+	if !c.protected[0x300] {
+		t.Error("Expected the original protection to survive")
+	}
+}
@@ -0,0 +1,107 @@
+package chip8
+
+// Quirks selects between the behaviours that differ across CHIP-8 interpreters.
+// DefaultQuirks returns the set matching the original COSMAC VIP interpreter.
+type Quirks struct {
+	// AddByteSetsVF makes 7XNN (ADD Vx, byte) write the carry into VF. The
+	// original interpreter leaves VF untouched; only a handful of later
+	// interpreters set it, so this stays off unless a ROM depends on it.
+	AddByteSetsVF bool `json:"addByteSetsVF"`
+
+	// ShiftFlagLast controls when 8XY6/8XYE write VF. When off, VF receives the
+	// shifted-out bit before the shift is applied, so shifting VF itself shifts
+	// the flag. When on, the shift happens first and VF is written last, so VF
+	// always ends up holding the shifted-out bit of the original value. The two
+	// only disagree when the target register is VF.
+	ShiftFlagLast bool `json:"shiftFlagLast"`
+
+	// ShiftUsesVY makes 8XY6/8XYE shift VY and store the result in VX, as the
+	// COSMAC VIP did. When off, VX is shifted in place and VY is ignored, which
+	// is what CHIP-48 and SUPER-CHIP do.
+	ShiftUsesVY bool `json:"shiftUsesVY"`
+
+	// IncrementIOnStore makes FX55/FX65 leave I pointing past the last register
+	// stored or loaded (I += X + 1), as on the COSMAC VIP. When off, I is left
+	// unchanged, as on SUPER-CHIP.
+	IncrementIOnStore bool `json:"incrementIOnStore"`
+
+	// VFResetOnLogic makes 8XY1/8XY2/8XY3 (OR, AND, XOR) clear VF afterwards, a
+	// side effect of how the COSMAC VIP implemented them. Later interpreters
+	// leave VF alone.
+	VFResetOnLogic bool `json:"vfResetOnLogic"`
+
+	// LowResScrollFull makes the SUPER-CHIP scroll opcodes (00CN, 00FB, 00FC)
+	// move the full distance in low-res mode. SUPER-CHIP itself measures scroll
+	// distances in hi-res pixels, so in low-res mode they move half as far;
+	// XO-CHIP scrolls the full amount.
+	LowResScrollFull bool `json:"lowResScrollFull"`
+
+	// DisplayWait makes DXYN stall the CPU until the next vertical blank (the
+	// next UpdateTimers call), as the COSMAC VIP did, limiting draws to one per
+	// frame. It is off by default, since it slows down draw-heavy ROMs.
+	DisplayWait bool `json:"displayWait"`
+
+	// ClipSprites makes DXYN cut off sprites at the screen edge instead of
+	// wrapping them to the other side. The starting coordinate always wraps, so a
+	// sprite drawn at X=64 in low-res mode still starts at column 0. The COSMAC
+	// VIP and SUPER-CHIP clip; XO-CHIP wraps.
+	ClipSprites bool `json:"clipSprites"`
+
+	// JumpQuirk makes BNNN behave as BXNN: jump to XNN plus VX, where X is the
+	// high nibble of the address, as CHIP-48 and SUPER-CHIP did. When off, the
+	// offset comes from V0 as on the COSMAC VIP.
+	JumpQuirk bool `json:"jumpQuirk"`
+
+	// WaitKeyOnRelease makes FX0A finish only once the key it saw pressed is
+	// released, as on the COSMAC VIP, so a held key is read once rather than on
+	// every pass through a key-wait loop. When off, FX0A takes the first key
+	// found down, as most later interpreters do.
+	WaitKeyOnRelease bool `json:"waitKeyOnRelease"`
+
+	// LargeSpritesInLowRes makes DXY0 draw a 16x16 sprite in low-res mode as
+	// well as in hi-res mode, as SUPER-CHIP and XO-CHIP do. When off, low-res
+	// DXY0 keeps its original meaning and draws no rows.
+	LargeSpritesInLowRes bool `json:"largeSpritesInLowRes"`
+}
+
+// DefaultQuirks returns the quirk set of the original COSMAC VIP interpreter.
+func DefaultQuirks() Quirks {
+	return Quirks{
+		ShiftUsesVY:       true,
+		IncrementIOnStore: true,
+		VFResetOnLogic:    true,
+		ClipSprites:       true,
+	}
+}
+
+// QuirkPreset names a complete quirk set matching a well-known interpreter.
+type QuirkPreset string
+
+const (
+	PresetCOSMACVIP QuirkPreset = "cosmac-vip"
+	PresetSCHIP11   QuirkPreset = "schip-1.1"
+	PresetXOCHIP    QuirkPreset = "xo-chip"
+)
+
+// QuirkPresets lists the available presets.
+func QuirkPresets() []QuirkPreset {
+	return []QuirkPreset{PresetCOSMACVIP, PresetSCHIP11, PresetXOCHIP}
+}
+
+// Quirks returns the quirk set for the preset, or false if the name is unknown.
+// Unlike DefaultQuirks, the COSMAC VIP preset also waits for the display and for
+// key releases, as the real interpreter did.
+func (p QuirkPreset) Quirks() (Quirks, bool) {
+	switch p {
+	case PresetCOSMACVIP:
+		q := DefaultQuirks()
+		q.DisplayWait = true
+		q.WaitKeyOnRelease = true
+		return q, true
+	case PresetSCHIP11:
+		return MachineQuirks(MachineSCHIP), true
+	case PresetXOCHIP:
+		return MachineQuirks(MachineXOCHIP), true
+	}
+	return Quirks{}, false
+}
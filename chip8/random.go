@@ -0,0 +1,50 @@
+package chip8
+
+import (
+	"math/rand"
+	"time"
+)
+
+// WithSeed makes RND reproducible: every Reset seeds the generator with seed
+// instead of the current time.
+func WithSeed(seed int64) Option {
+	return func(c *Chip8) {
+		c.pinnedSeed = &seed
+	}
+}
+
+// SetSeed reseeds the RND generator now, so the values it produces from here on
+// are reproducible. Unless the CPU was built WithSeed, the next Reset goes back
+// to a time-based seed.
+func (c *Chip8) SetSeed(seed int64) {
+	c.RandSeed = seed
+	c.RandDraws = 0
+	c.rng = rand.New(rand.NewSource(seed))
+	c.rngSeed, c.rngDraws = seed, 0
+}
+
+// reseed seeds the RND generator for a reset.
+func (c *Chip8) reseed() {
+	if c.pinnedSeed != nil {
+		c.SetSeed(*c.pinnedSeed)
+		return
+	}
+	c.SetSeed(time.Now().UnixNano())
+}
+
+// randomByte draws the next value for RND. When the generator is missing (a
+// clone) or out of step with RandSeed and RandDraws (a restored save state), it
+// is rebuilt from RandSeed and advanced past the values already drawn, so the
+// same stream continues.
+func (c *Chip8) randomByte() byte {
+	if c.rng == nil || c.rngSeed != c.RandSeed || c.rngDraws != c.RandDraws {
+		c.rng = rand.New(rand.NewSource(c.RandSeed))
+		for i := uint64(0); i < c.RandDraws; i++ {
+			c.rng.Intn(256)
+		}
+		c.rngSeed, c.rngDraws = c.RandSeed, c.RandDraws
+	}
+	c.RandDraws++
+	c.rngDraws++
+	return byte(c.rng.Intn(256))
+}
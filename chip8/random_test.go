@@ -0,0 +1,118 @@
+package chip8
+
+import "testing"
+
+// rndProgram fills V0-V7 with RND results and then spins.
+var rndProgram = []byte{
+	0xC0, 0xFF, 0xC1, 0xFF, 0xC2, 0xFF, 0xC3, 0xFF,
+	0xC4, 0xFF, 0xC5, 0xFF, 0xC6, 0xFF, 0xC7, 0xFF,
+	0x12, 0x10,
+}
+
+// runRND runs rndProgram to completion and returns the registers it filled.
+func runRND(c *Chip8) [16]byte {
+	c.LoadROM(rndProgram)
+	c.IsRunning = true
+	for i := 0; i < 8; i++ {
+		c.EmulateCycle()
+	}
+	return c.Registers
+}
+
+/*
+TestSeededRNGIsReproducible checks that CPUs with the same seed produce the same
+RND values, that a pinned seed survives Reset, and that a different seed gives a
+different stream.
+*/
+func TestSeededRNGIsReproducible(t *testing.T) {
+	a := New(WithSeed(1234))
+	b := New(WithSeed(1234))
+	first := runRND(a)
+	if got := runRND(b); got != first {
+		t.Errorf("Expected identical seeds to give identical values, got %v and %v", first, got)
+	}
+
+	a.Reset()
+	if got := runRND(a); got != first {
+		t.Errorf("Expected Reset to reuse the pinned seed, got %v, want %v", got, first)
+	}
+
+	c := New()
+	c.SetSeed(1234)
+	if got := runRND(c); got != first {
+		t.Errorf("Expected SetSeed to match WithSeed, got %v, want %v", got, first)
+	}
+
+	d := New(WithSeed(99))
+	if got := runRND(d); got == first {
+		t.Errorf("Expected a different seed to give different values, got %v for both", got)
+	}
+}
+
+/*
+TestClonedRNGContinuesStream checks that a clone picks up the RND stream where
+the original left off and that drawing from one does not advance the other.
+*/
+func TestClonedRNGContinuesStream(t *testing.T) {
+	c := New(WithSeed(7))
+	c.LoadROM(rndProgram)
+	c.IsRunning = true
+	for i := 0; i < 4; i++ {
+		c.EmulateCycle()
+	}
+	clone := c.Clone()
+	for i := 0; i < 4; i++ {
+		c.EmulateCycle()
+	}
+	for i := 0; i < 4; i++ {
+		clone.EmulateCycle()
+	}
+	if clone.Registers != c.Registers {
+		t.Errorf("Expected the clone to continue the same stream, got %v, want %v", clone.Registers, c.Registers)
+	}
+	if c.RandDraws != 8 || clone.RandDraws != 8 {
+		t.Errorf("Expected 8 draws each, got %d and %d", c.RandDraws, clone.RandDraws)
+	}
+}
+
+/*
+TestRNGDistributionIsUniform draws many RND values from a fixed seed and checks
+them against a uniform distribution with a chi-squared test. With 255 degrees of
+freedom, 330.5 is the critical value at p = 0.001.
+*/
+func TestRNGDistributionIsUniform(t *testing.T) {
+	const draws = 256 * 1000
+	c := New(WithSeed(2024))
+	var counts [256]int
+	for i := 0; i < draws; i++ {
+		counts[c.randomByte()]++
+	}
+	expected := float64(draws) / 256
+	chi2 := 0.0
+	for _, n := range counts {
+		d := float64(n) - expected
+		chi2 += d * d / expected
+	}
+	if chi2 > 330.5 {
+		t.Errorf("Expected RND values to be uniform, got chi-squared %.1f", chi2)
+	}
+}
+
+/*
+TestRNDDoesNotAllocate checks that executing RND reuses the CPU's generator
+rather than building a new one each time.
+*/
+func TestRNDDoesNotAllocate(t *testing.T) {
+	c := New(WithSeed(1))
+	// RND V0, 0xFF ; JP 0x200
+	c.LoadROM([]byte{0xC0, 0xFF, 0x12, 0x00})
+	c.IsRunning = true
+	c.EmulateCycle()
+	c.EmulateCycle()
+	if allocs := testing.AllocsPerRun(100, func() {
+		c.EmulateCycle()
+		c.EmulateCycle()
+	}); allocs != 0 {
+		t.Errorf("Expected RND to run without allocating, got %.1f allocations per run", allocs)
+	}
+}
@@ -0,0 +1,106 @@
+package chip8
+
+import (
+	"strconv"
+	"strings"
+)
+
+// opcodeInfo documents one instruction. In Pattern, hex digits are fixed and
+// X, Y, N, NN and NNN mark the operand fields decode extracts.
+type opcodeInfo struct {
+	Pattern     string
+	Mnemonic    string
+	Description string
+}
+
+// opcodeTable lists every instruction the interpreter implements, in opcode
+// order. Where patterns overlap, the more specific one comes first.
+var opcodeTable = []opcodeInfo{
+	{"00CN", "SCD N", "Scroll the display down N lines (SUPER-CHIP)"},
+	{"00E0", "CLS", "Clear the display (the selected planes on XO-CHIP)"},
+	{"00EE", "RET", "Return from a subroutine"},
+	{"00FB", "SCR", "Scroll the display right 4 pixels (SUPER-CHIP)"},
+	{"00FC", "SCL", "Scroll the display left 4 pixels (SUPER-CHIP)"},
+	{"00FE", "LOW", "Switch to 64x32 low-resolution mode (SUPER-CHIP)"},
+	{"00FF", "HIGH", "Switch to 128x64 high-resolution mode (SUPER-CHIP)"},
+	{"0NNN", "SYS NNN", "Call a machine-code routine; ignored by modern interpreters"},
+	{"1NNN", "JP NNN", "Jump to address NNN"},
+	{"2NNN", "CALL NNN", "Call the subroutine at NNN"},
+	{"3XNN", "SE VX, NN", "Skip the next instruction if VX equals NN"},
+	{"4XNN", "SNE VX, NN", "Skip the next instruction if VX does not equal NN"},
+	{"5XY0", "SE VX, VY", "Skip the next instruction if VX equals VY"},
+	{"6XNN", "LD VX, NN", "Set VX to NN"},
+	{"7XNN", "ADD VX, NN", "Add NN to VX without setting the carry flag"},
+	{"8XY0", "LD VX, VY", "Set VX to VY"},
+	{"8XY1", "OR VX, VY", "Set VX to VX OR VY"},
+	{"8XY2", "AND VX, VY", "Set VX to VX AND VY"},
+	{"8XY3", "XOR VX, VY", "Set VX to VX XOR VY"},
+	{"8XY4", "ADD VX, VY", "Add VY to VX; VF is set to the carry"},
+	{"8XY5", "SUB VX, VY", "Subtract VY from VX; VF is set to 1 if there was no borrow"},
+	{"8XY6", "SHR VX", "Shift right by one; VF is set to the bit shifted out"},
+	{"8XY7", "SUBN VX, VY", "Set VX to VY minus VX; VF is set to 1 if there was no borrow"},
+	{"8XYE", "SHL VX", "Shift left by one; VF is set to the bit shifted out"},
+	{"9XY0", "SNE VX, VY", "Skip the next instruction if VX does not equal VY"},
+	{"ANNN", "LD I, NNN", "Set I to NNN"},
+	{"BNNN", "JP V0, NNN", "Jump to NNN plus V0"},
+	{"CXNN", "RND VX, NN", "Set VX to a random byte ANDed with NN"},
+	{"DXYN", "DRW VX, VY, N", "Draw an N-row sprite from I at (VX, VY); VF is set on collision"},
+	{"EX9E", "SKP VX", "Skip the next instruction if the key in VX is pressed"},
+	{"EXA1", "SKNP VX", "Skip the next instruction if the key in VX is not pressed"},
+	{"F000", "LD I, NNNN", "Set I to the 16-bit address in the next word (XO-CHIP)"},
+	{"FX01", "PLANE X", "Select the bit planes that drawing affects (XO-CHIP)"},
+	{"F002", "AUDIO", "Load the 16-byte audio pattern from I (XO-CHIP)"},
+	{"FX07", "LD VX, DT", "Set VX to the delay timer"},
+	{"FX0A", "LD VX, K", "Wait for a key press and store the key in VX"},
+	{"FX15", "LD DT, VX", "Set the delay timer to VX"},
+	{"FX18", "LD ST, VX", "Set the sound timer to VX"},
+	{"FX1E", "ADD I, VX", "Add VX to I"},
+	{"FX29", "LD F, VX", "Point I at the font sprite for the digit in VX"},
+	{"FX33", "LD B, VX", "Store the decimal digits of VX at I, I+1 and I+2"},
+	{"FX3A", "PITCH VX", "Set the audio pattern pitch to VX (XO-CHIP)"},
+	{"FX55", "LD [I], VX", "Store V0 to VX in memory starting at I"},
+	{"FX65", "LD VX, [I]", "Load V0 to VX from memory starting at I"},
+}
+
+// OpcodeReference returns the instruction set reference: for each implemented
+// opcode, its "pattern" (e.g. "8XY4"), "mnemonic" and a one-line "description".
+func OpcodeReference() []map[string]string {
+	ref := make([]map[string]string, len(opcodeTable))
+	for i, op := range opcodeTable {
+		ref[i] = map[string]string{
+			"pattern":     op.Pattern,
+			"mnemonic":    op.Mnemonic,
+			"description": op.Description,
+		}
+	}
+	return ref
+}
+
+// describe returns the reference description of opcode, from the first table
+// entry whose pattern it matches.
+func describe(opcode uint16) string {
+	if strings.HasPrefix(Disassemble(opcode), "UNKNOWN") {
+		return "Unknown instruction"
+	}
+	for _, op := range opcodeTable {
+		if matchesPattern(op.Pattern, opcode) {
+			return op.Description
+		}
+	}
+	return "Unknown instruction"
+}
+
+// matchesPattern reports whether opcode fits a reference pattern such as "8XY4".
+// Hex digits must match; the operand letters X, Y and N match anything.
+func matchesPattern(pattern string, opcode uint16) bool {
+	for i := 0; i < 4; i++ {
+		digit, err := strconv.ParseUint(pattern[i:i+1], 16, 8)
+		if err != nil {
+			continue
+		}
+		if uint16(digit) != (opcode>>(12-4*i))&0xF {
+			return false
+		}
+	}
+	return true
+}
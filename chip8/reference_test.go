@@ -0,0 +1,54 @@
+package chip8
+
+import (
+	"strings"
+	"testing"
+)
+
+/*
+TestOpcodeReferenceCoversImplemented checks every opcode the disassembler
+recognises against the reference, so that a new instruction cannot be added
+without documenting it, and that every entry has a description.
+*/
+func TestOpcodeReferenceCoversImplemented(t *testing.T) {
+	ref := OpcodeReference()
+	for _, entry := range ref {
+		if entry["pattern"] == "" || entry["mnemonic"] == "" || entry["description"] == "" {
+			t.Errorf("Expected a complete entry, got %v", entry)
+		}
+	}
+	for op := 0; op <= 0xFFFF; op++ {
+		opcode := uint16(op)
+		if strings.HasPrefix(Disassemble(opcode), "UNKNOWN") {
+			continue
+		}
+		found := false
+		for _, entry := range ref {
+			if matchesPattern(entry["pattern"], opcode) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Fatalf("Expected a reference entry for %04X (%s)", opcode, Disassemble(opcode))
+		}
+	}
+}
+
+/*
+TestDescribe checks that opcodes get the description of the most specific
+matching entry, and that unknown opcodes are reported as such.
+*/
+func TestDescribe(t *testing.T) {
+	tests := map[uint16]string{
+		0x00E0: "Clear the display (the selected planes on XO-CHIP)",
+		0x0123: "Call a machine-code routine; ignored by modern interpreters",
+		0x8AB4: "Add VY to VX; VF is set to the carry",
+		0x5121: "Unknown instruction",
+	}
+	for opcode, want := range tests {
+		if got := describe(opcode); got != want {
+			t.Errorf("%04X: expected %q, got %q", opcode, want, got)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package chip8
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// TraceCapacity is the number of executed instructions the trace keeps.
+const TraceCapacity = 1024
+
+// TraceEntry records one executed instruction.
+type TraceEntry struct {
+	Cycle    uint64 `json:"cycle"`
+	PC       uint16 `json:"pc"`
+	Opcode   uint16 `json:"opcode"`
+	Mnemonic string `json:"mnemonic"`
+}
+
+// traceBuffer is a fixed-size ring of the most recent trace entries.
+type traceBuffer struct {
+	entries []TraceEntry
+	next    int
+	full    bool
+}
+
+func (t *traceBuffer) add(e TraceEntry) {
+	t.entries[t.next] = e
+	t.next++
+	if t.next == len(t.entries) {
+		t.next = 0
+		t.full = true
+	}
+}
+
+// ordered returns the buffered entries oldest first.
+func (t *traceBuffer) ordered() []TraceEntry {
+	if !t.full {
+		return append([]TraceEntry(nil), t.entries[:t.next]...)
+	}
+	out := make([]TraceEntry, 0, len(t.entries))
+	out = append(out, t.entries[t.next:]...)
+	return append(out, t.entries[:t.next]...)
+}
+
+// SetTracing turns instruction tracing on or off. Turning it on starts an empty
+// trace; turning it off discards the trace.
+func (c *Chip8) SetTracing(enabled bool) {
+	if enabled {
+		c.trace = &traceBuffer{entries: make([]TraceEntry, TraceCapacity)}
+	} else {
+		c.trace = nil
+	}
+}
+
+// Trace returns the most recent executed instructions, oldest first, or nil when
+// tracing is off.
+func (c *Chip8) Trace() []TraceEntry {
+	if c.trace == nil {
+		return nil
+	}
+	return c.trace.ordered()
+}
+
+// LoadTrace turns tracing on with entries as the trace so far, e.g. when
+// restoring a save state. Only the most recent TraceCapacity entries are kept.
+func (c *Chip8) LoadTrace(entries []TraceEntry) {
+	c.SetTracing(true)
+	if len(entries) > TraceCapacity {
+		entries = entries[len(entries)-TraceCapacity:]
+	}
+	for _, e := range entries {
+		c.trace.add(e)
+	}
+}
+
+// FormatTraceCSV renders entries as CSV with a cycle,pc,opcode,mnemonic header.
+// PC and opcode are written as 0x-prefixed hex.
+func FormatTraceCSV(entries []TraceEntry) []byte {
+	var sb strings.Builder
+	w := csv.NewWriter(&sb)
+	w.Write([]string{"cycle", "pc", "opcode", "mnemonic"})
+	for _, e := range entries {
+		w.Write([]string{
+			fmt.Sprintf("%d", e.Cycle),
+			fmt.Sprintf("0x%04X", e.PC),
+			fmt.Sprintf("0x%04X", e.Opcode),
+			e.Mnemonic,
+		})
+	}
+	w.Flush()
+	return []byte(sb.String())
+}
+
+// FormatTraceJSONL renders entries as JSON lines, one object per instruction.
+func FormatTraceJSONL(entries []TraceEntry) ([]byte, error) {
+	var sb strings.Builder
+	enc := json.NewEncoder(&sb)
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			return nil, err
+		}
+	}
+	return []byte(sb.String()), nil
+}
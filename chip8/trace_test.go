@@ -0,0 +1,87 @@
+package chip8
+
+import "testing"
+
+var sampleTrace = []TraceEntry{
+	{Cycle: 1, PC: 0x200, Opcode: 0x00E0, Mnemonic: "CLS"},
+	{Cycle: 2, PC: 0x202, Opcode: 0x6A2B, Mnemonic: "LD VA, 0x2B"},
+}
+
+/*
+TestFormatTraceCSV checks the CSV header and rows, including quoting of the
+comma inside a mnemonic.
+*/
+func TestFormatTraceCSV(t *testing.T) {
+	want := "cycle,pc,opcode,mnemonic\n" +
+		"1,0x0200,0x00E0,CLS\n" +
+		"2,0x0202,0x6A2B,\"LD VA, 0x2B\"\n"
+	if got := string(FormatTraceCSV(sampleTrace)); got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+/*
+TestFormatTraceJSONL checks that each entry becomes one JSON object per line.
+*/
+func TestFormatTraceJSONL(t *testing.T) {
+	want := `{"cycle":1,"pc":512,"opcode":224,"mnemonic":"CLS"}` + "\n" +
+		`{"cycle":2,"pc":514,"opcode":27179,"mnemonic":"LD VA, 0x2B"}` + "\n"
+	got, err := FormatTraceJSONL(sampleTrace)
+	if err != nil {
+		t.Fatalf("FormatTraceJSONL failed: %v", err)
+	}
+	if string(got) != want {
+		t.Errorf("Expected %q, got %q", want, string(got))
+	}
+}
+
+/*
+TestTraceRecordsExecution checks that executed instructions are traced oldest
+first and that the ring keeps only the most recent TraceCapacity entries.
+*/
+func TestTraceRecordsExecution(t *testing.T) {
+	c := New()
+	c.SetTracing(true)
+	copy(c.Memory[ProgramStart:], []byte{0x12, 0x00}) // JP 0x200
+	c.IsRunning = true
+	for i := 0; i < TraceCapacity+5; i++ {
+		c.EmulateCycle()
+	}
+
+	trace := c.Trace()
+	if len(trace) != TraceCapacity {
+		t.Fatalf("Expected %d entries, got %d", TraceCapacity, len(trace))
+	}
+	if trace[0].Cycle != 6 || trace[len(trace)-1].Cycle != TraceCapacity+5 {
+		t.Errorf("Expected cycles 6..%d, got %d..%d", TraceCapacity+5, trace[0].Cycle, trace[len(trace)-1].Cycle)
+	}
+	if trace[0].PC != ProgramStart || trace[0].Mnemonic != "JP 0x200" {
+		t.Errorf("Unexpected entry: %+v", trace[0])
+	}
+}
+
+/*
+TestLoadTrace checks that a loaded trace turns tracing on, keeps only the newest
+TraceCapacity entries, and is extended by further execution.
+*/
+func TestLoadTrace(t *testing.T) {
+	entries := make([]TraceEntry, TraceCapacity+10)
+	for i := range entries {
+		entries[i].Cycle = uint64(i + 1)
+	}
+	c := New()
+	c.LoadTrace(entries)
+	trace := c.Trace()
+	if len(trace) != TraceCapacity || trace[0].Cycle != 11 {
+		t.Fatalf("Expected the newest %d entries starting at cycle 11, got %d from %d", TraceCapacity, len(trace), trace[0].Cycle)
+	}
+
+	c = New()
+	c.LoadTrace(entries[:2])
+	copy(c.Memory[ProgramStart:], []byte{0x60, 0x01})
+	c.IsRunning = true
+	c.EmulateCycle()
+	if trace := c.Trace(); len(trace) != 3 || trace[2].Mnemonic != "LD V0, 0x01" {
+		t.Errorf("Expected execution to append to the loaded trace, got %+v", trace)
+	}
+}
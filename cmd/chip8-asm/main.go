@@ -0,0 +1,71 @@
+// Command chip8-asm assembles a CHIP-8 assembly source file into a .ch8 ROM.
+//
+// Usage:
+//
+//	chip8-asm [-o out.ch8] program.asm
+//
+// When -o is omitted the ROM is written next to the source with a .ch8
+// extension. Errors are reported as "file:line: message".
+package main
+
+import (
+	"chip8-wails/internal/asm"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run executes the tool and returns the process exit code.
+func run(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("chip8-asm", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	output := fs.String("o", "", "output ROM path (default: source name with .ch8 extension)")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: chip8-asm [-o out.ch8] program.asm")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	input := fs.Arg(0)
+	source, err := os.ReadFile(input)
+	if err != nil {
+		fmt.Fprintf(stderr, "chip8-asm: %v\n", err)
+		return 1
+	}
+
+	rom, err := asm.Assemble(string(source))
+	if err != nil {
+		var asmErr *asm.Error
+		if errors.As(err, &asmErr) {
+			fmt.Fprintf(stderr, "%s:%d: %s\n", input, asmErr.Line, asmErr.Msg)
+		} else {
+			fmt.Fprintf(stderr, "%s: %v\n", input, err)
+		}
+		return 1
+	}
+
+	out := *output
+	if out == "" {
+		out = strings.TrimSuffix(input, filepath.Ext(input)) + ".ch8"
+	}
+	if err := os.WriteFile(out, rom, 0644); err != nil {
+		fmt.Fprintf(stderr, "chip8-asm: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(stdout, "Wrote %d bytes to %s\n", len(rom), out)
+	return 0
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+/*
+TestRunAssemblesSource checks that the tool writes the expected ROM bytes for
+a small program using labels, a comment and a db directive.
+*/
+func TestRunAssemblesSource(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "prog.asm")
+	out := filepath.Join(dir, "out.ch8")
+	program := `; draw a sprite forever
+start:
+	CLS
+	LD I, sprite
+	LD V0, 0x0A
+	DRW V0, V0, 2
+loop: JP loop
+sprite:
+	db 0xFF, 0x81
+`
+	if err := os.WriteFile(src, []byte(program), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"-o", out, src}, &stdout, &stderr); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d (stderr: %s)", code, stderr.String())
+	}
+
+	got, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{
+		0x00, 0xE0, // CLS
+		0xA2, 0x0A, // LD I, sprite
+		0x60, 0x0A, // LD V0, 0x0A
+		0xD0, 0x02, // DRW V0, V0, 2
+		0x12, 0x08, // JP loop
+		0xFF, 0x81, // sprite
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("Expected % X, got % X", want, got)
+	}
+}
+
+/*
+TestRunReportsLineNumber checks that assembly errors name the file and line.
+*/
+func TestRunReportsLineNumber(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "bad.asm")
+	if err := os.WriteFile(src, []byte("CLS\nJP nowhere\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{src}, &stdout, &stderr); code != 1 {
+		t.Errorf("Expected exit code 1, got %d", code)
+	}
+	if want := src + ":2:"; !strings.Contains(stderr.String(), want) {
+		t.Errorf("Expected stderr to contain %q, got %q", want, stderr.String())
+	}
+}
@@ -0,0 +1,215 @@
+// Command chip8headless runs a CHIP-8 ROM without the GUI, for testing ROMs in CI.
+//
+// Usage:
+//
+//	chip8headless [-cycles n] [-ipf n] [-machine chip8|schip|xochip] [-quirks list] [-expect file] rom.ch8
+//
+// The ROM runs for the given number of instructions, ticking the timers once
+// every -ipf instructions as the 60Hz frame loop would. The final display is
+// printed as an ASCII grid ('.' off, '#' on; XO-CHIP's second plane shows as
+// '+' and both planes as '@'), followed by the CPU state as JSON. With -expect,
+// the grid is compared against the file and the exit code is 1 on a mismatch,
+// so test ROMs that draw a pass/fail screen can be used as regression tests.
+package main
+
+import (
+	"bytes"
+	"chip8-wails/chip8"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run executes the tool and returns the process exit code.
+func run(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("chip8headless", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	cycles := fs.Int("cycles", 1000, "number of instructions to execute")
+	ipf := fs.Int("ipf", 12, "instructions per 60Hz frame, i.e. per timer tick")
+	machine := fs.String("machine", string(chip8.MachineCHIP8), "platform whose quirks and memory size to use: chip8, schip or xochip")
+	quirks := fs.String("quirks", "", "comma-separated quirks to set on top of the platform's, e.g. displayWait,!shiftUsesVY")
+	expect := fs.String("expect", "", "file holding the expected ASCII display; exit 1 if it differs")
+	seed := fs.Int64("seed", 0, "seed for RND, so ROMs using random numbers give repeatable output; 0 seeds from the clock")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: chip8headless [flags] rom.ch8")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 || *ipf < 1 {
+		fs.Usage()
+		return 2
+	}
+	m := chip8.Machine(*machine)
+	if !m.Valid() {
+		fmt.Fprintf(stderr, "chip8headless: unknown machine %q\n", *machine)
+		return 2
+	}
+	q, err := applyQuirks(chip8.MachineQuirks(m), *quirks)
+	if err != nil {
+		fmt.Fprintf(stderr, "chip8headless: %v\n", err)
+		return 2
+	}
+
+	rom, err := os.ReadFile(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(stderr, "chip8headless: %v\n", err)
+		return 1
+	}
+	var opts []chip8.Option
+	if m == chip8.MachineXOCHIP {
+		opts = append(opts, chip8.WithMemorySize(chip8.XOCHIPMemorySize))
+	}
+	if *seed != 0 {
+		opts = append(opts, chip8.WithSeed(*seed))
+	}
+	cpu := chip8.New(opts...)
+	cpu.Quirks = q
+	if err := cpu.LoadROM(rom); err != nil {
+		fmt.Fprintf(stderr, "chip8headless: %v\n", err)
+		return 1
+	}
+	cpu.IsRunning = true
+	for i := 0; i < *cycles && cpu.IsRunning; i++ {
+		cpu.EmulateCycle()
+		if (i+1)%*ipf == 0 {
+			cpu.UpdateTimers()
+		}
+	}
+
+	grid := renderASCII(cpu)
+	fmt.Fprint(stdout, grid)
+	state, err := json.MarshalIndent(dumpState(cpu), "", "  ")
+	if err != nil {
+		fmt.Fprintf(stderr, "chip8headless: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(stdout, "%s\n", state)
+
+	if *expect != "" {
+		want, err := os.ReadFile(*expect)
+		if err != nil {
+			fmt.Fprintf(stderr, "chip8headless: %v\n", err)
+			return 1
+		}
+		if diff := diffLines(string(want), grid); diff != "" {
+			fmt.Fprintf(stderr, "display does not match %s:\n%s", *expect, diff)
+			return 1
+		}
+	}
+	return 0
+}
+
+// applyQuirks sets or clears the named quirks (by their JSON names) on q. A name
+// prefixed with '!' clears the quirk.
+func applyQuirks(q chip8.Quirks, list string) (chip8.Quirks, error) {
+	if strings.TrimSpace(list) == "" {
+		return q, nil
+	}
+	data, err := json.Marshal(q)
+	if err != nil {
+		return q, err
+	}
+	fields := map[string]bool{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return q, err
+	}
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		value := !strings.HasPrefix(name, "!")
+		name = strings.TrimPrefix(name, "!")
+		if _, ok := fields[name]; !ok {
+			return q, fmt.Errorf("unknown quirk %q", name)
+		}
+		fields[name] = value
+	}
+	data, err = json.Marshal(fields)
+	if err != nil {
+		return q, err
+	}
+	err = json.Unmarshal(data, &q)
+	return q, err
+}
+
+// renderASCII draws the active display, one text line per pixel row.
+func renderASCII(cpu *chip8.Chip8) string {
+	const glyphs = ".#+@"
+	var b strings.Builder
+	pixels := cpu.Pixels()
+	width := cpu.Width()
+	for y := 0; y < cpu.Height(); y++ {
+		for _, p := range pixels[y*width : (y+1)*width] {
+			b.WriteByte(glyphs[p&3])
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// machineState is the CPU state printed after the run.
+type machineState struct {
+	PC         uint16   `json:"pc"`
+	I          uint16   `json:"i"`
+	SP         byte     `json:"sp"`
+	V          []int    `json:"v"`
+	Stack      []uint16 `json:"stack"`
+	DelayTimer byte     `json:"delayTimer"`
+	SoundTimer byte     `json:"soundTimer"`
+	Cycles     uint64   `json:"cycles"`
+	HiRes      bool     `json:"hiRes"`
+	Halted     bool     `json:"halted"`
+	LastError  string   `json:"lastError,omitempty"`
+	Memory     string   `json:"memory"` // Hex dump of all of memory
+}
+
+// dumpState collects the state printed after the run.
+func dumpState(cpu *chip8.Chip8) machineState {
+	v := make([]int, len(cpu.Registers))
+	for i, r := range cpu.Registers {
+		v[i] = int(r)
+	}
+	return machineState{
+		PC:         cpu.PC,
+		I:          cpu.I,
+		SP:         cpu.SP,
+		V:          v,
+		Stack:      append([]uint16(nil), cpu.Stack[:cpu.SP]...),
+		DelayTimer: cpu.DelayTimer,
+		SoundTimer: cpu.SoundTimer,
+		Cycles:     cpu.CycleCount,
+		HiRes:      cpu.HiRes,
+		Halted:     !cpu.IsRunning,
+		LastError:  cpu.LastError,
+		Memory:     hex.EncodeToString(cpu.Memory[:]),
+	}
+}
+
+// diffLines compares two texts line by line, ignoring trailing whitespace and a
+// final newline, and describes each differing line. It returns "" if they match.
+func diffLines(want, got string) string {
+	wantLines := strings.Split(strings.TrimRight(want, "\r\n"), "\n")
+	gotLines := strings.Split(strings.TrimRight(got, "\r\n"), "\n")
+	var b bytes.Buffer
+	for i := 0; i < len(wantLines) || i < len(gotLines); i++ {
+		var w, g string
+		if i < len(wantLines) {
+			w = strings.TrimRight(wantLines[i], " \t\r")
+		}
+		if i < len(gotLines) {
+			g = strings.TrimRight(gotLines[i], " \t\r")
+		}
+		if w != g {
+			fmt.Fprintf(&b, "line %d:\n  want %s\n  got  %s\n", i+1, w, g)
+		}
+	}
+	return b.String()
+}
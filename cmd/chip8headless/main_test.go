@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"chip8-wails/chip8"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeROM writes rom to a temporary file and returns its path.
+func writeROM(t *testing.T, rom []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.ch8")
+	if err := os.WriteFile(path, rom, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// drawROM draws the font sprite for 0 at the top left, then loops forever.
+var drawROM = []byte{
+	0x60, 0x00, // LD V0, 0
+	0xF0, 0x29, // LD F, V0
+	0xD0, 0x05, // DRW V0, V0, 5
+	0x12, 0x06, // JP 0x206
+}
+
+/*
+TestRunPrintsDisplayAndState runs a ROM that draws a digit and checks the ASCII
+grid and the JSON state on stdout.
+*/
+func TestRunPrintsDisplayAndState(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"-cycles", "10", writeROM(t, drawROM)}, &stdout, &stderr); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d (stderr: %s)", code, stderr.String())
+	}
+	lines := strings.Split(stdout.String(), "\n")
+	if !strings.HasPrefix(lines[0], "####.") || !strings.HasPrefix(lines[1], "#..#.") {
+		t.Errorf("Expected the 0 glyph at the top left, got %q / %q", lines[0], lines[1])
+	}
+	if len(lines[0]) != chip8.DisplayWidth {
+		t.Errorf("Expected %d columns, got %d", chip8.DisplayWidth, len(lines[0]))
+	}
+	if out := stdout.String(); !strings.Contains(out, `"pc": 518`) || !strings.Contains(out, `"cycles": 10`) {
+		t.Errorf("Expected the final PC and cycle count in the JSON state, got:\n%s", out[len(out)-400:])
+	}
+}
+
+/*
+TestRunExpectedOutput checks that -expect passes on a matching display and fails
+with exit code 1 on a mismatch.
+*/
+func TestRunExpectedOutput(t *testing.T) {
+	rom := writeROM(t, drawROM)
+	var first, stderr bytes.Buffer
+	run([]string{"-cycles", "10", rom}, &first, &stderr)
+	grid := first.String()[:strings.Index(first.String(), "{")]
+
+	expected := filepath.Join(t.TempDir(), "expected.txt")
+	os.WriteFile(expected, []byte(grid), 0644)
+	var stdout bytes.Buffer
+	if code := run([]string{"-cycles", "10", "-expect", expected, rom}, &stdout, &stderr); code != 0 {
+		t.Errorf("Expected a matching display to pass, got exit code %d (stderr: %s)", code, stderr.String())
+	}
+
+	os.WriteFile(expected, []byte(strings.Replace(grid, "####", "....", 1)), 0644)
+	stderr.Reset()
+	if code := run([]string{"-cycles", "10", "-expect", expected, rom}, &stdout, &stderr); code != 1 {
+		t.Errorf("Expected a differing display to fail with exit code 1, got %d", code)
+	}
+	if !strings.Contains(stderr.String(), "line 1:") {
+		t.Errorf("Expected the differing line to be reported, got %q", stderr.String())
+	}
+}
+
+/*
+TestRunSeedIsRepeatable checks that two runs with the same -seed draw the same
+random sprite positions and print identical output.
+*/
+func TestRunSeedIsRepeatable(t *testing.T) {
+	// RND V0, 0x3F ; RND V1, 0x1F ; LD F, V0 ; DRW V0, V1, 5 ; JP 0x200
+	rom := writeROM(t, []byte{0xC0, 0x3F, 0xC1, 0x1F, 0xF0, 0x29, 0xD0, 0x15, 0x12, 0x00})
+	var first, second, stderr bytes.Buffer
+	if code := run([]string{"-cycles", "50", "-seed", "1234", rom}, &first, &stderr); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d (stderr: %s)", code, stderr.String())
+	}
+	run([]string{"-cycles", "50", "-seed", "1234", rom}, &second, &stderr)
+	if first.String() != second.String() {
+		t.Error("Expected identical output for identical seeds")
+	}
+}
+
+/*
+TestApplyQuirks checks setting and clearing quirks by name, and rejecting
+unknown names.
+*/
+func TestApplyQuirks(t *testing.T) {
+	q, err := applyQuirks(chip8.DefaultQuirks(), "displayWait, !shiftUsesVY")
+	if err != nil {
+		t.Fatalf("applyQuirks failed: %v", err)
+	}
+	if !q.DisplayWait || q.ShiftUsesVY || !q.IncrementIOnStore {
+		t.Errorf("Expected displayWait set and shiftUsesVY cleared, got %+v", q)
+	}
+	if _, err := applyQuirks(q, "noSuchQuirk"); err == nil {
+		t.Error("Expected an error for an unknown quirk")
+	}
+}
@@ -0,0 +1,26 @@
+package main
+
+/*
+clearCoalescer reduces flicker from ROMs that clear the screen and then redraw it.
+When the newest display change is a bare clear, the update is held back for one
+frame so that redraws landing in the next frame are sent together with the clear
+instead of as a blank frame followed by the redrawn one.
+*/
+type clearCoalescer struct {
+	enabled bool
+	held    bool
+}
+
+/*
+hold reports whether this frame's display update should wait. screenCleared says
+whether the display currently holds an undrawn clear. An update is never held for
+more than one frame.
+*/
+func (cc *clearCoalescer) hold(screenCleared bool) bool {
+	if !cc.enabled || !screenCleared || cc.held {
+		cc.held = false
+		return false
+	}
+	cc.held = true
+	return true
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"chip8-wails/chip8"
+	"testing"
+	"time"
+)
+
+/*
+TestClearThenDrawEmitsOnce runs CLS, lets a frame boundary pass, then runs DRW, and
+checks that with coalescing enabled only one display update is emitted for the pair.
+*/
+func TestClearThenDrawEmitsOnce(t *testing.T) {
+	a := NewApp()
+	a.clearCoalescer.enabled = true
+	if err := a.cpu.LoadROM([]byte{0x00, 0xE0, 0xD0, 0x15}); err != nil {
+		t.Fatalf("LoadROM failed: %v", err)
+	}
+	a.cpu.I = chip8.FontSetStart
+	a.cpu.IsRunning = true
+
+	now := time.Unix(0, 0)
+	emitted := 0
+	frame := func() {
+		now = now.Add(20 * time.Millisecond)
+		if _, ok := a.pollDisplay(now); ok {
+			emitted++
+		}
+	}
+
+	a.runCycle(&timerSpreader{}) // CLS
+	frame()
+	if emitted != 0 {
+		t.Fatalf("Expected the bare clear to be held back, got %d updates", emitted)
+	}
+	a.runCycle(&timerSpreader{}) // DRW
+	frame()
+	frame()
+
+	if emitted != 1 {
+		t.Errorf("Expected exactly 1 display update for clear+draw, got %d", emitted)
+	}
+}
+
+/*
+TestClearAloneIsEmittedNextFrame checks that a clear with no following draw is held
+for only one frame, and that with coalescing disabled it is emitted immediately.
+*/
+func TestClearAloneIsEmittedNextFrame(t *testing.T) {
+	for _, enabled := range []bool{true, false} {
+		a := NewApp()
+		a.clearCoalescer.enabled = enabled
+		if err := a.cpu.LoadROM([]byte{0x00, 0xE0}); err != nil {
+			t.Fatalf("LoadROM failed: %v", err)
+		}
+		a.cpu.IsRunning = true
+		a.runCycle(&timerSpreader{})
+
+		now := time.Unix(0, 0)
+		_, first := a.pollDisplay(now.Add(20 * time.Millisecond))
+		_, second := a.pollDisplay(now.Add(40 * time.Millisecond))
+
+		if enabled && (first || !second) {
+			t.Errorf("enabled: expected update on second frame only, got first=%v second=%v", first, second)
+		}
+		if !enabled && (!first || second) {
+			t.Errorf("disabled: expected update on first frame only, got first=%v second=%v", first, second)
+		}
+	}
+}
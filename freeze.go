@@ -0,0 +1,45 @@
+package main
+
+// frozenDisplayFrames is how many consecutive running frames (10s at 60Hz) must
+// show an identical picture before the display is reported as possibly frozen.
+// It is deliberately long because plenty of ROMs sit on a static screen.
+const frozenDisplayFrames = 600
+
+/*
+freezeDetector counts consecutive frames with an unchanged display hash. It is
+advisory only: a static screen may be perfectly legitimate.
+*/
+type freezeDetector struct {
+	threshold int
+	lastHash  uint64
+	unchanged int
+	reported  bool
+}
+
+/*
+observe records this frame's display hash and returns true exactly once each time
+the unchanged-frame count reaches the threshold.
+*/
+func (f *freezeDetector) observe(hash uint64) bool {
+	if hash != f.lastHash {
+		f.lastHash = hash
+		f.unchanged = 0
+		f.reported = false
+		return false
+	}
+	f.unchanged++
+	if f.unchanged >= f.threshold && !f.reported {
+		f.reported = true
+		return true
+	}
+	return false
+}
+
+/*
+reset forgets the frame history, e.g. after a new ROM is loaded.
+*/
+func (f *freezeDetector) reset() {
+	f.lastHash = 0
+	f.unchanged = 0
+	f.reported = false
+}
@@ -0,0 +1,37 @@
+package main
+
+import "testing"
+
+/*
+TestFreezeDetectorCountsUnchangedFrames feeds a run of identical hashes followed
+by a different one and checks when the detector fires and that a change resets it.
+*/
+func TestFreezeDetectorCountsUnchangedFrames(t *testing.T) {
+	f := &freezeDetector{threshold: 3}
+
+	if f.observe(0xAA) {
+		t.Fatal("Expected first hash not to count as frozen")
+	}
+	results := []bool{f.observe(0xAA), f.observe(0xAA), f.observe(0xAA), f.observe(0xAA)}
+	want := []bool{false, false, true, false}
+	for i := range want {
+		if results[i] != want[i] {
+			t.Errorf("Frame %d: expected %v, got %v", i, want[i], results[i])
+		}
+	}
+	if f.unchanged != 4 {
+		t.Errorf("Expected 4 unchanged frames, got %d", f.unchanged)
+	}
+
+	if f.observe(0xBB) {
+		t.Error("Expected a changed hash not to report frozen")
+	}
+	if f.unchanged != 0 {
+		t.Errorf("Expected counter to reset on change, got %d", f.unchanged)
+	}
+	f.observe(0xBB)
+	f.observe(0xBB)
+	if !f.observe(0xBB) {
+		t.Error("Expected detector to fire again after a fresh run of identical frames")
+	}
+}
@@ -29,15 +29,18 @@ export function showNotification(message, type = "info", duration = 3000) {
  * @type {{
  *   clockSpeed: number,
  *   displayColor: string,
+ *   palette: string[],
  *   scanlineEffect: boolean,
  *   pixelScale: number,
  *   romsPath: string,
- *   keyMap: Record<string|number, number>
+ *   keyMap: Record<string|number, number>,
+ *   gamepadMap: Record<string, number>
  * }}
  */
 const defaultSettings = {
   clockSpeed: 700,
   displayColor: "#33FF00",
+  palette: ["#000000", "#33FF00", "#FF5500", "#FFFFFF"],
   scanlineEffect: false,
   pixelScale: 10,
   romsPath: "./roms",
@@ -59,6 +62,12 @@ const defaultSettings = {
     c: 0xb,
     v: 0xf,
   },
+  gamepadMap: {
+    up: 0x5, left: 0x7, down: 0x8, right: 0x9,
+    a: 0x6, b: 0x4, x: 0x1, y: 0x2,
+    lb: 0x3, rb: 0xc, lt: 0xd, rt: 0xe,
+    select: 0xa, start: 0xf, ls: 0x0, rs: 0xb,
+  },
 };
 
 /**
@@ -0,0 +1,450 @@
+package asm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Origin is the address the first assembled byte is loaded at.
+const Origin = 0x200
+
+// maxAddress is the highest address a CHIP-8 program can occupy.
+const maxAddress = 0xFFF
+
+// Error is an assembly error tied to a source line.
+type Error struct {
+	Line int
+	Msg  string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+}
+
+// statement is one instruction or directive from the source.
+type statement struct {
+	line     int
+	mnemonic string
+	operands []string
+	addr     uint16
+}
+
+/*
+Assemble translates CHIP-8 assembly into a ROM image loaded at Origin.
+
+The syntax matches the output of chip8.Disassemble (e.g. "LD V1, 0x2A",
+"DRW V0, V1, 5"). In addition:
+  - a label is an identifier followed by ':', either alone or before an
+    instruction, and can be used wherever an address is expected;
+  - "db" emits the listed bytes verbatim;
+  - "LD I, long ADDR" emits the XO-CHIP F000 long load followed by the 16-bit
+    address; a bare "LD I, long", as Disassemble prints it, emits only F000 and
+    leaves the address to the next line;
+  - ';' starts a comment that runs to the end of the line.
+
+Numbers may be decimal, 0x hex, 0b binary or 0o octal. Mnemonics and register
+names are case-insensitive; labels are case-sensitive.
+*/
+func Assemble(source string) ([]byte, error) {
+	stmts, labels, err := parse(source)
+	if err != nil {
+		return nil, err
+	}
+	var rom []byte
+	for _, st := range stmts {
+		out, err := encode(st, labels)
+		if err != nil {
+			return nil, &Error{Line: st.line, Msg: err.Error()}
+		}
+		rom = append(rom, out...)
+	}
+	return rom, nil
+}
+
+// parse splits the source into statements, assigning each an address and
+// collecting label definitions.
+func parse(source string) ([]statement, map[string]uint16, error) {
+	var stmts []statement
+	labels := make(map[string]uint16)
+	addr := uint16(Origin)
+
+	for i, raw := range strings.Split(source, "\n") {
+		lineNo := i + 1
+		text := raw
+		if idx := strings.IndexByte(text, ';'); idx >= 0 {
+			text = text[:idx]
+		}
+		text = strings.TrimSpace(text)
+
+		for {
+			colon := strings.IndexByte(text, ':')
+			if colon < 0 || strings.ContainsAny(text[:colon], " \t,") {
+				break
+			}
+			name := text[:colon]
+			if !isIdentifier(name) {
+				return nil, nil, &Error{Line: lineNo, Msg: fmt.Sprintf("invalid label %q", name)}
+			}
+			if _, exists := labels[name]; exists {
+				return nil, nil, &Error{Line: lineNo, Msg: fmt.Sprintf("label %q already defined", name)}
+			}
+			labels[name] = addr
+			text = strings.TrimSpace(text[colon+1:])
+		}
+		if text == "" {
+			continue
+		}
+
+		mnemonic, rest := text, ""
+		if idx := strings.IndexAny(text, " \t"); idx >= 0 {
+			mnemonic, rest = text[:idx], text[idx+1:]
+		}
+		st := statement{line: lineNo, mnemonic: strings.ToUpper(mnemonic), addr: addr}
+		if rest = strings.TrimSpace(rest); rest != "" {
+			for _, op := range strings.Split(rest, ",") {
+				st.operands = append(st.operands, strings.TrimSpace(op))
+			}
+		}
+
+		size := 2
+		if st.mnemonic == "DB" {
+			if len(st.operands) == 0 {
+				return nil, nil, &Error{Line: lineNo, Msg: "db needs at least one byte"}
+			}
+			size = len(st.operands)
+		}
+		if target, ok := longLoad(st); ok && target != "" {
+			size = 4
+		}
+		if int(addr)+size-1 > maxAddress {
+			return nil, nil, &Error{Line: lineNo, Msg: "program does not fit in memory"}
+		}
+		addr += uint16(size)
+		stmts = append(stmts, st)
+	}
+	return stmts, labels, nil
+}
+
+// encode produces the bytes for a single statement.
+func encode(st statement, labels map[string]uint16) ([]byte, error) {
+	ops := st.operands
+	if st.mnemonic == "DB" {
+		out := make([]byte, 0, len(ops))
+		for _, op := range ops {
+			v, err := number(op, 0xFF, labels)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, byte(v))
+		}
+		return out, nil
+	}
+
+	if target, ok := longLoad(st); ok {
+		if target == "" {
+			return []byte{0xF0, 0x00}, nil
+		}
+		nnnn, err := number(target, 0xFFFF, labels)
+		if err != nil {
+			return nil, err
+		}
+		return []byte{0xF0, 0x00, byte(nnnn >> 8), byte(nnnn)}, nil
+	}
+
+	opcode, err := encodeInstruction(st.mnemonic, ops, labels)
+	if err != nil {
+		return nil, err
+	}
+	return []byte{byte(opcode >> 8), byte(opcode)}, nil
+}
+
+// longLoad reports whether a statement is the XO-CHIP "LD I, long" and returns
+// the address written after "long", if any.
+func longLoad(st statement) (target string, ok bool) {
+	if st.mnemonic != "LD" || len(st.operands) != 2 || !strings.EqualFold(st.operands[0], "I") {
+		return "", false
+	}
+	fields := strings.Fields(st.operands[1])
+	if len(fields) == 0 || len(fields) > 2 || !strings.EqualFold(fields[0], "long") {
+		return "", false
+	}
+	if len(fields) == 2 {
+		target = fields[1]
+	}
+	return target, true
+}
+
+// encodeInstruction returns the opcode for a mnemonic and its operands.
+func encodeInstruction(mnemonic string, ops []string, labels map[string]uint16) (uint16, error) {
+	switch mnemonic {
+	case "CLS":
+		return 0x00E0, want(ops, 0)
+	case "RET":
+		return 0x00EE, want(ops, 0)
+	case "SCR":
+		return 0x00FB, want(ops, 0)
+	case "SCL":
+		return 0x00FC, want(ops, 0)
+	case "SCD":
+		if err := want(ops, 1); err != nil {
+			return 0, err
+		}
+		n, err := number(ops[0], 0xF, labels)
+		if err != nil {
+			return 0, err
+		}
+		return 0x00C0 | n, nil
+	case "PLANE":
+		if err := want(ops, 1); err != nil {
+			return 0, err
+		}
+		n, err := number(ops[0], 0x3, labels)
+		if err != nil {
+			return 0, err
+		}
+		return 0xF001 | n<<8, nil
+	case "AUDIO":
+		return 0xF002, want(ops, 0)
+	case "PITCH":
+		return regOnly(0xF03A, ops)
+	case "LOW":
+		return 0x00FE, want(ops, 0)
+	case "HIGH":
+		return 0x00FF, want(ops, 0)
+	case "SYS":
+		return addrOp(0x0000, ops, labels)
+	case "CALL":
+		return addrOp(0x2000, ops, labels)
+	case "JP":
+		if len(ops) == 2 {
+			if r, ok := register(ops[0]); !ok || r != 0 {
+				return 0, fmt.Errorf("JP with an offset only supports V0")
+			}
+			return addrOp(0xB000, ops[1:], labels)
+		}
+		return addrOp(0x1000, ops, labels)
+	case "SE":
+		return regOrByte(0x5000, 0x3000, ops, labels)
+	case "SNE":
+		return regOrByte(0x9000, 0x4000, ops, labels)
+	case "OR":
+		return regReg(0x8001, ops)
+	case "AND":
+		return regReg(0x8002, ops)
+	case "XOR":
+		return regReg(0x8003, ops)
+	case "SUB":
+		return regReg(0x8005, ops)
+	case "SUBN":
+		return regReg(0x8007, ops)
+	case "SHR":
+		return shift(0x8006, ops)
+	case "SHL":
+		return shift(0x800E, ops)
+	case "RND":
+		return regByte(0xC000, ops, labels)
+	case "SKP":
+		return regOnly(0xE09E, ops)
+	case "SKNP":
+		return regOnly(0xE0A1, ops)
+	case "DRW":
+		if err := want(ops, 3); err != nil {
+			return 0, err
+		}
+		x, err := reg(ops[0])
+		if err != nil {
+			return 0, err
+		}
+		y, err := reg(ops[1])
+		if err != nil {
+			return 0, err
+		}
+		n, err := number(ops[2], 0xF, labels)
+		if err != nil {
+			return 0, err
+		}
+		return 0xD000 | x<<8 | y<<4 | n, nil
+	case "ADD":
+		if err := want(ops, 2); err != nil {
+			return 0, err
+		}
+		if strings.EqualFold(ops[0], "I") {
+			return regOnly(0xF01E, ops[1:])
+		}
+		return regOrByte(0x8004, 0x7000, ops, labels)
+	case "LD":
+		return encodeLoad(ops, labels)
+	}
+	return 0, fmt.Errorf("unknown instruction %q", mnemonic)
+}
+
+// encodeLoad handles the many forms of LD.
+func encodeLoad(ops []string, labels map[string]uint16) (uint16, error) {
+	if err := want(ops, 2); err != nil {
+		return 0, err
+	}
+	dst, src := strings.ToUpper(ops[0]), strings.ToUpper(ops[1])
+	switch dst {
+	case "I":
+		return addrOp(0xA000, ops[1:], labels)
+	case "DT":
+		return regOnly(0xF015, ops[1:])
+	case "ST":
+		return regOnly(0xF018, ops[1:])
+	case "F":
+		return regOnly(0xF029, ops[1:])
+	case "B":
+		return regOnly(0xF033, ops[1:])
+	case "[I]":
+		return regOnly(0xF055, ops[1:])
+	}
+	x, err := reg(ops[0])
+	if err != nil {
+		return 0, err
+	}
+	switch src {
+	case "DT":
+		return 0xF007 | x<<8, nil
+	case "K":
+		return 0xF00A | x<<8, nil
+	case "[I]":
+		return 0xF065 | x<<8, nil
+	}
+	return regOrByte(0x8000, 0x6000, ops, labels)
+}
+
+func want(ops []string, n int) error {
+	if len(ops) != n {
+		return fmt.Errorf("expected %d operand(s), got %d", n, len(ops))
+	}
+	return nil
+}
+
+func addrOp(base uint16, ops []string, labels map[string]uint16) (uint16, error) {
+	if err := want(ops, 1); err != nil {
+		return 0, err
+	}
+	nnn, err := number(ops[0], 0xFFF, labels)
+	if err != nil {
+		return 0, err
+	}
+	return base | nnn, nil
+}
+
+func regOnly(base uint16, ops []string) (uint16, error) {
+	if err := want(ops, 1); err != nil {
+		return 0, err
+	}
+	x, err := reg(ops[0])
+	if err != nil {
+		return 0, err
+	}
+	return base | x<<8, nil
+}
+
+func regReg(base uint16, ops []string) (uint16, error) {
+	if err := want(ops, 2); err != nil {
+		return 0, err
+	}
+	x, err := reg(ops[0])
+	if err != nil {
+		return 0, err
+	}
+	y, err := reg(ops[1])
+	if err != nil {
+		return 0, err
+	}
+	return base | x<<8 | y<<4, nil
+}
+
+func regByte(base uint16, ops []string, labels map[string]uint16) (uint16, error) {
+	if err := want(ops, 2); err != nil {
+		return 0, err
+	}
+	x, err := reg(ops[0])
+	if err != nil {
+		return 0, err
+	}
+	nn, err := number(ops[1], 0xFF, labels)
+	if err != nil {
+		return 0, err
+	}
+	return base | x<<8 | nn, nil
+}
+
+// regOrByte picks the register-register form when the second operand is a
+// register and the register-immediate form otherwise.
+func regOrByte(regBase, byteBase uint16, ops []string, labels map[string]uint16) (uint16, error) {
+	if err := want(ops, 2); err != nil {
+		return 0, err
+	}
+	if _, ok := register(ops[1]); ok {
+		return regReg(regBase, ops)
+	}
+	return regByte(byteBase, ops, labels)
+}
+
+// shift accepts both "SHR Vx" and "SHR Vx, Vy".
+func shift(base uint16, ops []string) (uint16, error) {
+	if len(ops) == 1 {
+		return regOnly(base, ops)
+	}
+	return regReg(base, ops)
+}
+
+// register parses V0-VF.
+func register(s string) (uint16, bool) {
+	if len(s) != 2 || (s[0] != 'V' && s[0] != 'v') {
+		return 0, false
+	}
+	v, err := strconv.ParseUint(s[1:], 16, 4)
+	if err != nil {
+		return 0, false
+	}
+	return uint16(v), true
+}
+
+func reg(s string) (uint16, error) {
+	r, ok := register(s)
+	if !ok {
+		return 0, fmt.Errorf("expected a register V0-VF, got %q", s)
+	}
+	return r, nil
+}
+
+// number parses a numeric literal or label and checks it fits in max.
+func number(s string, max uint16, labels map[string]uint16) (uint16, error) {
+	if addr, ok := labels[s]; ok {
+		if addr > max {
+			return 0, fmt.Errorf("label %q (0x%X) out of range", s, addr)
+		}
+		return addr, nil
+	}
+	v, err := strconv.ParseUint(s, 0, 16)
+	if err != nil {
+		if isIdentifier(s) {
+			return 0, fmt.Errorf("undefined label %q", s)
+		}
+		return 0, fmt.Errorf("invalid number %q", s)
+	}
+	if v > uint64(max) {
+		return 0, fmt.Errorf("value %s out of range (max 0x%X)", s, max)
+	}
+	return uint16(v), nil
+}
+
+func isIdentifier(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r := range s {
+		switch {
+		case r == '_', r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+		case i > 0 && r >= '0' && r <= '9':
+		default:
+			return false
+		}
+	}
+	return true
+}
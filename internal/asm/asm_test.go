@@ -0,0 +1,103 @@
+package asm
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+/*
+TestAssembleInstructionForms checks the encoding of instructions with several
+operand forms.
+*/
+func TestAssembleInstructionForms(t *testing.T) {
+	source := `
+	LD V1, V2
+	LD V3, 0x2A
+	LD VA, DT
+	LD [I], V5
+	ADD I, V4
+	ADD V1, 1
+	SE V1, V2
+	SNE V1, 0b11
+	SHR V6
+	JP V0, 0x300
+	SKNP VF
+`
+	want := []byte{
+		0x81, 0x20,
+		0x63, 0x2A,
+		0xFA, 0x07,
+		0xF5, 0x55,
+		0xF4, 0x1E,
+		0x71, 0x01,
+		0x51, 0x20,
+		0x41, 0x03,
+		0x86, 0x06,
+		0xB3, 0x00,
+		0xEF, 0xA1,
+	}
+	got, err := Assemble(source)
+	if err != nil {
+		t.Fatalf("Assemble failed: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("Expected % X, got % X", want, got)
+	}
+}
+
+/*
+TestAssembleLongLoad checks the XO-CHIP long load: with an address or label it
+emits F000 and the 16-bit address, and bare, as Disassemble prints it, just
+F000 so the address can follow on the next line.
+*/
+func TestAssembleLongLoad(t *testing.T) {
+	source := `
+	LD I, long 0x1234
+	LD I, LONG data
+	LD I, long
+	db 0xAB, 0xCD
+data:
+`
+	want := []byte{
+		0xF0, 0x00, 0x12, 0x34,
+		0xF0, 0x00, 0x02, 0x0C,
+		0xF0, 0x00,
+		0xAB, 0xCD,
+	}
+	got, err := Assemble(source)
+	if err != nil {
+		t.Fatalf("Assemble failed: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("Expected % X, got % X", want, got)
+	}
+}
+
+/*
+TestAssembleTabSeparated checks that a tab between the mnemonic and its operands
+is accepted like a space.
+*/
+func TestAssembleTabSeparated(t *testing.T) {
+	got, err := Assemble("LD\tV0, 1\nDRW\tV0,\tV1, 5\n")
+	if err != nil {
+		t.Fatalf("Assemble failed: %v", err)
+	}
+	if want := []byte{0x60, 0x01, 0xD0, 0x15}; !bytes.Equal(got, want) {
+		t.Errorf("Expected % X, got % X", want, got)
+	}
+}
+
+/*
+TestAssembleErrorLine checks that errors carry the offending line number.
+*/
+func TestAssembleErrorLine(t *testing.T) {
+	_, err := Assemble("CLS\n\nLD V1, 0x100\n")
+	var asmErr *Error
+	if !errors.As(err, &asmErr) {
+		t.Fatalf("Expected *Error, got %v", err)
+	}
+	if asmErr.Line != 3 {
+		t.Errorf("Expected line 3, got %d", asmErr.Line)
+	}
+}
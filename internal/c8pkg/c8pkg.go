@@ -0,0 +1,80 @@
+package c8pkg
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// Version is the container format version written by Encode.
+const Version = 1
+
+// magic identifies a .c8pkg file.
+const magic = "C8PKG"
+
+// headerSize is the magic plus the version byte.
+const headerSize = len(magic) + 1
+
+// Package bundles a ROM image with a save state taken while running it.
+type Package struct {
+	ROM   []byte
+	State []byte
+}
+
+/*
+Encode serialises a package as: magic, version byte, then the ROM and the state,
+each prefixed with its length as a big-endian uint32.
+*/
+func Encode(p Package) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(magic)
+	buf.WriteByte(Version)
+	writeSection(&buf, p.ROM)
+	writeSection(&buf, p.State)
+	return buf.Bytes()
+}
+
+// Decode parses a package produced by Encode, rejecting malformed input.
+func Decode(data []byte) (*Package, error) {
+	if len(data) < headerSize || string(data[:len(magic)]) != magic {
+		return nil, fmt.Errorf("not a CHIP-8 package")
+	}
+	if v := data[len(magic)]; v != Version {
+		return nil, fmt.Errorf("unsupported package version %d (expected %d)", v, Version)
+	}
+	rest := data[headerSize:]
+	rom, rest, err := readSection(rest, "ROM")
+	if err != nil {
+		return nil, err
+	}
+	state, rest, err := readSection(rest, "state")
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("package has %d bytes of trailing data", len(rest))
+	}
+	if len(rom) == 0 {
+		return nil, fmt.Errorf("package contains no ROM")
+	}
+	return &Package{ROM: rom, State: state}, nil
+}
+
+func writeSection(buf *bytes.Buffer, section []byte) {
+	var size [4]byte
+	binary.BigEndian.PutUint32(size[:], uint32(len(section)))
+	buf.Write(size[:])
+	buf.Write(section)
+}
+
+func readSection(data []byte, name string) ([]byte, []byte, error) {
+	if len(data) < 4 {
+		return nil, nil, fmt.Errorf("package truncated before %s length", name)
+	}
+	size := binary.BigEndian.Uint32(data)
+	data = data[4:]
+	if uint64(size) > uint64(len(data)) {
+		return nil, nil, fmt.Errorf("package truncated in %s section", name)
+	}
+	return data[:size], data[size:], nil
+}
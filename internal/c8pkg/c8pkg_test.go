@@ -0,0 +1,49 @@
+package c8pkg
+
+import (
+	"bytes"
+	"testing"
+)
+
+/*
+TestRoundTrip checks that a package survives Encode followed by Decode unchanged.
+*/
+func TestRoundTrip(t *testing.T) {
+	in := Package{ROM: []byte{0x00, 0xE0, 0x12, 0x00}, State: []byte("state-bytes")}
+
+	out, err := Decode(Encode(in))
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if !bytes.Equal(out.ROM, in.ROM) {
+		t.Errorf("ROM mismatch: got %v, want %v", out.ROM, in.ROM)
+	}
+	if !bytes.Equal(out.State, in.State) {
+		t.Errorf("State mismatch: got %q, want %q", out.State, in.State)
+	}
+}
+
+/*
+TestDecodeRejectsMalformed checks that bad magic, unknown versions, truncation and
+trailing garbage are all reported as errors.
+*/
+func TestDecodeRejectsMalformed(t *testing.T) {
+	good := Encode(Package{ROM: []byte{0x12, 0x00}, State: []byte{1, 2, 3}})
+
+	badVersion := append([]byte{}, good...)
+	badVersion[len(magic)] = Version + 1
+
+	cases := map[string][]byte{
+		"empty":       {},
+		"magic":       append([]byte("NOPE!"), good[len(magic):]...),
+		"version":     badVersion,
+		"truncated":   good[:len(good)-1],
+		"trailing":    append(append([]byte{}, good...), 0xFF),
+		"missing rom": Encode(Package{State: []byte{1}}),
+	}
+	for name, data := range cases {
+		if _, err := Decode(data); err == nil {
+			t.Errorf("%s: expected error, got nil", name)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package demo
+
+import (
+	"testing"
+)
+
+/*
+TestDecodeQueuesEventsInOrder round-trips a demo through Encode/Decode and checks
+that the Player releases its events in recorded order as cycles advance.
+*/
+func TestDecodeQueuesEventsInOrder(t *testing.T) {
+	data, err := Encode(Demo{Seed: 42, HasSeed: true, Events: []Event{
+		{Cycle: 10, Key: 0x5, Down: true},
+		{Cycle: 10, Key: 0x6, Down: true},
+		{Cycle: 25, Key: 0x5, Down: false},
+		{Cycle: 40, Key: 0x6, Down: false},
+	}})
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	d, err := Decode(data)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if d.Seed != 42 || !d.HasSeed {
+		t.Errorf("Expected the RNG seed to round-trip, got %d (has seed %v)", d.Seed, d.HasSeed)
+	}
+	p := NewPlayer(d.Events)
+
+	if got := p.Due(9); len(got) != 0 {
+		t.Errorf("Expected no events before cycle 10, got %v", got)
+	}
+	got := p.Due(10)
+	if len(got) != 2 || got[0].Key != 0x5 || got[1].Key != 0x6 {
+		t.Errorf("Expected keys 5 and 6 at cycle 10, got %v", got)
+	}
+	got = p.Due(30)
+	if len(got) != 1 || got[0].Key != 0x5 || got[0].Down {
+		t.Errorf("Expected key 5 release by cycle 30, got %v", got)
+	}
+	if p.Done() {
+		t.Error("Expected player to have events remaining")
+	}
+	got = p.Due(100)
+	if len(got) != 1 || got[0].Key != 0x6 {
+		t.Errorf("Expected key 6 release by cycle 100, got %v", got)
+	}
+	if !p.Done() {
+		t.Error("Expected player to be done")
+	}
+}
+
+/*
+TestDecodeRejectsBadDemos checks that unknown versions, out-of-range keys and
+out-of-order events are rejected.
+*/
+func TestDecodeRejectsBadDemos(t *testing.T) {
+	cases := map[string]string{
+		"version": `{"version":99,"events":[]}`,
+		"key":     `{"version":1,"events":[{"cycle":1,"key":16,"down":true}]}`,
+		"order":   `{"version":1,"events":[{"cycle":5,"key":1,"down":true},{"cycle":2,"key":1,"down":false}]}`,
+		"json":    `not a demo`,
+	}
+	for name, input := range cases {
+		if _, err := Decode([]byte(input)); err == nil {
+			t.Errorf("%s: expected error, got nil", name)
+		}
+	}
+}
+
+/*
+TestDecodeSeed checks that a zero seed is kept when recorded, that a demo
+without one has none, and that older demos with only a non-zero seed have one.
+*/
+func TestDecodeSeed(t *testing.T) {
+	tests := []struct {
+		data    string
+		seed    int64
+		hasSeed bool
+	}{
+		{`{"version": 1, "hasSeed": true, "events": []}`, 0, true},
+		{`{"version": 1, "events": []}`, 0, false},
+		{`{"version": 1, "seed": 42, "events": []}`, 42, true},
+	}
+	for _, tt := range tests {
+		d, err := Decode([]byte(tt.data))
+		if err != nil {
+			t.Fatalf("Decode(%s) failed: %v", tt.data, err)
+		}
+		if d.Seed != tt.seed || d.HasSeed != tt.hasSeed {
+			t.Errorf("Decode(%s): expected seed %d (has seed %v), got %d (%v)", tt.data, tt.seed, tt.hasSeed, d.Seed, d.HasSeed)
+		}
+	}
+}
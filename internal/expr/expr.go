@@ -0,0 +1,205 @@
+// Package expr evaluates debugger watch expressions against CHIP-8 state.
+//
+// Expressions combine integer literals, the registers V0-VF, I and PC, and
+// memory reads written mem[addr], using + - * / % & | ^ << >>, unary minus and
+// parentheses. Operator precedence follows Go.
+package expr
+
+import (
+	"chip8-wails/chip8"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Eval parses src and evaluates it against c.
+func Eval(src string, c *chip8.Chip8) (int, error) {
+	tokens, err := tokenize(src)
+	if err != nil {
+		return 0, err
+	}
+	p := &parser{tokens: tokens, cpu: c}
+	v, err := p.binary(0)
+	if err != nil {
+		return 0, err
+	}
+	if p.pos < len(p.tokens) {
+		return 0, fmt.Errorf("unexpected %q", p.tokens[p.pos])
+	}
+	return v, nil
+}
+
+// precedence ranks binary operators; higher binds tighter.
+var precedence = map[string]int{
+	"+": 1, "-": 1, "|": 1, "^": 1,
+	"&": 2, "<<": 2, ">>": 2, "*": 2, "/": 2, "%": 2,
+}
+
+func tokenize(src string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(src); {
+		r := rune(src[i])
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_':
+			j := i
+			for j < len(src) && (unicode.IsLetter(rune(src[j])) || unicode.IsDigit(rune(src[j])) || src[j] == '_') {
+				j++
+			}
+			tokens = append(tokens, src[i:j])
+			i = j
+		case strings.HasPrefix(src[i:], "<<"), strings.HasPrefix(src[i:], ">>"):
+			tokens = append(tokens, src[i:i+2])
+			i += 2
+		case strings.ContainsRune("+-*/%&|^()[]", r):
+			tokens = append(tokens, string(r))
+			i++
+		default:
+			return nil, fmt.Errorf("unexpected character %q", r)
+		}
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("empty expression")
+	}
+	return tokens, nil
+}
+
+type parser struct {
+	tokens []string
+	pos    int
+	cpu    *chip8.Chip8
+}
+
+func (p *parser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *parser) expect(tok string) error {
+	if p.peek() != tok {
+		if p.pos >= len(p.tokens) {
+			return fmt.Errorf("expected %q at end of expression", tok)
+		}
+		return fmt.Errorf("expected %q, got %q", tok, p.peek())
+	}
+	p.pos++
+	return nil
+}
+
+// binary parses operators binding tighter than minPrec using precedence climbing.
+func (p *parser) binary(minPrec int) (int, error) {
+	left, err := p.unary()
+	if err != nil {
+		return 0, err
+	}
+	for {
+		op := p.peek()
+		prec, ok := precedence[op]
+		if !ok || prec <= minPrec {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.binary(prec)
+		if err != nil {
+			return 0, err
+		}
+		if left, err = apply(op, left, right); err != nil {
+			return 0, err
+		}
+	}
+}
+
+func apply(op string, a, b int) (int, error) {
+	switch op {
+	case "+":
+		return a + b, nil
+	case "-":
+		return a - b, nil
+	case "*":
+		return a * b, nil
+	case "/", "%":
+		if b == 0 {
+			return 0, fmt.Errorf("division by zero")
+		}
+		if op == "/" {
+			return a / b, nil
+		}
+		return a % b, nil
+	case "&":
+		return a & b, nil
+	case "|":
+		return a | b, nil
+	case "^":
+		return a ^ b, nil
+	case "<<", ">>":
+		if b < 0 || b > 31 {
+			return 0, fmt.Errorf("shift count %d out of range", b)
+		}
+		if op == "<<" {
+			return a << b, nil
+		}
+		return a >> b, nil
+	}
+	return 0, fmt.Errorf("unknown operator %q", op)
+}
+
+func (p *parser) unary() (int, error) {
+	if p.peek() == "-" {
+		p.pos++
+		v, err := p.unary()
+		return -v, err
+	}
+	return p.primary()
+}
+
+func (p *parser) primary() (int, error) {
+	tok := p.peek()
+	if tok == "" {
+		return 0, fmt.Errorf("unexpected end of expression")
+	}
+	p.pos++
+
+	if tok == "(" {
+		v, err := p.binary(0)
+		if err != nil {
+			return 0, err
+		}
+		return v, p.expect(")")
+	}
+
+	upper := strings.ToUpper(tok)
+	switch {
+	case upper == "I":
+		return int(p.cpu.I), nil
+	case upper == "PC":
+		return int(p.cpu.PC), nil
+	case upper == "MEM":
+		if err := p.expect("["); err != nil {
+			return 0, err
+		}
+		addr, err := p.binary(0)
+		if err != nil {
+			return 0, err
+		}
+		if err := p.expect("]"); err != nil {
+			return 0, err
+		}
+		if addr < 0 || addr >= len(p.cpu.Memory) {
+			return 0, fmt.Errorf("memory address 0x%X out of range", addr)
+		}
+		return int(p.cpu.Memory[addr]), nil
+	case len(upper) == 2 && upper[0] == 'V':
+		if r, err := strconv.ParseUint(upper[1:], 16, 4); err == nil {
+			return int(p.cpu.Registers[r]), nil
+		}
+	}
+
+	if v, err := strconv.ParseInt(tok, 0, 64); err == nil {
+		return int(v), nil
+	}
+	return 0, fmt.Errorf("unknown identifier %q", tok)
+}
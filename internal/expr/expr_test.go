@@ -0,0 +1,56 @@
+package expr
+
+import (
+	"chip8-wails/chip8"
+	"testing"
+)
+
+/*
+TestEvalAgainstState evaluates several expressions against a known CPU state.
+*/
+func TestEvalAgainstState(t *testing.T) {
+	c := chip8.New()
+	c.Registers[3] = 10
+	c.Registers[4] = 5
+	c.Registers[0xF] = 1
+	c.I = 0x300
+	c.PC = 0x210
+	c.Memory[0x300] = 0xAB
+	c.Memory[0x305] = 0x07
+
+	tests := []struct {
+		expr string
+		want int
+	}{
+		{"V3 + V4", 15},
+		{"v3 - v4 * 2", 0},
+		{"(V3 - V4) * 2", 10},
+		{"mem[I]", 0xAB},
+		{"mem[I + V4]", 0x07},
+		{"PC - 0x200", 0x10},
+		{"VF << 4 | 3", 0x13},
+		{"-V4 + 1", -4},
+	}
+	for _, tt := range tests {
+		got, err := Eval(tt.expr, c)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tt.expr, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%q: expected %d, got %d", tt.expr, tt.want, got)
+		}
+	}
+}
+
+/*
+TestEvalInvalid checks that malformed expressions return errors.
+*/
+func TestEvalInvalid(t *testing.T) {
+	c := chip8.New()
+	for _, src := range []string{"", "V3 +", "VG", "mem[5000]", "mem[I", "V1 / 0", "(V1", "V1 V2", "V1 $ 2"} {
+		if _, err := Eval(src, c); err == nil {
+			t.Errorf("%q: expected an error", src)
+		}
+	}
+}
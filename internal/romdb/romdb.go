@@ -0,0 +1,131 @@
+// Package romdb recognises well-known ROMs by their SHA-1 hash and records the
+// platform, quirks and clock speed they need.
+//
+// The database is a JSON file mapping lowercase hex SHA-1 hashes of ROM images
+// to entries:
+//
+//	{
+//	  "version": 1,
+//	  "roms": {
+//	    "<sha1>": {"title": "...", "platform": "schip", "clockSpeed": 1000}
+//	  }
+//	}
+//
+// A built-in database is embedded in the binary. Only hashes computed from
+// actual ROM files belong in it; a user-supplied file can add entries or
+// replace built-in ones.
+package romdb
+
+import (
+	"chip8-wails/chip8"
+	"crypto/sha1"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// FormatVersion is the database file version this package reads.
+const FormatVersion = 1
+
+//go:embed roms.json
+var builtin []byte
+
+// Entry describes a known ROM.
+type Entry struct {
+	Title      string        `json:"title"`
+	Platform   chip8.Machine `json:"platform"`
+	Quirks     *chip8.Quirks `json:"quirks,omitempty"`     // Overrides the platform's quirks when set
+	ClockSpeed int           `json:"clockSpeed,omitempty"` // Hz; 0 keeps the platform default
+}
+
+// EffectiveQuirks returns the entry's quirks, falling back to its platform's.
+func (e Entry) EffectiveQuirks() chip8.Quirks {
+	if e.Quirks != nil {
+		return *e.Quirks
+	}
+	return chip8.MachineQuirks(e.Platform)
+}
+
+// DB is a set of known ROMs keyed by SHA-1 hash.
+type DB struct {
+	roms map[string]Entry
+}
+
+type file struct {
+	Version int              `json:"version"`
+	ROMs    map[string]Entry `json:"roms"`
+}
+
+// Hash returns the lowercase hex SHA-1 of a ROM image, the database key.
+func Hash(rom []byte) string {
+	sum := sha1.Sum(rom)
+	return hex.EncodeToString(sum[:])
+}
+
+// Load returns the built-in database merged with the file at overridePath,
+// whose entries win. A missing override file is not an error.
+func Load(overridePath string) (*DB, error) {
+	db, err := Parse(builtin)
+	if err != nil {
+		return nil, fmt.Errorf("built-in ROM database: %w", err)
+	}
+	if overridePath == "" {
+		return db, nil
+	}
+	data, err := ioutil.ReadFile(overridePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return db, nil
+		}
+		return db, fmt.Errorf("failed to read ROM database %s: %w", overridePath, err)
+	}
+	user, err := Parse(data)
+	if err != nil {
+		return db, fmt.Errorf("%s: %w", overridePath, err)
+	}
+	for hash, entry := range user.roms {
+		db.roms[hash] = entry
+	}
+	return db, nil
+}
+
+// Parse reads a database file, validating its version, hashes and platforms.
+func Parse(data []byte) (*DB, error) {
+	var f file
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("invalid ROM database: %w", err)
+	}
+	if f.Version != FormatVersion {
+		return nil, fmt.Errorf("unsupported ROM database version %d", f.Version)
+	}
+	db := &DB{roms: make(map[string]Entry, len(f.ROMs))}
+	for hash, entry := range f.ROMs {
+		key := strings.ToLower(hash)
+		if _, err := hex.DecodeString(key); err != nil || len(key) != 2*sha1.Size {
+			return nil, fmt.Errorf("invalid SHA-1 %q in ROM database", hash)
+		}
+		if entry.Platform == "" {
+			entry.Platform = chip8.MachineCHIP8
+		}
+		if !entry.Platform.Valid() {
+			return nil, fmt.Errorf("unknown platform %q for %s", entry.Platform, hash)
+		}
+		db.roms[key] = entry
+	}
+	return db, nil
+}
+
+// Lookup returns the entry for a ROM image, if it is known.
+func (db *DB) Lookup(rom []byte) (Entry, bool) {
+	entry, ok := db.roms[Hash(rom)]
+	return entry, ok
+}
+
+// Len returns the number of known ROMs.
+func (db *DB) Len() int {
+	return len(db.roms)
+}
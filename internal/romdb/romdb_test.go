@@ -0,0 +1,95 @@
+package romdb
+
+import (
+	"chip8-wails/chip8"
+	"chip8-wails/internal/roms"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var testROM = []byte{0x00, 0xE0, 0x12, 0x02}
+
+/*
+TestBuiltinParses checks that the embedded database is valid.
+*/
+func TestBuiltinParses(t *testing.T) {
+	if _, err := Parse(builtin); err != nil {
+		t.Fatalf("Expected the built-in database to parse, got %v", err)
+	}
+}
+
+/*
+TestBuiltinKnowsBundledROMs checks that every example ROM shipped with the app
+is recognised by the built-in database.
+*/
+func TestBuiltinKnowsBundledROMs(t *testing.T) {
+	db, err := Load("")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	names := roms.Bundled()
+	if len(names) == 0 {
+		t.Fatal("Expected bundled ROMs to check against")
+	}
+	for _, name := range names {
+		data, err := roms.LoadBundled(name)
+		if err != nil {
+			t.Fatalf("LoadBundled(%q) failed: %v", name, err)
+		}
+		entry, ok := db.Lookup(data)
+		if !ok {
+			t.Errorf("Expected %s (SHA-1 %s) in the built-in database", name, Hash(data))
+			continue
+		}
+		if entry.Title == "" || entry.Platform != chip8.MachineCHIP8 {
+			t.Errorf("Expected %s to be a titled CHIP-8 entry, got %+v", name, entry)
+		}
+	}
+}
+
+/*
+TestLoadOverride checks that entries from a user file are looked up by hash,
+that a missing file is ignored, and that quirks fall back to the platform's.
+*/
+func TestLoadOverride(t *testing.T) {
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.json")); err != nil {
+		t.Fatalf("Expected a missing override file to be ignored, got %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "romdb.json")
+	data := `{"version": 1, "roms": {"` + Hash(testROM) + `": {"title": "Test", "platform": "schip", "clockSpeed": 900}}}`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	db, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	entry, ok := db.Lookup(testROM)
+	if !ok || entry.Title != "Test" || entry.Platform != chip8.MachineSCHIP || entry.ClockSpeed != 900 {
+		t.Fatalf("Expected the override entry, got %+v (found=%v)", entry, ok)
+	}
+	if entry.EffectiveQuirks() != chip8.MachineQuirks(chip8.MachineSCHIP) {
+		t.Error("Expected quirks to default to the platform's")
+	}
+	if _, ok := db.Lookup([]byte{0x12, 0x00}); ok {
+		t.Error("Expected an unknown ROM not to be found")
+	}
+}
+
+/*
+TestParseRejectsInvalid checks that bad versions, hashes and platforms are refused.
+*/
+func TestParseRejectsInvalid(t *testing.T) {
+	bad := []string{
+		`{"version": 2, "roms": {}}`,
+		`{"version": 1, "roms": {"xyz": {"title": "Bad hash"}}}`,
+		`{"version": 1, "roms": {"` + Hash(testROM) + `": {"platform": "nes"}}}`,
+	}
+	for _, data := range bad {
+		if _, err := Parse([]byte(data)); err == nil {
+			t.Errorf("Expected an error for %s", data)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package roms
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+)
+
+// bundledFS holds the example ROMs shipped inside the binary. Each .ch8 is
+// assembled from the .asm source next to it with cmd/chip8-asm.
+//
+//go:embed bundled/*.ch8
+var bundledFS embed.FS
+
+// Bundled returns the names of the built-in example ROMs, sorted.
+func Bundled() []string {
+	entries, _ := fs.ReadDir(bundledFS, "bundled")
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && isROMName(e.Name()) {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadBundled returns the data of a built-in example ROM by name.
+func LoadBundled(name string) ([]byte, error) {
+	if strings.ContainsAny(name, `/\`) {
+		return nil, fmt.Errorf("no bundled ROM named %q", name)
+	}
+	data, err := bundledFS.ReadFile("bundled/" + name)
+	if err != nil {
+		return nil, fmt.Errorf("no bundled ROM named %q", name)
+	}
+	return data, nil
+}
@@ -0,0 +1,28 @@
+; A single pixel bouncing off the edges of the screen, one step per two frames.
+    LD V0, 10       ; x
+    LD V1, 5        ; y
+    LD V2, 1        ; x direction (1 or -1)
+    LD V3, 1        ; y direction (1 or -1)
+    LD I, dot
+loop:
+    DRW V0, V1, 1
+    LD V4, 2
+    LD DT, V4
+wait:
+    LD V4, DT
+    SE V4, 0
+    JP wait
+    DRW V0, V1, 1   ; erase before moving
+    ADD V0, V2
+    ADD V1, V3
+    SNE V0, 0
+    LD V2, 1
+    SNE V0, 63
+    LD V2, 0xFF
+    SNE V1, 0
+    LD V3, 1
+    SNE V1, 31
+    LD V3, 0xFF
+    JP loop
+dot:
+    db 0x80, 0x00
@@ -0,0 +1,9 @@
+; Keypad test: shows the hex digit of each key as it is pressed.
+loop:
+    LD V0, K
+    CLS
+    LD F, V0
+    LD V1, 30
+    LD V2, 13
+    DRW V1, V2, 5
+    JP loop
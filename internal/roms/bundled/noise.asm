@@ -0,0 +1,9 @@
+; Scatters random 8-pixel strips across the screen forever.
+    LD I, strip
+loop:
+    RND V0, 63
+    RND V1, 31
+    DRW V0, V1, 1
+    JP loop
+strip:
+    db 0xFF, 0x00
@@ -0,0 +1,48 @@
+package roms
+
+import (
+	"bytes"
+	"chip8-wails/internal/asm"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+/*
+TestBundledROMsMatchSources checks that every bundled ROM loads, passes
+validation and is up to date with the assembly source it was built from.
+*/
+func TestBundledROMsMatchSources(t *testing.T) {
+	names := Bundled()
+	if len(names) == 0 {
+		t.Fatal("Expected at least one bundled ROM")
+	}
+	for _, name := range names {
+		data, err := LoadBundled(name)
+		if err != nil {
+			t.Errorf("%s: LoadBundled failed: %v", name, err)
+			continue
+		}
+		if err := Validate(data); err != nil {
+			t.Errorf("%s: expected a valid ROM, got %v", name, err)
+		}
+		source, err := os.ReadFile(filepath.Join("bundled", strings.TrimSuffix(name, ".ch8")+".asm"))
+		if err != nil {
+			t.Errorf("%s: missing assembly source: %v", name, err)
+			continue
+		}
+		want, err := asm.Assemble(string(source))
+		if err != nil {
+			t.Errorf("%s: source does not assemble: %v", name, err)
+			continue
+		}
+		if !bytes.Equal(data, want) {
+			t.Errorf("%s: out of date with its source; reassemble it with cmd/chip8-asm", name)
+		}
+	}
+
+	if _, err := LoadBundled("../loader.go"); err == nil {
+		t.Error("Expected a path outside the bundle to be rejected")
+	}
+}
@@ -0,0 +1,29 @@
+package roms
+
+import "bytes"
+
+// romHeader describes a file header some tools prepend to ROM images.
+type romHeader struct {
+	name      string
+	signature []byte
+	length    int
+}
+
+// knownHeaders lists the headers StripHeader recognises. HP48/HP49 binary
+// transfers start with "HPHP48-" or "HPHP49-" plus a one-byte version letter.
+var knownHeaders = []romHeader{
+	{name: "HP48 binary", signature: []byte("HPHP48-"), length: 8},
+	{name: "HP49 binary", signature: []byte("HPHP49-"), length: 8},
+}
+
+// StripHeader removes a recognised file header from data and returns the
+// remaining bytes along with the header's name. If no header is recognised the
+// data is returned unchanged with an empty name.
+func StripHeader(data []byte) ([]byte, string) {
+	for _, h := range knownHeaders {
+		if len(data) > h.length && bytes.HasPrefix(data, h.signature) {
+			return data[h.length:], h.name
+		}
+	}
+	return data, ""
+}
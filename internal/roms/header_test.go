@@ -0,0 +1,37 @@
+package roms
+
+import (
+	"bytes"
+	"testing"
+)
+
+/*
+TestStripHeaderRecognised checks that an HP48 header is removed and named.
+*/
+func TestStripHeaderRecognised(t *testing.T) {
+	rom := []byte{0x00, 0xE0, 0x12, 0x00}
+	data := append([]byte("HPHP48-A"), rom...)
+
+	got, name := StripHeader(data)
+	if name != "HP48 binary" {
+		t.Errorf("Expected header name %q, got %q", "HP48 binary", name)
+	}
+	if !bytes.Equal(got, rom) {
+		t.Errorf("Expected % X, got % X", rom, got)
+	}
+}
+
+/*
+TestStripHeaderAbsent checks that a headerless ROM is left untouched.
+*/
+func TestStripHeaderAbsent(t *testing.T) {
+	rom := []byte{0x00, 0xE0, 0x12, 0x00}
+
+	got, name := StripHeader(rom)
+	if name != "" {
+		t.Errorf("Expected no header, got %q", name)
+	}
+	if !bytes.Equal(got, rom) {
+		t.Errorf("Expected % X, got % X", rom, got)
+	}
+}
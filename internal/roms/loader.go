@@ -1,15 +1,28 @@
 package roms
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"io/fs"
 	"io/ioutil"
+	"net/http"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
+// URLTimeout bounds the whole download in LoadFromURL, including reading the body.
+const URLTimeout = 15 * time.Second
+
 type Loader struct {
 	RomsDir string
+	// Recursive makes List descend into subdirectories, returning slash-separated
+	// paths relative to RomsDir; otherwise only top-level files are listed.
+	Recursive bool
 }
 
 // NewLoader returns a Loader for the given ROMs directory, creating it if necessary.
@@ -22,6 +35,9 @@ func NewLoader(romsDir string) *Loader {
 
 // List returns a list of available ROM filenames in the Loader's directory.
 func (l *Loader) List() ([]string, error) {
+	if l.Recursive {
+		return l.listRecursive()
+	}
 	files, err := ioutil.ReadDir(l.RomsDir)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read ROMs directory: %w", err)
@@ -29,17 +45,66 @@ func (l *Loader) List() ([]string, error) {
 
 	var romNames []string
 	for _, file := range files {
-		name := strings.ToLower(file.Name())
-		if !file.IsDir() && (strings.HasSuffix(name, ".ch8") || strings.HasSuffix(name, ".c8")) {
+		if !file.IsDir() && isROMName(file.Name()) {
 			romNames = append(romNames, file.Name())
 		}
 	}
 	return romNames, nil
 }
 
-// LoadFromDir loads a ROM by its filename from the Loader's directory.
+// listRecursive walks the Loader's directory tree and returns the ROMs found as
+// slash-separated paths relative to it, in lexical order.
+func (l *Loader) listRecursive() ([]string, error) {
+	var romNames []string
+	err := filepath.WalkDir(l.RomsDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !isROMName(d.Name()) {
+			return nil
+		}
+		rel, err := filepath.Rel(l.RomsDir, path)
+		if err != nil {
+			return err
+		}
+		romNames = append(romNames, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ROMs directory: %w", err)
+	}
+	return romNames, nil
+}
+
+// isROMName reports whether a filename has a CHIP-8 ROM extension.
+func isROMName(name string) bool {
+	name = strings.ToLower(name)
+	return strings.HasSuffix(name, ".ch8") || strings.HasSuffix(name, ".c8")
+}
+
+// Name returns the name List gives the ROM at path: its slash-separated path
+// relative to the Loader's directory if it lies inside it, otherwise its base
+// name.
+func (l *Loader) Name(path string) string {
+	dir, errDir := filepath.Abs(l.RomsDir)
+	abs, errPath := filepath.Abs(path)
+	if errDir == nil && errPath == nil {
+		if rel, err := filepath.Rel(dir, abs); err == nil && filepath.IsLocal(rel) {
+			return filepath.ToSlash(rel)
+		}
+	}
+	return filepath.Base(path)
+}
+
+// LoadFromDir loads a ROM by its filename, or by a slash-separated path relative
+// to the Loader's directory as returned by a recursive List. Paths that would
+// leave the directory are rejected.
 func (l *Loader) LoadFromDir(filename string) ([]byte, error) {
-	path := filepath.Join(l.RomsDir, filename)
+	rel := filepath.FromSlash(filename)
+	if !filepath.IsLocal(rel) {
+		return nil, fmt.Errorf("invalid ROM name %q: must be inside the ROMs directory", filename)
+	}
+	path := filepath.Join(l.RomsDir, rel)
 	return l.LoadFromPath(path)
 }
 
@@ -51,3 +116,45 @@ func (l *Loader) LoadFromPath(path string) ([]byte, error) {
 	}
 	return data, nil
 }
+
+// LoadFromURL downloads a ROM over HTTP or HTTPS and returns its data and a name
+// taken from the last path segment. Responses with a non-2xx status, or larger
+// than maxSize bytes (by Content-Length or by what is actually read), are rejected.
+func (l *Loader) LoadFromURL(ctx context.Context, rawURL string, maxSize int) ([]byte, string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, "", fmt.Errorf("invalid ROM URL %q: only http and https URLs are supported", rawURL)
+	}
+	ctx, cancel := context.WithTimeout(ctx, URLTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid ROM URL %q: %w", rawURL, err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("error downloading ROM from %s: %w", u.Redacted(), err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, "", fmt.Errorf("error downloading ROM from %s: server returned %s", u.Redacted(), resp.Status)
+	}
+	if resp.ContentLength > int64(maxSize) {
+		return nil, "", fmt.Errorf("ROM at %s is too large: %d bytes, at most %d fit in memory", u.Redacted(), resp.ContentLength, maxSize)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, int64(maxSize)+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("error downloading ROM from %s: %w", u.Redacted(), err)
+	}
+	if len(data) > maxSize {
+		return nil, "", fmt.Errorf("ROM at %s is too large: more than %d bytes", u.Redacted(), maxSize)
+	}
+	if len(data) == 0 {
+		return nil, "", fmt.Errorf("ROM at %s is empty", u.Redacted())
+	}
+	name := path.Base(u.Path)
+	if name == "/" || name == "." {
+		name = u.Host
+	}
+	return data, name, nil
+}
@@ -0,0 +1,113 @@
+package roms
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+/*
+TestLoadFromURL checks that a ROM is downloaded and named after the URL path, and
+that bad statuses, oversized bodies and non-HTTP schemes are rejected.
+*/
+func TestLoadFromURL(t *testing.T) {
+	rom := []byte{0x00, 0xE0, 0x12, 0x02}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/games/pong.ch8":
+			w.Write(rom)
+		case "/big.ch8":
+			w.Write(make([]byte, 64))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	l := &Loader{RomsDir: t.TempDir()}
+
+	data, name, err := l.LoadFromURL(context.Background(), srv.URL+"/games/pong.ch8", 32)
+	if err != nil {
+		t.Fatalf("LoadFromURL failed: %v", err)
+	}
+	if !bytes.Equal(data, rom) || name != "pong.ch8" {
+		t.Errorf("Expected % X named pong.ch8, got % X named %q", rom, data, name)
+	}
+
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{srv.URL + "/missing.ch8", "404"},
+		{srv.URL + "/big.ch8", "too large"},
+		{"file:///etc/passwd", "only http and https"},
+	}
+	for _, tt := range tests {
+		if _, _, err := l.LoadFromURL(context.Background(), tt.url, 32); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Expected an error containing %q for %s, got %v", tt.want, tt.url, err)
+		}
+	}
+}
+
+/*
+TestListRecursive checks that a recursive Loader finds ROMs in nested folders as
+slash-separated relative paths that LoadFromDir accepts and Name returns, while a
+flat Loader only sees the top level.
+*/
+func TestListRecursive(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string][]byte{
+		"top.ch8":                  {0x00, 0xE0},
+		"games/pong.ch8":           {0x12, 0x00},
+		"games/arcade/invaders.C8": {0x60, 0x01},
+		"games/readme.txt":         []byte("not a rom"),
+	}
+	for name, data := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	flat := NewLoader(dir)
+	got, err := flat.List()
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, []string{"top.ch8"}) {
+		t.Errorf("Expected only the top-level ROM, got %v", got)
+	}
+
+	l := NewLoader(dir)
+	l.Recursive = true
+	got, err = l.List()
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	want := []string{"games/arcade/invaders.C8", "games/pong.ch8", "top.ch8"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	data, err := l.LoadFromDir("games/arcade/invaders.C8")
+	if err != nil || !bytes.Equal(data, files["games/arcade/invaders.C8"]) {
+		t.Errorf("Expected LoadFromDir to read the nested ROM, got % X (%v)", data, err)
+	}
+	if _, err := l.LoadFromDir("../outside.ch8"); err == nil {
+		t.Error("Expected a path outside the ROMs directory to be rejected")
+	}
+	if name := l.Name(filepath.Join(dir, "games", "pong.ch8")); name != "games/pong.ch8" {
+		t.Errorf("Expected Name to match List, got %q", name)
+	}
+	if name := l.Name(filepath.Join(t.TempDir(), "other.ch8")); name != "other.ch8" {
+		t.Errorf("Expected the base name for a ROM outside the directory, got %q", name)
+	}
+}
@@ -0,0 +1,76 @@
+package roms
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// MaxROMSize is the largest ROM any supported machine can hold: XO-CHIP's 64KB
+// address space minus the 512 bytes below the program start.
+const MaxROMSize = 0x10000 - 0x200
+
+// Warning is returned by Validate for data that can be loaded but looks like it
+// is not a CHIP-8 ROM. Each reason is a short, user-facing sentence.
+type Warning struct {
+	Reasons []string
+}
+
+func (w *Warning) Error() string {
+	return "suspicious ROM: " + strings.Join(w.Reasons, "; ")
+}
+
+// foreignMagic lists the leading bytes of common file formats that are never
+// CHIP-8 programs.
+var foreignMagic = []struct {
+	name  string
+	magic []byte
+}{
+	{"an ELF executable", []byte("\x7fELF")},
+	{"a Windows executable", []byte("MZ")},
+	{"a Mach-O executable", []byte{0xCF, 0xFA, 0xED, 0xFE}},
+	{"a ZIP archive", []byte("PK\x03\x04")},
+	{"a gzip archive", []byte{0x1F, 0x8B}},
+	{"a PNG image", []byte("\x89PNG")},
+	{"a PDF document", []byte("%PDF")},
+}
+
+// Validate checks that data is plausible as a CHIP-8 ROM. Empty or oversized
+// data is an error. Data that fits but looks wrong (all zeros, an odd length
+// that misaligns opcodes, or the signature of another file format) yields a
+// *Warning so the caller can still load it.
+func Validate(data []byte) error {
+	if len(data) == 0 {
+		return errors.New("ROM is empty")
+	}
+	if len(data) > MaxROMSize {
+		return fmt.Errorf("ROM is %d bytes, more than the %d any machine can hold", len(data), MaxROMSize)
+	}
+	var reasons []string
+	for _, f := range foreignMagic {
+		if bytes.HasPrefix(data, f.magic) {
+			reasons = append(reasons, fmt.Sprintf("the file looks like %s", f.name))
+			break
+		}
+	}
+	if isAllZero(data) {
+		reasons = append(reasons, "the file contains only zero bytes")
+	}
+	if len(data)%2 != 0 {
+		reasons = append(reasons, fmt.Sprintf("its length (%d bytes) is odd, so the last opcode is incomplete", len(data)))
+	}
+	if len(reasons) > 0 {
+		return &Warning{Reasons: reasons}
+	}
+	return nil
+}
+
+func isAllZero(data []byte) bool {
+	for _, b := range data {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
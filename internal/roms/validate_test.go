@@ -0,0 +1,47 @@
+package roms
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+/*
+TestValidate checks that empty and oversized data are errors, suspicious data
+yields a Warning naming the problem, and an ordinary ROM passes.
+*/
+func TestValidate(t *testing.T) {
+	if err := Validate([]byte{0x00, 0xE0, 0x12, 0x02}); err != nil {
+		t.Errorf("Expected a plain ROM to pass, got %v", err)
+	}
+
+	for _, data := range [][]byte{nil, make([]byte, MaxROMSize+1)} {
+		err := Validate(data)
+		var w *Warning
+		if err == nil || errors.As(err, &w) {
+			t.Errorf("Expected a hard error for %d bytes, got %v", len(data), err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"elf", []byte("\x7fELF\x02\x01\x01\x00"), "ELF executable"},
+		{"pe", []byte("MZ\x90\x00"), "Windows executable"},
+		{"zeros", make([]byte, 16), "only zero bytes"},
+		{"odd", []byte{0x00, 0xE0, 0x12}, "odd"},
+	}
+	for _, tt := range tests {
+		err := Validate(tt.data)
+		var w *Warning
+		if !errors.As(err, &w) {
+			t.Errorf("%s: expected a *Warning, got %v", tt.name, err)
+			continue
+		}
+		if !strings.Contains(w.Error(), tt.want) {
+			t.Errorf("%s: expected the warning to mention %q, got %q", tt.name, tt.want, w.Error())
+		}
+	}
+}
@@ -0,0 +1,276 @@
+package savestate
+
+import (
+	"bytes"
+	"chip8-wails/chip8"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+)
+
+// Version is the save-state format version written by EncodeJSON. Version 1
+// had no core version, and version 2 a fixed 4KB memory stored as an array of
+// numbers; states in the original gob format count as version 0.
+const Version = 3
+
+// envelope wraps a JSON save state with its format version and the version of
+// the emulator core that wrote it.
+type envelope struct {
+	Version    int                `json:"version"`
+	Core       string             `json:"core,omitempty"`
+	MemorySize int                `json:"memorySize,omitempty"`
+	CPU        *chip8.Chip8       `json:"cpu"`
+	Trace      []chip8.TraceEntry `json:"trace,omitempty"` // Present when tracing was on
+}
+
+// VersionError reports a save state this build cannot read.
+type VersionError struct {
+	Version int    // Format version found in the state
+	Core    string // Core version that wrote it, if recorded
+}
+
+func (e *VersionError) Error() string {
+	core := e.Core
+	if core == "" {
+		core = "unknown"
+	}
+	return fmt.Sprintf("save state format version %d (written by core %s) is not supported; this build (core %s) reads versions up to %d",
+		e.Version, core, chip8.CoreVersion, Version)
+}
+
+// EncodeJSON serialises a CPU snapshot as versioned JSON: memory, registers,
+// display, timers, stack, quirks and the other exported CPU fields, plus the
+// execution trace if tracing is on.
+func EncodeJSON(cpu *chip8.Chip8) ([]byte, error) {
+	data, err := json.Marshal(envelope{Version: Version, Core: chip8.CoreVersion, MemorySize: cpu.MemorySize(), CPU: cpu, Trace: cpu.Trace()})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode CPU state: %w", err)
+	}
+	return data, nil
+}
+
+// EncodeGob serialises a CPU snapshot in the original gob format.
+func EncodeGob(cpu *chip8.Chip8) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(cpu); err != nil {
+		return nil, fmt.Errorf("failed to encode CPU state: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Decode restores a CPU snapshot written by EncodeJSON or EncodeGob, migrating
+// states from older builds. The format is told apart by the leading byte: JSON
+// states start with '{', which can never begin a gob stream.
+func Decode(data []byte) (*chip8.Chip8, error) {
+	if len(data) > 0 && data[0] == '{' {
+		return decodeJSON(data)
+	}
+	return decodeGob(data)
+}
+
+// decodeJSON restores a state written by EncodeJSON. The CPU starts from
+// chip8.New, so fields added since the state was written keep their defaults.
+func decodeJSON(data []byte) (*chip8.Chip8, error) {
+	env := envelope{CPU: chip8.New()}
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, fmt.Errorf("failed to decode CPU state: %w", err)
+	}
+	if env.Version < 1 || env.Version > Version {
+		return nil, &VersionError{Version: env.Version, Core: env.Core}
+	}
+	if env.MemorySize != 0 && env.CPU.MemorySize() != env.MemorySize {
+		return nil, fmt.Errorf("failed to decode CPU state: memory holds %d bytes but the state records %d", env.CPU.MemorySize(), env.MemorySize)
+	}
+	if err := checkMemorySize(env.CPU); err != nil {
+		return nil, err
+	}
+	if err := checkPointers(env.CPU); err != nil {
+		return nil, err
+	}
+	if env.Trace != nil {
+		env.CPU.LoadTrace(env.Trace)
+	}
+	return env.CPU, nil
+}
+
+// checkMemorySize rejects states whose memory could not belong to any machine.
+func checkMemorySize(cpu *chip8.Chip8) error {
+	if size := cpu.MemorySize(); size < chip8.DefaultMemorySize || size > chip8.XOCHIPMemorySize {
+		return fmt.Errorf("failed to decode CPU state: unsupported memory size %d", size)
+	}
+	return nil
+}
+
+// checkPointers rejects states whose PC, I or stack pointer lie outside memory
+// or the stack, which would make the next instruction index out of range.
+func checkPointers(cpu *chip8.Chip8) error {
+	size := cpu.MemorySize()
+	switch {
+	case int(cpu.PC) >= size:
+		return fmt.Errorf("failed to decode CPU state: PC 0x%04X is outside the %d bytes of memory", cpu.PC, size)
+	case int(cpu.I) >= size:
+		return fmt.Errorf("failed to decode CPU state: I 0x%04X is outside the %d bytes of memory", cpu.I, size)
+	case int(cpu.SP) > len(cpu.Stack):
+		return fmt.Errorf("failed to decode CPU state: stack pointer %d exceeds the %d-entry stack", cpu.SP, len(cpu.Stack))
+	}
+	return nil
+}
+
+// fixedMemoryState is the gob layout of builds whose memory was a fixed 4KB
+// array. Fields not listed here were diagnostics and start from their defaults.
+type fixedMemoryState struct {
+	Memory          [chip8.DefaultMemorySize]byte
+	Registers       [16]byte
+	I               uint16
+	PC              uint16
+	Display         [chip8.HiResWidth * chip8.HiResHeight]byte
+	Plane2          [chip8.HiResWidth * chip8.HiResHeight]byte
+	SelectedPlanes  byte
+	HiRes           bool
+	DelayTimer      byte
+	SoundTimer      byte
+	AudioPitch      byte
+	AudioBuffer     [16]byte
+	Stack           [16]uint16
+	SP              byte
+	StackHighWater  byte
+	Keys            [16]bool
+	DrawFlag        bool
+	ScreenCleared   bool
+	IsRunning       bool
+	Breakpoints     map[uint16]bool
+	BreakpointSkips map[uint16]int
+	CycleCount      uint64
+	CollisionCount  uint64
+	Quirks          chip8.Quirks
+	Strict          bool
+	LastError       string
+}
+
+// legacyState is the CPU layout of builds from before the SUPER-CHIP display,
+// whose Display array only held the 64x32 screen.
+type legacyState struct {
+	Memory      [chip8.DefaultMemorySize]byte
+	Registers   [16]byte
+	I           uint16
+	PC          uint16
+	Display     [chip8.DisplayWidth * chip8.DisplayHeight]byte
+	DelayTimer  byte
+	SoundTimer  byte
+	Stack       [16]uint16
+	SP          byte
+	Keys        [16]bool
+	DrawFlag    bool
+	IsRunning   bool
+	Breakpoints map[uint16]bool
+}
+
+// decodeGob restores a gob state, falling back to the fixed-memory and then the
+// legacy layout when the current one does not match.
+func decodeGob(data []byte) (*chip8.Chip8, error) {
+	cpu, err := decodeGobLayout(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkPointers(cpu); err != nil {
+		return nil, err
+	}
+	return cpu, nil
+}
+
+// decodeGobLayout decodes a gob state in whichever layout matches.
+func decodeGobLayout(data []byte) (*chip8.Chip8, error) {
+	var cpu chip8.Chip8
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&cpu); err == nil {
+		if err := checkMemorySize(&cpu); err != nil {
+			return nil, err
+		}
+		migrateGob(&cpu)
+		return &cpu, nil
+	}
+	var fixed fixedMemoryState
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&fixed); err == nil {
+		cpu := migrateFixedMemory(&fixed)
+		migrateGob(cpu)
+		return cpu, nil
+	}
+	var old legacyState
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&old); err != nil {
+		return nil, fmt.Errorf("failed to decode CPU state: not a recognised save state (%v)", err)
+	}
+	return migrateLegacy(&old), nil
+}
+
+// migrateGob fills fields that gob states written before they existed leave at
+// zero. gob omits zero values, so a zero here means the field was missing or
+// genuinely zero; the defaults are only applied where zero is not a usable state.
+func migrateGob(cpu *chip8.Chip8) {
+	if cpu.SelectedPlanes == 0 {
+		cpu.SelectedPlanes = 1
+	}
+	if cpu.AudioPitch == 0 && cpu.AudioBuffer == [16]byte{} {
+		cpu.AudioPitch = chip8.DefaultAudioPitch
+		cpu.AudioBuffer = chip8.DefaultAudioPattern
+	}
+	if cpu.Quirks == (chip8.Quirks{}) {
+		cpu.Quirks = chip8.DefaultQuirks()
+	}
+}
+
+// migrateFixedMemory converts a fixed-memory state into the current layout,
+// starting from chip8.New so that unlisted fields have their defaults.
+func migrateFixedMemory(old *fixedMemoryState) *chip8.Chip8 {
+	cpu := chip8.New()
+	copy(cpu.Memory, old.Memory[:])
+	cpu.Registers = old.Registers
+	cpu.I = old.I
+	cpu.PC = old.PC
+	cpu.Display = old.Display
+	cpu.Plane2 = old.Plane2
+	cpu.SelectedPlanes = old.SelectedPlanes
+	cpu.HiRes = old.HiRes
+	cpu.DelayTimer = old.DelayTimer
+	cpu.SoundTimer = old.SoundTimer
+	cpu.AudioPitch = old.AudioPitch
+	cpu.AudioBuffer = old.AudioBuffer
+	cpu.Stack = old.Stack
+	cpu.SP = old.SP
+	cpu.StackHighWater = old.StackHighWater
+	cpu.Keys = old.Keys
+	cpu.DrawFlag = old.DrawFlag
+	cpu.ScreenCleared = old.ScreenCleared
+	cpu.IsRunning = old.IsRunning
+	if old.Breakpoints != nil {
+		cpu.Breakpoints = old.Breakpoints
+	}
+	cpu.BreakpointSkips = old.BreakpointSkips
+	cpu.CycleCount = old.CycleCount
+	cpu.CollisionCount = old.CollisionCount
+	cpu.Quirks = old.Quirks
+	cpu.Strict = old.Strict
+	cpu.LastError = old.LastError
+	return cpu
+}
+
+// migrateLegacy converts a legacy state into the current layout, starting from
+// chip8.New so that every newer field has its default. The old 64x32 display
+// maps onto the start of the low-res frame, which uses the same layout.
+func migrateLegacy(old *legacyState) *chip8.Chip8 {
+	cpu := chip8.New()
+	copy(cpu.Memory, old.Memory[:])
+	cpu.Registers = old.Registers
+	cpu.I = old.I
+	cpu.PC = old.PC
+	copy(cpu.Display[:], old.Display[:])
+	cpu.DelayTimer = old.DelayTimer
+	cpu.SoundTimer = old.SoundTimer
+	cpu.Stack = old.Stack
+	cpu.SP = old.SP
+	cpu.Keys = old.Keys
+	cpu.DrawFlag = true
+	cpu.IsRunning = old.IsRunning
+	if old.Breakpoints != nil {
+		cpu.Breakpoints = old.Breakpoints
+	}
+	return cpu
+}
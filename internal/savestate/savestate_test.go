@@ -0,0 +1,315 @@
+package savestate
+
+import (
+	"bytes"
+	"chip8-wails/chip8"
+	"encoding/gob"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// runningCPU returns a CPU partway through a small program, with state spread
+// across registers, memory, display, timers and stack.
+func runningCPU() *chip8.Chip8 {
+	c := chip8.New()
+	c.LoadROM([]byte{
+		0x60, 0x2A, // LD V0, 0x2A
+		0xF0, 0x15, // LD DT, V0
+		0xA3, 0x00, // LD I, 0x300
+		0xF0, 0x33, // LD B, V0
+		0xD0, 0x05, // DRW V0, V0, 5
+		0x22, 0x0E, // CALL 0x20E
+		0x00, 0x00,
+		0x61, 0x07, // 0x20E: LD V1, 0x07
+	})
+	c.Quirks.ClipSprites = false
+	c.Breakpoints[0x210] = true
+	c.IsRunning = true
+	for i := 0; i < 7; i++ {
+		c.EmulateCycle()
+	}
+	c.UpdateTimers()
+	return c
+}
+
+// assertSameState compares the fields a save state must preserve.
+func assertSameState(t *testing.T, want, got *chip8.Chip8) {
+	t.Helper()
+	checks := []struct {
+		name      string
+		want, got interface{}
+	}{
+		{"Memory", want.Memory, got.Memory},
+		{"Registers", want.Registers, got.Registers},
+		{"Display", want.Display, got.Display},
+		{"PC", want.PC, got.PC},
+		{"I", want.I, got.I},
+		{"SP", want.SP, got.SP},
+		{"Stack", want.Stack, got.Stack},
+		{"DelayTimer", want.DelayTimer, got.DelayTimer},
+		{"SoundTimer", want.SoundTimer, got.SoundTimer},
+		{"Quirks", want.Quirks, got.Quirks},
+		{"Breakpoints", want.Breakpoints, got.Breakpoints},
+		{"CycleCount", want.CycleCount, got.CycleCount},
+		{"FrameCount", want.FrameCount, got.FrameCount},
+		{"IsRunning", want.IsRunning, got.IsRunning},
+	}
+	for _, c := range checks {
+		if !reflect.DeepEqual(c.want, c.got) {
+			t.Errorf("%s mismatch: got %v, want %v", c.name, c.got, c.want)
+		}
+	}
+}
+
+/*
+TestJSONRoundTrip checks that a running state survives EncodeJSON and Decode.
+*/
+func TestJSONRoundTrip(t *testing.T) {
+	in := runningCPU()
+	data, err := EncodeJSON(in)
+	if err != nil {
+		t.Fatalf("EncodeJSON failed: %v", err)
+	}
+	out, err := Decode(data)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	assertSameState(t, in, out)
+}
+
+/*
+TestGobStillDecodes checks that states in the original gob format still load.
+*/
+func TestGobStillDecodes(t *testing.T) {
+	in := runningCPU()
+	data, err := EncodeGob(in)
+	if err != nil {
+		t.Fatalf("EncodeGob failed: %v", err)
+	}
+	out, err := Decode(data)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	assertSameState(t, in, out)
+}
+
+/*
+TestDecodeRejectsUnknownVersion checks that a state from a newer format version
+is refused with an error naming its version and the core that wrote it.
+*/
+func TestDecodeRejectsUnknownVersion(t *testing.T) {
+	_, err := Decode([]byte(`{"version":99,"core":"9.9.9","cpu":{}}`))
+	var verr *VersionError
+	if !errors.As(err, &verr) {
+		t.Fatalf("Expected a VersionError, got %v", err)
+	}
+	if msg := err.Error(); !strings.Contains(msg, "version 99") || !strings.Contains(msg, "9.9.9") {
+		t.Errorf("Expected the version and core in the error, got %q", msg)
+	}
+}
+
+/*
+TestDecodeRejectsOutOfRangePointers checks that states whose PC, I or stack
+pointer would index past memory or the stack are refused rather than loaded, in
+both the JSON and gob formats.
+*/
+func TestDecodeRejectsOutOfRangePointers(t *testing.T) {
+	for _, state := range []string{
+		`{"version":3,"cpu":{"PC":65000,"IsRunning":true}}`,
+		`{"version":3,"cpu":{"I":4096}}`,
+		`{"version":3,"cpu":{"SP":40}}`,
+	} {
+		if _, err := Decode([]byte(state)); err == nil {
+			t.Errorf("Expected %s to be refused", state)
+		}
+	}
+
+	cpu := chip8.New()
+	cpu.SP = 17
+	data, err := EncodeGob(cpu)
+	if err != nil {
+		t.Fatalf("EncodeGob failed: %v", err)
+	}
+	if _, err := Decode(data); err == nil || !strings.Contains(err.Error(), "stack pointer 17") {
+		t.Errorf("Expected the gob state to be refused for its stack pointer, got %v", err)
+	}
+}
+
+/*
+TestDecodeVersion1FillsNewFields checks that a version 1 JSON state, which has no
+core version, loads and that fields it lacks keep their defaults.
+*/
+func TestDecodeVersion1FillsNewFields(t *testing.T) {
+	cpu, err := Decode([]byte(`{"version":1,"cpu":{"PC":772,"Registers":[1,2,3,4,5,6,7,8,9,10,11,12,13,14,15,16]}}`))
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if cpu.PC != 772 || cpu.Registers[0xF] != 16 {
+		t.Errorf("Expected the saved PC and registers, got PC %d VF %d", cpu.PC, cpu.Registers[0xF])
+	}
+	if cpu.SelectedPlanes != 1 || cpu.AudioBuffer != chip8.DefaultAudioPattern || cpu.Quirks != chip8.DefaultQuirks() {
+		t.Error("Expected missing fields to keep their defaults")
+	}
+}
+
+/*
+TestDecodeMigratesLegacyGob checks that a gob state from before the hi-res
+display was added loads, with its 64x32 display carried over and newer fields
+given their defaults.
+*/
+func TestDecodeMigratesLegacyGob(t *testing.T) {
+	old := legacyState{PC: 0x246, I: 0x300, IsRunning: true}
+	old.Registers[0x3] = 0x42
+	old.Display[chip8.DisplayWidth+5] = 1
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(old); err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	cpu, err := Decode(buf.Bytes())
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if cpu.PC != 0x246 || cpu.I != 0x300 || cpu.Registers[0x3] != 0x42 {
+		t.Errorf("Expected the saved registers, got PC 0x%X I 0x%X V3 0x%X", cpu.PC, cpu.I, cpu.Registers[0x3])
+	}
+	if cpu.Display[chip8.DisplayWidth+5] != 1 || cpu.HiRes {
+		t.Error("Expected the low-res display to carry over")
+	}
+	if cpu.SelectedPlanes != 1 || cpu.Quirks != chip8.DefaultQuirks() || cpu.Breakpoints == nil {
+		t.Error("Expected newer fields to get their defaults")
+	}
+}
+
+/*
+TestDecodeMigratesFixedMemoryGob checks that a gob state from builds with a fixed
+4KB memory array still loads.
+*/
+func TestDecodeMigratesFixedMemoryGob(t *testing.T) {
+	old := fixedMemoryState{PC: 0x246, I: 0x300, HiRes: true, Quirks: chip8.Quirks{ShiftFlagLast: true}}
+	old.Memory[0x300] = 0x42
+	old.Display[chip8.HiResWidth+5] = 1
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(old); err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	cpu, err := Decode(buf.Bytes())
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if cpu.MemorySize() != chip8.DefaultMemorySize || cpu.Memory[0x300] != 0x42 {
+		t.Errorf("Expected 4KB of memory with the saved byte, got %d bytes", cpu.MemorySize())
+	}
+	if cpu.PC != 0x246 || !cpu.HiRes || cpu.Display[chip8.HiResWidth+5] != 1 || !cpu.Quirks.ShiftFlagLast {
+		t.Error("Expected the saved registers, display and quirks to carry over")
+	}
+}
+
+/*
+TestMemorySizeRoundTrip checks that a 64KB state keeps its size through JSON, and
+that a state whose memory disagrees with the recorded size is rejected.
+*/
+func TestMemorySizeRoundTrip(t *testing.T) {
+	c := chip8.New(chip8.WithMemorySize(chip8.XOCHIPMemorySize))
+	c.Memory[0xF000] = 0x99
+	data, err := EncodeJSON(c)
+	if err != nil {
+		t.Fatalf("EncodeJSON failed: %v", err)
+	}
+	got, err := Decode(data)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if got.MemorySize() != chip8.XOCHIPMemorySize || got.Memory[0xF000] != 0x99 {
+		t.Errorf("Expected 64KB of memory with the saved byte, got %d bytes", got.MemorySize())
+	}
+
+	bad := strings.Replace(string(data), `"memorySize":65536`, `"memorySize":4096`, 1)
+	if _, err := Decode([]byte(bad)); err == nil {
+		t.Error("Expected a memory size mismatch to be rejected")
+	}
+}
+
+/*
+TestDecodeVersion2MemoryArray checks that version 2 states, which stored memory
+as an array of numbers, still load as 4KB of memory.
+*/
+func TestDecodeVersion2MemoryArray(t *testing.T) {
+	values := make([]string, chip8.DefaultMemorySize)
+	for i := range values {
+		values[i] = "0"
+	}
+	values[0x300] = "66"
+	data := `{"version":2,"core":"1.1.0","cpu":{"Memory":[` + strings.Join(values, ",") + `]}}`
+	cpu, err := Decode([]byte(data))
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if cpu.MemorySize() != chip8.DefaultMemorySize || cpu.Memory[0x300] != 66 {
+		t.Errorf("Expected 4KB of memory with the saved byte, got %d bytes", cpu.MemorySize())
+	}
+}
+
+/*
+TestJSONRoundTripTrace checks that the execution trace is saved while tracing is
+on, and that states without one leave tracing off.
+*/
+func TestJSONRoundTripTrace(t *testing.T) {
+	c := chip8.New()
+	c.SetTracing(true)
+	copy(c.Memory[chip8.ProgramStart:], []byte{0x60, 0x01, 0x61, 0x02})
+	c.IsRunning = true
+	c.EmulateCycle()
+	c.EmulateCycle()
+
+	data, err := EncodeJSON(c)
+	if err != nil {
+		t.Fatalf("EncodeJSON failed: %v", err)
+	}
+	got, err := Decode(data)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if !reflect.DeepEqual(got.Trace(), c.Trace()) {
+		t.Errorf("Expected the trace to round-trip, got %+v", got.Trace())
+	}
+
+	data, err = EncodeJSON(chip8.New())
+	if err != nil {
+		t.Fatalf("EncodeJSON failed: %v", err)
+	}
+	if got, _ := Decode(data); got.Trace() != nil {
+		t.Error("Expected no trace for a state saved with tracing off")
+	}
+}
+
+/*
+TestRandomStreamSurvivesSaveState checks that a restored CPU draws the same RND
+values the original would have drawn next.
+*/
+func TestRandomStreamSurvivesSaveState(t *testing.T) {
+	in := chip8.New(chip8.WithSeed(5))
+	in.LoadROM([]byte{0xC0, 0xFF, 0xC1, 0xFF, 0xC2, 0xFF, 0xC3, 0xFF})
+	in.IsRunning = true
+	in.EmulateCycle()
+	in.EmulateCycle()
+
+	data, err := EncodeJSON(in)
+	if err != nil {
+		t.Fatalf("EncodeJSON failed: %v", err)
+	}
+	out, err := Decode(data)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	in.EmulateCycle()
+	in.EmulateCycle()
+	out.EmulateCycle()
+	out.EmulateCycle()
+	if out.Registers != in.Registers {
+		t.Errorf("Expected the restored CPU to continue the RND stream, got %v, want %v", out.Registers, in.Registers)
+	}
+}
@@ -0,0 +1,126 @@
+package settings
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// KeyCount is the number of keys on the CHIP-8 hex keypad.
+const KeyCount = 16
+
+// GamepadButtons names the buttons of a standard-mapping gamepad, in the
+// Gamepad API's button index order.
+var GamepadButtons = []string{
+	"a", "b", "x", "y", "lb", "rb", "lt", "rt",
+	"select", "start", "ls", "rs", "up", "down", "left", "right",
+}
+
+/*
+DefaultKeyMap returns the default keyboard bindings: the left-hand 4x4 block
+1234/QWER/ASDF/ZXCV laid out like the COSMAC VIP hex keypad.
+*/
+func DefaultKeyMap() map[string]int {
+	return map[string]int{
+		"1": 0x1, "2": 0x2, "3": 0x3, "4": 0xc,
+		"q": 0x4, "w": 0x5, "e": 0x6, "r": 0xd,
+		"a": 0x7, "s": 0x8, "d": 0x9, "f": 0xe,
+		"z": 0xa, "x": 0x0, "c": 0xb, "v": 0xf,
+	}
+}
+
+/*
+DefaultGamepadMap returns the default gamepad bindings. The d-pad drives the
+same keys as WASD on the default keyboard map and A and B act as E and Q, which
+covers most games' controls; the remaining buttons fill in the rest of the
+keypad so every CHIP-8 key is reachable.
+*/
+func DefaultGamepadMap() map[string]int {
+	return map[string]int{
+		"up": 0x5, "left": 0x7, "down": 0x8, "right": 0x9,
+		"a": 0x6, "b": 0x4, "x": 0x1, "y": 0x2,
+		"lb": 0x3, "rb": 0xc, "lt": 0xd, "rt": 0xe,
+		"select": 0xa, "start": 0xf, "ls": 0x0, "rs": 0xb,
+	}
+}
+
+/*
+ValidateKeyMap checks that every keyboard key in the map is named, that each
+maps to a CHIP-8 key between 0x0 and 0xF, and that all 16 CHIP-8 keys are bound
+to at least one keyboard key. Several keyboard keys may share a CHIP-8 key.
+*/
+func ValidateKeyMap(keyMap map[string]int) error {
+	for keyboardKey, chip8Key := range keyMap {
+		if keyboardKey == "" {
+			return fmt.Errorf("key map has an empty keyboard key (bound to CHIP-8 key %d)", chip8Key)
+		}
+	}
+	return validateBindings("key map", "keyboard key", keyMap)
+}
+
+/*
+ValidateGamepadMap checks a gamepad map the same way as a key map, except that
+each button must be one of GamepadButtons.
+*/
+func ValidateGamepadMap(gamepadMap map[string]int) error {
+	for button := range gamepadMap {
+		if !isGamepadButton(button) {
+			return fmt.Errorf("gamepad map has unknown button %q, expected one of %s", button, strings.Join(GamepadButtons, ", "))
+		}
+	}
+	return validateBindings("gamepad map", "gamepad button", gamepadMap)
+}
+
+/*
+validateBindings checks that every input in bindings maps to a CHIP-8 key
+between 0x0 and 0xF and that all 16 CHIP-8 keys are bound.
+*/
+func validateBindings(mapName, inputName string, bindings map[string]int) error {
+	var reachable [KeyCount]bool
+	for input, chip8Key := range bindings {
+		if chip8Key < 0 || chip8Key >= KeyCount {
+			return fmt.Errorf("%s %q maps to %d, expected a CHIP-8 key from 0x0 to 0xF", inputName, input, chip8Key)
+		}
+		reachable[chip8Key] = true
+	}
+	var missing []string
+	for chip8Key, ok := range reachable {
+		if !ok {
+			missing = append(missing, fmt.Sprintf("0x%X", chip8Key))
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%s leaves CHIP-8 keys unbound: %s", mapName, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+/*
+isGamepadButton reports whether name is one of GamepadButtons.
+*/
+func isGamepadButton(name string) bool {
+	for _, button := range GamepadButtons {
+		if button == name {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+ReverseKeyMap returns the keyboard keys bound to each CHIP-8 key, sorted so the
+result is stable. CHIP-8 keys nothing is bound to, and out-of-range entries, are
+left out.
+*/
+func ReverseKeyMap(keyMap map[string]int) map[int][]string {
+	reverse := make(map[int][]string)
+	for keyboardKey, chip8Key := range keyMap {
+		if chip8Key >= 0 && chip8Key < KeyCount {
+			reverse[chip8Key] = append(reverse[chip8Key], keyboardKey)
+		}
+	}
+	for _, keys := range reverse {
+		sort.Strings(keys)
+	}
+	return reverse
+}
@@ -1,6 +1,7 @@
 package settings
 
 import (
+	"chip8-wails/chip8"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -15,6 +16,63 @@ type Settings struct {
 	KeyMap         map[string]int `json:"keyMap"`
 	PixelScale     int            `json:"pixelScale"`
 	RomsPath       string         `json:"romsPath"`
+	// GamepadMap binds standard gamepad buttons (see GamepadButtons) to CHIP-8 keys.
+	GamepadMap map[string]int `json:"gamepadMap"`
+	// Palette holds the display colours by pixel value: background, plane 1, plane 2, both planes.
+	// Index 1 is kept in step with DisplayColor.
+	Palette []string `json:"palette"`
+	// ScanROMSubfolders lists ROMs in subdirectories of RomsPath as well as at its top level.
+	ScanROMSubfolders bool `json:"scanRomSubfolders"`
+	// MaxEventsPerSecond caps display/debug event emission; negative disables the cap.
+	MaxEventsPerSecond int `json:"maxEventsPerSecond"`
+	// CoalesceClears holds back a cleared frame briefly so it is sent together with its redraw.
+	CoalesceClears bool `json:"coalesceClears"`
+	// WindowDecorated shows native window chrome instead of the frameless window.
+	WindowDecorated bool `json:"windowDecorated"`
+	// StripROMHeaders removes recognised tool headers (e.g. HP48) from ROM files before loading.
+	StripROMHeaders bool `json:"stripRomHeaders"`
+	// Quirks selects interpreter-specific opcode behaviour; defaults to the COSMAC VIP.
+	Quirks chip8.Quirks `json:"quirks"`
+	// HaltOnUnknownOpcode stops emulation at an unknown opcode instead of skipping it.
+	HaltOnUnknownOpcode bool `json:"haltOnUnknownOpcode"`
+	// HaltOnMemoryOverflow stops emulation when a sprite draw or register store/load runs past the end of memory instead of wrapping.
+	HaltOnMemoryOverflow bool `json:"haltOnMemoryOverflow"`
+	// QuirkPreset names the preset the quirks were chosen from; empty means they were set individually.
+	QuirkPreset chip8.QuirkPreset `json:"quirkPreset"`
+	// MachineClockSpeeds is the clock speed (Hz) applied when switching to each machine type.
+	MachineClockSpeeds map[string]int `json:"machineClockSpeeds"`
+	// ROMClockSpeeds holds per-ROM clock speeds (Hz) that take precedence over the machine default.
+	ROMClockSpeeds map[string]int `json:"romClockSpeeds"`
+	// FrameBudgetMs caps the wall-clock time spent running instructions per frame; negative disables it.
+	FrameBudgetMs int `json:"frameBudgetMs"`
+	// PlaylistSeconds is how long each playlist ROM plays before advancing; negative disables auto-advance.
+	PlaylistSeconds int `json:"playlistSeconds"`
+	// AutoSaveSeconds is how often a rolling auto-save state is written; 0 disables auto-saving.
+	AutoSaveSeconds int `json:"autoSaveSeconds"`
+	// RewindDepth is how many instructions the debugger can step back through; negative disables rewinding.
+	RewindDepth int `json:"rewindDepth"`
+	// CycleAccurateTimers ticks the timers every ClockSpeed/60 executed instructions instead of once per frame,
+	// and updates the XO-CHIP tone at every tick, so pitch and pattern changes play exactly when the ROM makes them.
+	CycleAccurateTimers bool `json:"cycleAccurateTimers"`
+	// SlowMotionOnCollision briefly slows emulation after every sprite collision, as a debugging aid.
+	SlowMotionOnCollision bool `json:"slowMotionOnCollision"`
+	// RunUntilMaxCycles bounds how many instructions a debugger run-until may execute before giving up.
+	RunUntilMaxCycles int `json:"runUntilMaxCycles"`
+	// AllowROMURLs enables loading ROMs straight from http(s) links; off by default so the app stays offline.
+	AllowROMURLs bool `json:"allowRomUrls"`
+}
+
+/*
+DefaultMachineClockSpeeds returns the default clock speed for each machine type.
+Later platforms ran on faster hardware and their games expect more instructions
+per frame.
+*/
+func DefaultMachineClockSpeeds() map[string]int {
+	return map[string]int{
+		string(chip8.MachineCHIP8):  700,
+		string(chip8.MachineSCHIP):  1000,
+		string(chip8.MachineXOCHIP): 1500,
+	}
 }
 
 /*
@@ -22,17 +80,22 @@ DefaultSettings returns a new Settings object with default values.
 */
 func DefaultSettings() Settings {
 	return Settings{
-		ClockSpeed:     700,
-		DisplayColor:   "#33FF00",
-		ScanlineEffect: false,
-		PixelScale:     10,
-		RomsPath:       "./roms",
-		KeyMap: map[string]int{
-			"1": 0x1, "2": 0x2, "3": 0x3, "4": 0xc,
-			"q": 0x4, "w": 0x5, "e": 0x6, "r": 0xd,
-			"a": 0x7, "s": 0x8, "d": 0x9, "f": 0xe,
-			"z": 0xa, "x": 0x0, "c": 0xb, "v": 0xf,
-		},
+		ClockSpeed:         700,
+		DisplayColor:       "#33FF00",
+		Palette:            DefaultPalette(),
+		ScanlineEffect:     false,
+		PixelScale:         10,
+		RomsPath:           "./roms",
+		ScanROMSubfolders:  true,
+		MaxEventsPerSecond: 60,
+		FrameBudgetMs:      8,
+		PlaylistSeconds:    60,
+		RewindDepth:        600,
+		RunUntilMaxCycles:  1000000,
+		Quirks:             chip8.DefaultQuirks(),
+		MachineClockSpeeds: DefaultMachineClockSpeeds(),
+		KeyMap:             DefaultKeyMap(),
+		GamepadMap:         DefaultGamepadMap(),
 	}
 }
 
@@ -62,9 +125,14 @@ func (m *Manager) Load() (Settings, error) {
 		return Settings{}, fmt.Errorf("failed to read settings file: %w", err)
 	}
 	var s Settings
+	var present struct {
+		Quirks *json.RawMessage `json:"quirks"`
+	}
 	if err := json.Unmarshal(data, &s); err != nil {
 		fmt.Printf("Warning: could not parse settings.json, falling back to defaults: %v\n", err)
 		s = DefaultSettings() // Use defaults if parsing fails
+	} else if json.Unmarshal(data, &present) == nil && present.Quirks == nil {
+		s.Quirks = chip8.DefaultQuirks()
 	}
 	// Ensure new fields have default values if loading old settings file
 	if s.PixelScale == 0 {
@@ -73,6 +141,45 @@ func (m *Manager) Load() (Settings, error) {
 	if s.RomsPath == "" {
 		s.RomsPath = "./roms"
 	}
+	if ValidatePalette(s.Palette) != nil {
+		s.Palette = nil
+	}
+	s.Palette = FillPalette(s.Palette, s.DisplayColor)
+	s.DisplayColor = s.Palette[1]
+	if s.MaxEventsPerSecond == 0 {
+		s.MaxEventsPerSecond = 60
+	}
+	if s.FrameBudgetMs == 0 {
+		s.FrameBudgetMs = 8
+	}
+	if s.PlaylistSeconds == 0 {
+		s.PlaylistSeconds = 60
+	}
+	if s.RewindDepth == 0 {
+		s.RewindDepth = 600
+	}
+	if s.RunUntilMaxCycles <= 0 {
+		s.RunUntilMaxCycles = 1000000
+	}
+	if quirks, ok := s.QuirkPreset.Quirks(); ok {
+		s.Quirks = quirks
+	} else {
+		s.QuirkPreset = ""
+	}
+	if s.MachineClockSpeeds == nil {
+		s.MachineClockSpeeds = DefaultMachineClockSpeeds()
+	}
+	// A bad map on disk would make every later save fail validation
+	if err := ValidateKeyMap(s.KeyMap); err != nil {
+		fmt.Printf("Warning: invalid key map in settings.json, using the default: %v\n", err)
+		s.KeyMap = DefaultKeyMap()
+	}
+	if s.GamepadMap == nil {
+		s.GamepadMap = DefaultGamepadMap()
+	} else if err := ValidateGamepadMap(s.GamepadMap); err != nil {
+		fmt.Printf("Warning: invalid gamepad map in settings.json, using the default: %v\n", err)
+		s.GamepadMap = DefaultGamepadMap()
+	}
 	return s, nil
 }
 
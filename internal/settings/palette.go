@@ -0,0 +1,55 @@
+package settings
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// PaletteSize is the number of display colours: the background, the first and
+// second XO-CHIP planes, and pixels set in both planes.
+const PaletteSize = 4
+
+var hexColor = regexp.MustCompile(`^#[0-9A-Fa-f]{6}$`)
+
+/*
+DefaultPalette returns the default display colours, indexed by pixel value:
+black background, classic green foreground, orange for the second XO-CHIP plane
+and white where both planes are set.
+*/
+func DefaultPalette() []string {
+	return []string{"#000000", "#33FF00", "#FF5500", "#FFFFFF"}
+}
+
+/*
+ValidatePalette checks that a palette has at most PaletteSize entries and that
+each is a "#RRGGBB" colour. Empty entries are allowed and mean the default.
+*/
+func ValidatePalette(palette []string) error {
+	if len(palette) > PaletteSize {
+		return fmt.Errorf("palette has %d colours, at most %d are supported", len(palette), PaletteSize)
+	}
+	for i, c := range palette {
+		if c != "" && !hexColor.MatchString(c) {
+			return fmt.Errorf("palette colour %d is %q, expected #RRGGBB", i, c)
+		}
+	}
+	return nil
+}
+
+/*
+FillPalette returns a full palette from a possibly short one: missing or empty
+entries take their defaults, with the foreground (index 1) defaulting to fg so
+a single DisplayColor keeps working.
+*/
+func FillPalette(palette []string, fg string) []string {
+	full := DefaultPalette()
+	if hexColor.MatchString(fg) {
+		full[1] = fg
+	}
+	for i, c := range palette {
+		if i < PaletteSize && c != "" {
+			full[i] = c
+		}
+	}
+	return full
+}
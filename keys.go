@@ -0,0 +1,85 @@
+package main
+
+/*
+keyEventBuffer is how many key events can wait for the emulation loop before a
+sender has to apply the backlog itself.
+*/
+const keyEventBuffer = 64
+
+/*
+keyEvent is a press or release of a CHIP-8 key (0x0-0xF).
+*/
+type keyEvent struct {
+	key  int
+	down bool
+}
+
+/*
+keyQueue carries key events from the frontend to the emulation loop, which
+applies them between instructions. Events come out in the order they were
+pushed. A release arriving in the same batch as its own press is held back to
+the next batch, so every press is seen by at least one instruction however
+briefly the key was down.
+
+push may be called from any goroutine; take must be called with the App mutex
+held, which guards the events held back.
+*/
+type keyQueue struct {
+	events  chan keyEvent
+	pending []keyEvent
+}
+
+/*
+newKeyQueue returns a queue buffering up to size events.
+*/
+func newKeyQueue(size int) *keyQueue {
+	return &keyQueue{events: make(chan keyEvent, size)}
+}
+
+/*
+push queues an event without blocking. It reports false if the buffer is full,
+in which case the caller should apply the backlog with take and try again.
+*/
+func (q *keyQueue) push(ev keyEvent) bool {
+	select {
+	case q.events <- ev:
+		return true
+	default:
+		return false
+	}
+}
+
+/*
+take drains the buffer and returns the events to apply at this instruction
+boundary, oldest first. It stops before a release of a key pressed earlier in
+the same batch; that release and everything after it wait for the next call.
+*/
+func (q *keyQueue) take() []keyEvent {
+drain:
+	for {
+		select {
+		case ev := <-q.events:
+			q.pending = append(q.pending, ev)
+		default:
+			break drain
+		}
+	}
+	var pressed [16]bool
+	n := 0
+	for _, ev := range q.pending {
+		if !ev.down && pressed[ev.key] {
+			break
+		}
+		if ev.down {
+			pressed[ev.key] = true
+		}
+		n++
+	}
+	if n == 0 {
+		return nil
+	}
+	ready := make([]keyEvent, n)
+	copy(ready, q.pending)
+	q.pending = append(q.pending[:0], q.pending[n:]...)
+	return ready
+}
@@ -0,0 +1,47 @@
+package main
+
+import "testing"
+
+/*
+TestKeyQueueOrdering checks that key events come out in the order they were
+pushed and that a release in the same batch as its press is held back until the
+next batch.
+*/
+func TestKeyQueueOrdering(t *testing.T) {
+	q := newKeyQueue(8)
+	q.push(keyEvent{key: 1, down: true})
+	q.push(keyEvent{key: 2, down: true})
+	q.push(keyEvent{key: 1, down: false})
+	q.push(keyEvent{key: 3, down: true})
+
+	first := q.take()
+	want := []keyEvent{{key: 1, down: true}, {key: 2, down: true}}
+	if len(first) != len(want) || first[0] != want[0] || first[1] != want[1] {
+		t.Fatalf("Expected %v, got %v", want, first)
+	}
+	second := q.take()
+	want = []keyEvent{{key: 1, down: false}, {key: 3, down: true}}
+	if len(second) != len(want) || second[0] != want[0] || second[1] != want[1] {
+		t.Errorf("Expected the held-back release then the next press %v, got %v", want, second)
+	}
+	if rest := q.take(); rest != nil {
+		t.Errorf("Expected an empty queue, got %v", rest)
+	}
+}
+
+/*
+TestKeyQueueFull checks that push reports a full buffer instead of blocking.
+*/
+func TestKeyQueueFull(t *testing.T) {
+	q := newKeyQueue(1)
+	if !q.push(keyEvent{key: 1, down: true}) {
+		t.Fatal("Expected the first push to succeed")
+	}
+	if q.push(keyEvent{key: 2, down: true}) {
+		t.Error("Expected a push to a full buffer to fail")
+	}
+	q.take()
+	if !q.push(keyEvent{key: 2, down: true}) {
+		t.Error("Expected a push to succeed once the buffer was drained")
+	}
+}
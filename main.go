@@ -1,6 +1,7 @@
 package main
 
 import (
+	"chip8-wails/internal/settings"
 	"embed"
 	"encoding/json" // Import the JSON package
 	"log"           // Import log
@@ -38,6 +39,13 @@ func main() {
 	}
 	app.wailsInfo = wailsInfo // Assign the parsed info
 
+	// Window chrome can't be changed after creation, so read it up front
+	startupSettings, err := app.settingsManager.Load()
+	if err != nil {
+		log.Printf("Could not load settings for window setup, using defaults: %v", err)
+		startupSettings = settings.DefaultSettings()
+	}
+
 	// Create application with options
 	err = wails.Run(&options.App{
 		Title:     wailsInfo.Info.ProductName, // Use ProductName for the title
@@ -45,12 +53,15 @@ func main() {
 		Height:    1080,
 		MinWidth:  800,
 		MinHeight: 600,
-		Frameless: true, // Frameless window
+		Frameless: !startupSettings.WindowDecorated, // Frameless unless native chrome was requested
 		AssetServer: &assetserver.Options{
 			Assets: assets,
 		},
 		BackgroundColour: &options.RGBA{R: 44, G: 62, B: 80, A: 1}, // Matches bg-[#2c3e50]
 		OnStartup:        app.startup,
+		DragAndDrop: &options.DragAndDrop{
+			EnableFileDrop: true,
+		},
 		Bind: []interface{}{
 			app,
 		},
@@ -0,0 +1,16 @@
+package main
+
+/*
+changeMask compares two equally sized memory slices and returns a bitmap with one
+bit per byte, set where the bytes differ. Bit i%8 (least significant first) of
+mask byte i/8 corresponds to byte i.
+*/
+func changeMask(prev, cur []byte) []byte {
+	mask := make([]byte, (len(cur)+7)/8)
+	for i := range cur {
+		if i >= len(prev) || prev[i] != cur[i] {
+			mask[i/8] |= 1 << (i % 8)
+		}
+	}
+	return mask
+}
@@ -0,0 +1,26 @@
+package main
+
+import "testing"
+
+/*
+TestChangeMaskSingleWrite checks that changing one byte sets exactly that byte's bit.
+*/
+func TestChangeMaskSingleWrite(t *testing.T) {
+	prev := make([]byte, 32)
+	cur := make([]byte, 32)
+	cur[11] = 0x42
+
+	mask := changeMask(prev, cur)
+	if len(mask) != 4 {
+		t.Fatalf("Expected a 4 byte mask, got %d", len(mask))
+	}
+	for i, b := range mask {
+		want := byte(0)
+		if i == 1 {
+			want = 1 << 3
+		}
+		if b != want {
+			t.Errorf("Mask byte %d: expected %08b, got %08b", i, want, b)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import "chip8-wails/chip8"
+
+// memoryRegion is a labelled, inclusive span of CHIP-8 memory.
+type memoryRegion struct {
+	Start int
+	End   int
+	Type  string
+	Label string
+}
+
+/*
+memoryRegions lays out memSize bytes of memory for a ROM of romLen bytes: the
+interpreter area (with the font inside it), the program and the free space after
+it. The regions are contiguous and cover every address exactly once.
+*/
+func memoryRegions(romLen, memSize int) []memoryRegion {
+	fontEnd := chip8.FontSetStart + len(chip8.FontSet) - 1
+	regions := []memoryRegion{
+		{Start: 0x000, End: chip8.FontSetStart - 1, Type: "reserved", Label: "Interpreter"},
+		{Start: chip8.FontSetStart, End: fontEnd, Type: "font", Label: "Font"},
+		{Start: fontEnd + 1, End: chip8.ProgramStart - 1, Type: "reserved", Label: "Interpreter"},
+	}
+	next := chip8.ProgramStart
+	if romLen > 0 {
+		regions = append(regions, memoryRegion{Start: next, End: next + romLen - 1, Type: "program", Label: "Program"})
+		next += romLen
+	}
+	if last := memSize - 1; next <= last {
+		regions = append(regions, memoryRegion{Start: next, End: last, Type: "free", Label: "Free"})
+	}
+	return regions
+}
@@ -0,0 +1,44 @@
+package main
+
+import "testing"
+
+/*
+TestMemoryRegionsCoverMemory checks that the regions tile memory with no gaps or
+overlaps, with and without a ROM loaded.
+*/
+func TestMemoryRegionsCoverMemory(t *testing.T) {
+	for _, romLen := range []int{0, 2, 246, 4096 - 0x200} {
+		regions := memoryRegions(romLen, 4096)
+		next := 0
+		for _, r := range regions {
+			if r.Start != next {
+				t.Errorf("romLen=%d: %s region starts at 0x%03X, expected 0x%03X", romLen, r.Type, r.Start, next)
+			}
+			if r.End < r.Start {
+				t.Errorf("romLen=%d: %s region is empty or inverted", romLen, r.Type)
+			}
+			next = r.End + 1
+		}
+		if next != 4096 {
+			t.Errorf("romLen=%d: regions end at 0x%03X, expected 0x1000", romLen, next)
+		}
+	}
+}
+
+/*
+TestGetMemoryMapProgramRegion checks that the program region spans the loaded ROM.
+*/
+func TestGetMemoryMapProgramRegion(t *testing.T) {
+	a := NewApp()
+	a.loadROMFromData(make([]byte, 10), "blank.ch8")
+
+	for _, region := range a.GetMemoryMap() {
+		if region["type"] == "program" {
+			if region["start"] != 0x200 || region["end"] != 0x209 {
+				t.Errorf("Expected program 0x200-0x209, got %v-%v", region["start"], region["end"])
+			}
+			return
+		}
+	}
+	t.Error("Expected a program region")
+}
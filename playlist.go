@@ -0,0 +1,42 @@
+package main
+
+import "time"
+
+/*
+playlist cycles through a list of ROM paths, moving to the next one once the
+current one has played for duration. It wraps around at the end.
+*/
+type playlist struct {
+	paths    []string
+	index    int
+	duration time.Duration
+	started  time.Time
+}
+
+func newPlaylist(paths []string, duration time.Duration, now time.Time) *playlist {
+	return &playlist{paths: paths, duration: duration, started: now}
+}
+
+/*
+current returns the path of the ROM that should be playing.
+*/
+func (p *playlist) current() string {
+	return p.paths[p.index]
+}
+
+/*
+due reports whether the current ROM has played for its full duration. A
+non-positive duration disables auto-advance.
+*/
+func (p *playlist) due(now time.Time) bool {
+	return p.duration > 0 && now.Sub(p.started) >= p.duration
+}
+
+/*
+advance moves to the next ROM, restarting its play time, and returns its path.
+*/
+func (p *playlist) advance(now time.Time) string {
+	p.index = (p.index + 1) % len(p.paths)
+	p.started = now
+	return p.current()
+}
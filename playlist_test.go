@@ -0,0 +1,51 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+/*
+TestPlaylistAdvance checks auto-advance after the duration, manual skipping and
+wraparound at the end of the list.
+*/
+func TestPlaylistAdvance(t *testing.T) {
+	start := time.Unix(1000, 0)
+	p := newPlaylist([]string{"a.ch8", "b.ch8", "c.ch8"}, 30*time.Second, start)
+
+	if p.current() != "a.ch8" {
+		t.Fatalf("Expected to start with a.ch8, got %s", p.current())
+	}
+	if p.due(start.Add(29 * time.Second)) {
+		t.Error("Expected a.ch8 not to be due before 30s")
+	}
+	now := start.Add(30 * time.Second)
+	if !p.due(now) {
+		t.Fatal("Expected a.ch8 to be due after 30s")
+	}
+	if got := p.advance(now); got != "b.ch8" {
+		t.Errorf("Expected auto-advance to b.ch8, got %s", got)
+	}
+	if p.due(now.Add(time.Second)) {
+		t.Error("Expected the play time to restart on advance")
+	}
+
+	// Manual skip before the duration is up, then wrap around.
+	if got := p.advance(now.Add(5 * time.Second)); got != "c.ch8" {
+		t.Errorf("Expected manual skip to c.ch8, got %s", got)
+	}
+	if got := p.advance(now.Add(6 * time.Second)); got != "a.ch8" {
+		t.Errorf("Expected wraparound to a.ch8, got %s", got)
+	}
+}
+
+/*
+TestPlaylistZeroDurationNeverDue checks that a zero duration disables auto-advance.
+*/
+func TestPlaylistZeroDurationNeverDue(t *testing.T) {
+	start := time.Unix(0, 0)
+	p := newPlaylist([]string{"a.ch8"}, 0, start)
+	if p.due(start.Add(time.Hour)) {
+		t.Error("Expected a zero duration playlist never to auto-advance")
+	}
+}
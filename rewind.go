@@ -0,0 +1,76 @@
+package main
+
+import "chip8-wails/chip8"
+
+/*
+rewindBuffer keeps the most recent CPU snapshots, one per executed instruction,
+so the debugger can step backwards. When full, the oldest snapshot is dropped.
+*/
+type rewindBuffer struct {
+	snapshots []chip8.Chip8
+	start     int // Index of the oldest snapshot
+	count     int
+}
+
+/*
+newRewindBuffer returns a buffer holding up to depth snapshots. A non-positive
+depth disables rewinding.
+*/
+func newRewindBuffer(depth int) *rewindBuffer {
+	if depth < 0 {
+		depth = 0
+	}
+	return &rewindBuffer{snapshots: make([]chip8.Chip8, depth)}
+}
+
+/*
+push records a copy of cpu. Memory is copied into the slot's own buffer, but maps
+and other references are shared with cpu, so a snapshot captures registers,
+timers, memory and display but not debugger configuration. Pushing a CPU that has not executed anything since the last
+snapshot is a no-op, so stalled cycles do not fill the buffer.
+*/
+func (r *rewindBuffer) push(cpu *chip8.Chip8) {
+	if len(r.snapshots) == 0 {
+		return
+	}
+	if r.count > 0 && r.top().CycleCount == cpu.CycleCount {
+		return
+	}
+	if r.count == len(r.snapshots) {
+		r.start = (r.start + 1) % len(r.snapshots)
+		r.count--
+	}
+	slot := &r.snapshots[(r.start+r.count)%len(r.snapshots)]
+	memory := slot.Memory
+	*slot = *cpu
+	slot.Memory = append(memory[:0], cpu.Memory...)
+	r.count++
+}
+
+/*
+pop removes and returns the most recent snapshot. The snapshot is a deep copy,
+since the slot's memory buffer is reused by later pushes.
+*/
+func (r *rewindBuffer) pop() (chip8.Chip8, bool) {
+	if r.count == 0 {
+		return chip8.Chip8{}, false
+	}
+	snap := *r.top().Clone()
+	r.count--
+	return snap, true
+}
+
+/*
+top returns the most recent snapshot. The buffer must not be empty.
+*/
+func (r *rewindBuffer) top() *chip8.Chip8 {
+	return &r.snapshots[(r.start+r.count-1)%len(r.snapshots)]
+}
+
+/*
+clear drops all snapshots, e.g. when a different ROM is loaded.
+*/
+func (r *rewindBuffer) clear() {
+	r.start = 0
+	r.count = 0
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"chip8-wails/chip8"
+	"testing"
+)
+
+/*
+TestRewindBufferOrder checks that snapshots pop newest first, that the oldest is
+dropped once the buffer is full, and that clear empties it.
+*/
+func TestRewindBufferOrder(t *testing.T) {
+	r := newRewindBuffer(3)
+	cpu := chip8.New()
+	for i := 1; i <= 4; i++ {
+		cpu.CycleCount = uint64(i)
+		r.push(cpu)
+	}
+
+	for _, want := range []uint64{4, 3, 2} {
+		snap, ok := r.pop()
+		if !ok || snap.CycleCount != want {
+			t.Errorf("Expected snapshot at cycle %d, got %d (ok=%v)", want, snap.CycleCount, ok)
+		}
+	}
+	if _, ok := r.pop(); ok {
+		t.Error("Expected the oldest snapshot to have been dropped")
+	}
+
+	r.push(cpu)
+	r.clear()
+	if _, ok := r.pop(); ok {
+		t.Error("Expected clear to empty the buffer")
+	}
+}
+
+/*
+TestRewindBufferSkipsStalledCycles checks that pushing an unchanged CPU again
+does not add a duplicate snapshot.
+*/
+func TestRewindBufferSkipsStalledCycles(t *testing.T) {
+	r := newRewindBuffer(10)
+	cpu := chip8.New()
+	r.push(cpu)
+	r.push(cpu)
+	r.pop()
+	if _, ok := r.pop(); ok {
+		t.Error("Expected only one snapshot for an unchanged CPU")
+	}
+}
+
+/*
+TestRewindBufferCopiesMemory checks that a snapshot keeps the memory it was
+pushed with, even after the CPU and the buffer slot are written again.
+*/
+func TestRewindBufferCopiesMemory(t *testing.T) {
+	r := newRewindBuffer(1)
+	cpu := chip8.New()
+	cpu.Memory[0x300] = 1
+	r.push(cpu)
+	cpu.Memory[0x300] = 2
+
+	snap, _ := r.pop()
+	cpu.CycleCount++
+	r.push(cpu)
+	if snap.Memory[0x300] != 1 {
+		t.Errorf("Expected the snapshot to keep memory byte 1, got %d", snap.Memory[0x300])
+	}
+}
+
+/*
+TestRewindBufferZeroDepth checks that a zero depth disables rewinding.
+*/
+func TestRewindBufferZeroDepth(t *testing.T) {
+	r := newRewindBuffer(0)
+	r.push(chip8.New())
+	if _, ok := r.pop(); ok {
+		t.Error("Expected a zero-depth buffer to hold nothing")
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+/*
+adjacentIndex returns the index of the entry delta steps away from current in
+names, wrapping around at either end. If current is not in the list, stepping
+forward starts at the first entry and stepping backward at the last. It returns
+-1 for an empty list.
+*/
+func adjacentIndex(names []string, current string, delta int) int {
+	n := len(names)
+	if n == 0 {
+		return -1
+	}
+	pos := -1
+	for i, name := range names {
+		if name == current {
+			pos = i
+			break
+		}
+	}
+	if pos < 0 {
+		if delta < 0 {
+			return n - 1
+		}
+		return 0
+	}
+	return ((pos+delta)%n + n) % n
+}
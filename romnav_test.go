@@ -0,0 +1,76 @@
+package main
+
+import (
+	"chip8-wails/internal/roms"
+	"chip8-wails/internal/settings"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+/*
+TestAdjacentIndexWraps checks next/previous navigation including wraparound at
+both ends and an unknown current ROM.
+*/
+func TestAdjacentIndexWraps(t *testing.T) {
+	names := []string{"a.ch8", "b.ch8", "c.ch8"}
+	tests := []struct {
+		current string
+		delta   int
+		want    int
+	}{
+		{"a.ch8", 1, 1},
+		{"c.ch8", 1, 0},
+		{"b.ch8", -1, 0},
+		{"a.ch8", -1, 2},
+		{"missing.ch8", 1, 0},
+		{"missing.ch8", -1, 2},
+	}
+	for _, tt := range tests {
+		if got := adjacentIndex(names, tt.current, tt.delta); got != tt.want {
+			t.Errorf("adjacentIndex(%q, %d): expected %d, got %d", tt.current, tt.delta, tt.want, got)
+		}
+	}
+	if got := adjacentIndex(nil, "a.ch8", 1); got != -1 {
+		t.Errorf("Expected -1 for an empty list, got %d", got)
+	}
+}
+
+/*
+TestAdjacentROMAfterSoftReset loads a ROM from a subfolder by its path, soft
+resets it and checks that next and previous still move relative to it in the
+recursive ROM list.
+*/
+func TestAdjacentROMAfterSoftReset(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"games/a.ch8", "games/b.ch8", "top.ch8"} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte{0x00, 0xE0, 0x12, 0x00}, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	a := NewApp()
+	a.settings = settings.DefaultSettings()
+	a.romLoader = roms.NewLoader(dir)
+	a.romLoader.Recursive = true
+
+	name, err := a.LoadROMByPath(filepath.Join(dir, "games", "b.ch8"))
+	if err != nil || name != "games/b.ch8" {
+		t.Fatalf("Expected LoadROMByPath to load games/b.ch8, got %q (%v)", name, err)
+	}
+	if err := a.SoftReset(); err != nil {
+		t.Fatalf("SoftReset failed: %v", err)
+	}
+	if name, err := a.LoadNextROM(); err != nil || name != "top.ch8" {
+		t.Errorf("Expected the next ROM to be top.ch8, got %q (%v)", name, err)
+	}
+	if err := a.SoftReset(); err != nil {
+		t.Fatalf("SoftReset failed: %v", err)
+	}
+	if name, err := a.LoadPreviousROM(); err != nil || name != "games/b.ch8" {
+		t.Errorf("Expected the previous ROM to be games/b.ch8, got %q (%v)", name, err)
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"strconv"
+	"strings"
+)
+
+/*
+parseHexColor parses a CSS-style "#RRGGBB" or "#RGB" colour.
+*/
+func parseHexColor(s string) (color.RGBA, error) {
+	hex := strings.TrimPrefix(s, "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) != 6 {
+		return color.RGBA{}, fmt.Errorf("invalid colour %q", s)
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return color.RGBA{}, fmt.Errorf("invalid colour %q", s)
+	}
+	return color.RGBA{R: byte(v >> 16), G: byte(v >> 8), B: byte(v), A: 0xFF}, nil
+}
+
+/*
+renderPNG encodes a framebuffer of width*height pixels as a PNG, drawing each
+pixel as a scale*scale block: fg where any plane is lit, bg elsewhere.
+*/
+func renderPNG(pixels []byte, width, height, scale int, fg, bg color.RGBA) ([]byte, error) {
+	if scale < 1 {
+		return nil, fmt.Errorf("scale must be at least 1, got %d", scale)
+	}
+	img := image.NewRGBA(image.Rect(0, 0, width*scale, height*scale))
+	for y := 0; y < height*scale; y++ {
+		for x := 0; x < width*scale; x++ {
+			c := bg
+			if pixels[(y/scale)*width+x/scale] != 0 {
+				c = fg
+			}
+			img.SetRGBA(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("failed to encode PNG: %w", err)
+	}
+	return buf.Bytes(), nil
+}
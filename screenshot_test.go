@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+/*
+TestParseHexColor checks long and short forms and rejects malformed colours.
+*/
+func TestParseHexColor(t *testing.T) {
+	tests := map[string]color.RGBA{
+		"#33FF00": {R: 0x33, G: 0xFF, B: 0x00, A: 0xFF},
+		"102030":  {R: 0x10, G: 0x20, B: 0x30, A: 0xFF},
+		"#fff":    {R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF},
+	}
+	for in, want := range tests {
+		if got, err := parseHexColor(in); err != nil || got != want {
+			t.Errorf("parseHexColor(%q): expected %v, got %v (%v)", in, want, got, err)
+		}
+	}
+	for _, bad := range []string{"", "#12345", "#GGGGGG"} {
+		if _, err := parseHexColor(bad); err == nil {
+			t.Errorf("parseHexColor(%q): expected an error", bad)
+		}
+	}
+}
+
+/*
+TestRenderPNGScalesPixels renders a 2x2 framebuffer at scale 3 and checks the
+image size and the colour of each block.
+*/
+func TestRenderPNGScalesPixels(t *testing.T) {
+	fg := color.RGBA{R: 0x33, G: 0xFF, A: 0xFF}
+	bg := color.RGBA{A: 0xFF}
+	data, err := renderPNG([]byte{1, 0, 0, 2}, 2, 2, 3, fg, bg)
+	if err != nil {
+		t.Fatalf("renderPNG failed: %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if b := img.Bounds(); b.Dx() != 6 || b.Dy() != 6 {
+		t.Fatalf("Expected a 6x6 image, got %dx%d", b.Dx(), b.Dy())
+	}
+	checks := []struct {
+		x, y int
+		want color.RGBA
+	}{
+		{0, 0, fg}, {2, 2, fg}, {3, 0, bg}, {0, 5, bg}, {5, 5, fg},
+	}
+	for _, c := range checks {
+		if got := color.RGBAModel.Convert(img.At(c.x, c.y)).(color.RGBA); got != c.want {
+			t.Errorf("Pixel (%d,%d): expected %v, got %v", c.x, c.y, c.want, got)
+		}
+	}
+	if _, err := renderPNG([]byte{0}, 1, 1, 0, fg, bg); err == nil {
+		t.Error("Expected an error for scale 0")
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import "math"
+
+/*
+slowMotionFrames is how many frames emulation stays slowed after a collision.
+*/
+const slowMotionFrames = 30
+
+/*
+slowMotionDivisor is how much slower emulation runs during slow motion.
+*/
+const slowMotionDivisor = 20
+
+/*
+collisionSlowdown is a debugging aid that slows emulation for a short while
+after each sprite collision, so the moment of impact can be seen.
+*/
+type collisionSlowdown struct {
+	enabled    bool
+	remaining  int     // Frames of slow motion left
+	collisions uint64  // CPU collision count when last observed
+	scale      float64 // Fraction of the normal instructions run this frame; 0 means full speed
+	tickCarry  float64 // Part of a timer tick owed from earlier slowed frames
+}
+
+/*
+observe is called once per frame with the CPU's collision count and starts
+slow motion if a draw has collided since the previous frame.
+*/
+func (s *collisionSlowdown) observe(collisions uint64) {
+	if s.enabled && collisions > s.collisions {
+		s.remaining = slowMotionFrames
+	}
+	s.collisions = collisions
+}
+
+/*
+cycles returns how many instructions to run this frame out of the normal count,
+using up one frame of slow motion if it is active.
+*/
+func (s *collisionSlowdown) cycles(normal int) int {
+	s.scale = 0
+	if s.remaining == 0 {
+		return normal
+	}
+	s.remaining--
+	slowed := max(normal/slowMotionDivisor, 1)
+	s.scale = 1.0 / slowMotionDivisor
+	if normal > 0 {
+		s.scale = float64(slowed) / float64(normal)
+	}
+	return slowed
+}
+
+/*
+ticks returns how many timer ticks to take this frame out of the normal count.
+It must follow cycles for the same frame: while slow motion is on, the timers
+are slowed by the same ratio as the instructions, with any fraction of a tick
+carried to the next frame, so a ROM sees as many instructions between ticks as
+at full speed.
+*/
+func (s *collisionSlowdown) ticks(normal int) int {
+	if s.scale == 0 {
+		return normal
+	}
+	s.tickCarry += float64(normal) * s.scale
+	whole := math.Floor(s.tickCarry + 1e-9)
+	s.tickCarry -= whole
+	return int(whole)
+}
+
+/*
+reset cancels slow motion and forgets the collision count, e.g. when a ROM is
+loaded and the CPU's count starts over.
+*/
+func (s *collisionSlowdown) reset() {
+	s.remaining = 0
+	s.collisions = 0
+	s.scale = 0
+	s.tickCarry = 0
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"chip8-wails/chip8"
+	"testing"
+)
+
+/*
+TestCollisionSlowdownTriggers checks that a collision-setting draw starts slow
+motion for slowMotionFrames frames, after which full speed resumes.
+*/
+func TestCollisionSlowdownTriggers(t *testing.T) {
+	cpu := chip8.New()
+	cpu.I = 0x300
+	cpu.Memory[0x300] = 0x80
+	// DRW V0, V0, 1 twice: the second draw collides
+	cpu.LoadROM([]byte{0xD0, 0x01, 0xD0, 0x01})
+	cpu.IsRunning = true
+
+	s := collisionSlowdown{enabled: true}
+	cpu.EmulateCycle()
+	s.observe(cpu.CollisionCount)
+	if got := s.cycles(100); got != 100 {
+		t.Fatalf("Expected full speed after a draw without collision, got %d cycles", got)
+	}
+
+	cpu.EmulateCycle()
+	s.observe(cpu.CollisionCount)
+	for i := 0; i < slowMotionFrames; i++ {
+		if got := s.cycles(100); got != 100/slowMotionDivisor {
+			t.Fatalf("Frame %d: expected slow motion, got %d cycles", i, got)
+		}
+		s.observe(cpu.CollisionCount)
+	}
+	if got := s.cycles(100); got != 100 {
+		t.Errorf("Expected full speed once slow motion ends, got %d cycles", got)
+	}
+}
+
+/*
+TestCollisionSlowdownDisabled checks that nothing happens while the aid is off,
+and that slow motion still runs at least one instruction per frame.
+*/
+func TestCollisionSlowdownDisabled(t *testing.T) {
+	s := collisionSlowdown{}
+	s.observe(5)
+	if got := s.cycles(100); got != 100 {
+		t.Errorf("Expected full speed while disabled, got %d cycles", got)
+	}
+
+	s.enabled = true
+	s.observe(6)
+	if got := s.cycles(3); got != 1 {
+		t.Errorf("Expected at least one cycle in slow motion, got %d", got)
+	}
+}
+
+/*
+TestCollisionSlowdownScalesTicks checks that slow motion slows the timers by the
+same ratio as the instructions, so over 20 slowed frames of 100 instructions and
+one tick each, 5 instructions and 1/20 of a tick run per frame: one tick in all.
+*/
+func TestCollisionSlowdownScalesTicks(t *testing.T) {
+	s := collisionSlowdown{enabled: true}
+	s.observe(1)
+	cycles, ticks := 0, 0
+	for i := 0; i < slowMotionDivisor; i++ {
+		cycles += s.cycles(100)
+		ticks += s.ticks(1)
+	}
+	if cycles != 100 || ticks != 1 {
+		t.Errorf("Expected 100 instructions and 1 tick over %d slowed frames, got %d and %d", slowMotionDivisor, cycles, ticks)
+	}
+
+	s.reset()
+	if got := s.cycles(100); got != 100 {
+		t.Errorf("Expected full speed after reset, got %d cycles", got)
+	}
+	if got := s.ticks(1); got != 1 {
+		t.Errorf("Expected a full tick per frame at full speed, got %d", got)
+	}
+}
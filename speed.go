@@ -0,0 +1,133 @@
+package main
+
+import (
+	"chip8-wails/chip8"
+	"math"
+)
+
+// speedMultipliers are the emulation speeds offered on top of the base clock.
+var speedMultipliers = []float64{0.25, 0.5, 1, 2, 4}
+
+/*
+clampMultiplier snaps m to the nearest allowed speed multiplier.
+*/
+func clampMultiplier(m float64) float64 {
+	best := speedMultipliers[0]
+	for _, allowed := range speedMultipliers[1:] {
+		if math.Abs(allowed-m) < math.Abs(best-m) {
+			best = allowed
+		}
+	}
+	return best
+}
+
+/*
+cyclesPerFrame returns how many instructions to run per 60Hz frame for a clock
+speed in Hz scaled by multiplier. At least one instruction runs per frame.
+*/
+func cyclesPerFrame(clockHz int, multiplier float64) int {
+	cycles := int(math.Round(float64(clockHz) * multiplier / chip8.TimerFrequency))
+	if cycles < 1 {
+		return 1
+	}
+	return cycles
+}
+
+/*
+frameClock hands out whole instructions per frame for a clock speed that need
+not divide evenly into 60Hz frames. The fractional part carries over, so over
+time exactly clockHz * multiplier instructions run per second (e.g. 700Hz runs
+11, 12, 12, ... instructions per frame).
+*/
+type frameClock struct {
+	perFrame  float64
+	remainder float64
+}
+
+/*
+set changes the clock speed, dropping any carried-over fraction.
+*/
+func (f *frameClock) set(clockHz int, multiplier float64) {
+	f.perFrame = float64(clockHz) * multiplier / chip8.TimerFrequency
+	f.remainder = 0
+}
+
+/*
+next returns the number of instructions to run this frame. It may be 0 for
+clock speeds below 60Hz.
+*/
+func (f *frameClock) next() int {
+	f.remainder += f.perFrame
+	whole := math.Floor(f.remainder + 1e-9) // Absorb rounding error, e.g. 700/60 summed 60 times
+	f.remainder -= whole
+	return int(whole)
+}
+
+/*
+cycleTimer counts executed instructions and reports a timer tick every
+clockHz/60 of them. The fraction carries over, so over time exactly 60 ticks
+happen per clockHz instructions, whatever the frame batches look like. It backs
+the cycle-accurate timer mode, where the timers follow the instructions the ROM
+actually ran rather than the wall-clock frame.
+*/
+type cycleTimer struct {
+	perTick float64
+	count   float64
+}
+
+/*
+set changes the clock speed, dropping any partly counted tick.
+*/
+func (t *cycleTimer) set(clockHz int) {
+	t.perTick = float64(clockHz) / chip8.TimerFrequency
+	t.count = 0
+}
+
+/*
+step records an instruction slot and reports whether a timer tick is due.
+*/
+func (t *cycleTimer) step() bool {
+	t.count++
+	if t.count+1e-9 < t.perTick { // Absorb rounding error, as frameClock does
+		return false
+	}
+	t.count -= t.perTick
+	return true
+}
+
+/*
+timerSpreader spreads a frame's timer ticks evenly over its instructions when a
+speed multiplier above 1 calls for several ticks per frame, so a ROM sees the
+same number of instructions between ticks as at normal speed. All but the last
+tick happen during the batch; the last happens at the end of the frame as usual.
+*/
+type timerSpreader struct {
+	ticks  int
+	cycles int
+	ran    int
+	done   int
+}
+
+/*
+step records an executed instruction and reports whether a timer tick is due.
+*/
+func (s *timerSpreader) step() bool {
+	s.ran++
+	if s.done < s.ticks-1 && s.ran*s.ticks >= (s.done+1)*s.cycles {
+		s.done++
+		return true
+	}
+	return false
+}
+
+/*
+stalled reports whether a tick may be taken early because the CPU is waiting
+for the vertical blank, letting the batch continue.
+*/
+func (s *timerSpreader) stalled() bool {
+	if s.done < s.ticks-1 {
+		s.done++
+		return true
+	}
+	return false
+}
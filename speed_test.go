@@ -0,0 +1,134 @@
+package main
+
+import "testing"
+
+/*
+TestCyclesPerFrameScalesWithMultiplier checks that the multiplier scales the
+instructions run per frame.
+*/
+func TestCyclesPerFrameScalesWithMultiplier(t *testing.T) {
+	tests := []struct {
+		clock      int
+		multiplier float64
+		want       int
+	}{
+		{600, 1, 10},
+		{600, 0.25, 3},
+		{600, 0.5, 5},
+		{600, 2, 20},
+		{600, 4, 40},
+		{700, 1, 12},
+		{30, 0.25, 1},
+	}
+	for _, tt := range tests {
+		if got := cyclesPerFrame(tt.clock, tt.multiplier); got != tt.want {
+			t.Errorf("cyclesPerFrame(%d, %v): expected %d, got %d", tt.clock, tt.multiplier, tt.want, got)
+		}
+	}
+}
+
+/*
+TestClampMultiplier checks that arbitrary values snap to the allowed multipliers.
+*/
+func TestClampMultiplier(t *testing.T) {
+	tests := map[float64]float64{
+		0:    0.25,
+		-3:   0.25,
+		0.25: 0.25,
+		0.6:  0.5,
+		1:    1,
+		1.4:  1,
+		3.1:  4,
+		100:  4,
+	}
+	for in, want := range tests {
+		if got := clampMultiplier(in); got != want {
+			t.Errorf("clampMultiplier(%v): expected %v, got %v", in, want, got)
+		}
+	}
+}
+
+/*
+TestFrameClockAccumulatesFraction checks that a rate that does not divide evenly
+into frames runs the exact number of instructions per second, and that rates
+below one instruction per frame still make progress.
+*/
+func TestFrameClockAccumulatesFraction(t *testing.T) {
+	tests := []struct {
+		clock      int
+		multiplier float64
+		want       int
+	}{
+		{700, 1, 700},
+		{600, 1, 600},
+		{1000, 0.25, 250},
+		{30, 1, 30},
+		{30, 0.25, 7}, // 7.5 per second: the eighth lands in the next second
+	}
+	for _, tt := range tests {
+		var f frameClock
+		f.set(tt.clock, tt.multiplier)
+		total := 0
+		for frame := 0; frame < 60; frame++ {
+			total += f.next()
+		}
+		if total != tt.want {
+			t.Errorf("%d Hz x%v: expected %d instructions in 60 frames, got %d", tt.clock, tt.multiplier, tt.want, total)
+		}
+	}
+}
+
+/*
+TestTimerSpreaderSpacesTicks checks that four ticks over 40 instructions come
+every 10 instructions, leaving the last for the end of the frame, and that a
+vblank stall takes a remaining tick early.
+*/
+func TestTimerSpreaderSpacesTicks(t *testing.T) {
+	s := timerSpreader{ticks: 4, cycles: 40}
+	var at []int
+	for i := 1; i <= 40; i++ {
+		if s.step() {
+			at = append(at, i)
+		}
+	}
+	if len(at) != 3 || at[0] != 10 || at[1] != 20 || at[2] != 30 {
+		t.Errorf("Expected ticks after instructions 10, 20 and 30, got %v", at)
+	}
+	if s.stalled() {
+		t.Error("Expected no tick left for a stall once three have been taken")
+	}
+
+	s = timerSpreader{ticks: 2, cycles: 40}
+	if !s.stalled() || s.stalled() {
+		t.Error("Expected exactly one early tick for a two-tick frame")
+	}
+	if (&timerSpreader{ticks: 1, cycles: 10}).stalled() {
+		t.Error("Expected no early tick at normal speed")
+	}
+}
+
+/*
+TestCycleTimer checks that the cycle timer ticks once per clockHz/60
+instructions, carrying the fraction so a second of instructions gives exactly 60
+ticks even when the clock does not divide evenly.
+*/
+func TestCycleTimer(t *testing.T) {
+	var c cycleTimer
+	c.set(600)
+	for i := 1; i <= 30; i++ {
+		if got, want := c.step(), i%10 == 0; got != want {
+			t.Errorf("600Hz step %d: expected tick %v, got %v", i, want, got)
+		}
+	}
+
+	c.set(700)
+	ticks := 0
+	for i := 0; i < 700; i++ {
+		if c.step() {
+			ticks++
+		}
+	}
+	if ticks != 60 {
+		t.Errorf("Expected 60 ticks per 700 instructions at 700Hz, got %d", ticks)
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import "time"
+
+/*
+eventThrottle caps how often an event is emitted. Updates are coalesced: mark
+records that newer state exists, and ready reports when it may be sent, so the
+latest state always goes out eventually even if intermediate ones are dropped.
+*/
+type eventThrottle struct {
+	interval time.Duration
+	last     time.Time
+	pending  bool
+}
+
+/*
+newEventThrottle returns a throttle allowing at most perSecond emissions per second.
+A non-positive rate disables throttling.
+*/
+func newEventThrottle(perSecond int) *eventThrottle {
+	t := &eventThrottle{}
+	t.setRate(perSecond)
+	return t
+}
+
+/*
+setRate changes the maximum number of emissions per second.
+*/
+func (t *eventThrottle) setRate(perSecond int) {
+	if perSecond <= 0 {
+		t.interval = 0
+		return
+	}
+	t.interval = time.Second / time.Duration(perSecond)
+}
+
+/*
+setMinInterval raises the interval so emissions are at least d apart.
+*/
+func (t *eventThrottle) setMinInterval(d time.Duration) {
+	if t.interval < d {
+		t.interval = d
+	}
+}
+
+/*
+mark records that there is new state waiting to be emitted.
+*/
+func (t *eventThrottle) mark() {
+	t.pending = true
+}
+
+/*
+ready reports whether pending state should be emitted at now, and if so
+consumes it.
+*/
+func (t *eventThrottle) ready(now time.Time) bool {
+	if !t.pending || now.Sub(t.last) < t.interval {
+		return false
+	}
+	t.pending = false
+	t.last = now
+	return true
+}
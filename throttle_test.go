@@ -0,0 +1,66 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+/*
+TestEventThrottleCapsRate marks new state every millisecond for one second at a
+cap of 10 per second and checks that at most 10 emissions happen in that second.
+*/
+func TestEventThrottleCapsRate(t *testing.T) {
+	th := newEventThrottle(10)
+	start := time.Unix(0, 0)
+	emitted := 0
+	for ms := 0; ms < 1000; ms++ {
+		th.mark()
+		if th.ready(start.Add(time.Duration(ms) * time.Millisecond)) {
+			emitted++
+		}
+	}
+	if emitted > 10 {
+		t.Errorf("Expected at most 10 emissions in one second, got %d", emitted)
+	}
+	if emitted == 0 {
+		t.Error("Expected at least one emission")
+	}
+}
+
+/*
+TestEventThrottleEmitsFinalState checks that state marked while throttled is
+emitted once the interval elapses, and only once.
+*/
+func TestEventThrottleEmitsFinalState(t *testing.T) {
+	th := newEventThrottle(10)
+	start := time.Unix(0, 0)
+
+	th.mark()
+	if !th.ready(start) {
+		t.Fatal("Expected first update to be emitted immediately")
+	}
+	th.mark()
+	if th.ready(start.Add(50 * time.Millisecond)) {
+		t.Error("Expected update inside the interval to be held back")
+	}
+	if !th.ready(start.Add(100 * time.Millisecond)) {
+		t.Error("Expected held-back update to be emitted after the interval")
+	}
+	if th.ready(start.Add(300 * time.Millisecond)) {
+		t.Error("Expected nothing further to emit without new state")
+	}
+}
+
+/*
+TestEventThrottleUnlimited checks that a non-positive rate never holds updates back.
+*/
+func TestEventThrottleUnlimited(t *testing.T) {
+	th := newEventThrottle(0)
+	now := time.Unix(0, 0)
+	for i := 0; i < 5; i++ {
+		th.mark()
+		if !th.ready(now) {
+			t.Fatalf("Expected update %d to be emitted with throttling disabled", i)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+/*
+windowRuntime is the part of the Wails runtime the app uses to apply window
+options, behind an interface so tests can stand in for it.
+*/
+type windowRuntime interface {
+	// Relaunch starts a fresh instance of the app and quits this one, so options
+	// fixed when the window is created, such as its frame, take effect.
+	Relaunch(ctx context.Context) error
+}
+
+/*
+wailsWindow applies window options through the Wails runtime. Wails cannot change
+the frame of a window that is already open, so the app is relaunched with the
+saved settings and quit through the runtime.
+*/
+type wailsWindow struct{}
+
+func (wailsWindow) Relaunch(ctx context.Context) error {
+	if ctx == nil {
+		return errors.New("the window is not open")
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(exe, os.Args[1:]...)
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	runtime.Quit(ctx)
+	return nil
+}